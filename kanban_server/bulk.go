@@ -0,0 +1,446 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kanban_server/events"
+	"kanban_server/models"
+	"kanban_server/problem"
+	"kanban_server/service"
+	"kanban_server/store/gormstore"
+
+	"gorm.io/gorm"
+)
+
+// maxBulkTasks must match the limit on task_ids in BulkTaskRequest.
+const maxBulkTasks = 500
+
+const (
+	bulkActionMove         = "move"
+	bulkActionAssign       = "assign"
+	bulkActionRelabel      = "relabel"
+	bulkActionReprioritize = "reprioritize"
+	bulkActionArchive      = "archive"
+	bulkActionDelete       = "delete"
+)
+
+// BulkTaskRequest selects tasks either by ID or by a filter expression and
+// applies one action to all of them. Only the parameters of the chosen
+// action are read.
+type BulkTaskRequest struct {
+	Action       string   `json:"action" validate:"required,oneof=move assign relabel reprioritize archive delete"`
+	TaskIDs      []uint   `json:"task_ids" validate:"max=500,dive,min=1"`
+	Filter       string   `json:"filter" validate:"max=1000"`
+	AllowPartial bool     `json:"allow_partial"`
+	Status       string   `json:"status" validate:"omitempty,taskstatus"`
+	ColumnID     *uint    `json:"column_id"`
+	AssignedTo   *uint    `json:"assigned_to"`
+	AddLabels    []string `json:"add_labels" validate:"dive,notblank,max=50"`
+	RemoveLabels []string `json:"remove_labels" validate:"dive,notblank,max=50"`
+	Priority     string   `json:"priority" validate:"required_if=Action reprioritize,omitempty,taskpriority"`
+	Archived     *bool    `json:"archived"`
+}
+
+type BulkItemResult struct {
+	TaskID uint   `json:"task_id"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkTaskResult struct {
+	Action    string           `json:"action"`
+	Atomic    bool             `json:"atomic"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// bulkOperation is a validated BulkTaskRequest bound to one project.
+type bulkOperation struct {
+	req          BulkTaskRequest
+	projectID    uint
+	removeLabels []models.Label
+}
+
+func (s *Server) bulkTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	var req BulkTaskRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	op, err := s.newBulkOperation(r, project.ID, req)
+	if err != nil {
+		writeError(w, r, err, "Bulk operation failed")
+		return
+	}
+
+	tasks, results, err := op.selectTasks(s.db(r))
+	if err != nil {
+		writeError(w, r, err, "Failed to fetch tasks")
+		return
+	}
+
+	result := BulkTaskResult{Action: req.Action, Atomic: !req.AllowPartial}
+	if req.AllowPartial {
+		for _, task := range tasks {
+			err := s.db(r).Transaction(func(tx *gorm.DB) error {
+				return op.apply(tx, task)
+			})
+			results = append(results, bulkItemResult(task.ID, err))
+		}
+	} else {
+		// Unresolved task IDs already fail the whole batch. Otherwise only the
+		// item that aborted the transaction is reported.
+		failed := len(results) > 0
+		if !failed {
+			err := s.db(r).Transaction(func(tx *gorm.DB) error {
+				for _, task := range tasks {
+					if err := op.apply(tx, task); err != nil {
+						results = []BulkItemResult{bulkItemResult(task.ID, err)}
+						return err
+					}
+					results = append(results, bulkItemResult(task.ID, nil))
+				}
+				return nil
+			})
+			failed = err != nil
+		}
+		if failed {
+			result.Results = results
+			result.Failed = len(results)
+			problem.Write(w, r, problem.Invalid("Bulk operation failed, no tasks were changed").With("result", result))
+			return
+		}
+	}
+
+	for _, item := range results {
+		if item.OK {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+	result.Results = results
+
+	succeeded := make(map[uint]bool, len(results))
+	var changedIDs []uint
+	for _, item := range results {
+		succeeded[item.TaskID] = item.OK
+		if item.OK {
+			changedIDs = append(changedIDs, item.TaskID)
+		}
+	}
+	s.publishBulkEvents(r, req.Action, tasks, changedIDs, succeeded)
+
+	if req.Action == bulkActionAssign && *req.AssignedTo != 0 {
+		var inbox []models.Notification
+		for _, task := range tasks {
+			if succeeded[task.ID] && task.AssignedTo != *req.AssignedTo {
+				task.AssignedTo = *req.AssignedTo
+				inbox = append(inbox, service.AssignmentNotification(task))
+			}
+		}
+		s.sendNotifications(r, inbox...)
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Bulk operation applied",
+		Data:    result,
+	})
+}
+
+// publishBulkEvents publishes one event per task the bulk operation changed.
+func (s *Server) publishBulkEvents(r *http.Request, action string, tasks []models.Task, changedIDs []uint, succeeded map[uint]bool) {
+	if len(changedIDs) == 0 {
+		return
+	}
+	if action == bulkActionDelete {
+		for _, task := range tasks {
+			if succeeded[task.ID] {
+				s.publishEvent(r, events.TaskDeleted, task.ProjectID, task)
+			}
+		}
+		return
+	}
+
+	var changed []models.Task
+	if err := s.db(r).Preload("Labels").Where("id IN ?", changedIDs).Find(&changed).Error; err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load tasks for events", "error", err)
+		return
+	}
+	for _, task := range changed {
+		s.publishEvent(r, events.TaskUpdated, task.ProjectID, task)
+	}
+}
+
+func bulkItemResult(taskID uint, err error) BulkItemResult {
+	if err != nil {
+		return BulkItemResult{TaskID: taskID, Error: err.Error()}
+	}
+	return BulkItemResult{TaskID: taskID, OK: true}
+}
+
+// newBulkOperation validates the action parameters once so that per-task
+// failures only come from the tasks themselves.
+func (s *Server) newBulkOperation(r *http.Request, projectID uint, req BulkTaskRequest) (*bulkOperation, error) {
+	db := s.db(r)
+	if (len(req.TaskIDs) == 0) == (strings.TrimSpace(req.Filter) == "") {
+		return nil, problem.Invalid("Provide either task_ids or filter")
+	}
+
+	op := &bulkOperation{req: req, projectID: projectID}
+	switch req.Action {
+	case bulkActionMove:
+		if req.Status == "" && req.ColumnID == nil {
+			return nil, problem.Invalid("move requires status or column_id")
+		}
+		if req.ColumnID != nil && *req.ColumnID != 0 {
+			var column models.Column
+			if err := db.Where("project_id = ?", projectID).First(&column, *req.ColumnID).Error; err != nil {
+				return nil, problem.Field("column_id", "Column not found")
+			}
+		}
+	case bulkActionAssign:
+		if req.AssignedTo == nil {
+			return nil, problem.Field("assigned_to", "assign requires assigned_to (0 to unassign)")
+		}
+		if *req.AssignedTo != 0 {
+			var user models.User
+			if err := db.First(&user, *req.AssignedTo).Error; err != nil {
+				return nil, problem.Field("assigned_to", "User not found")
+			}
+			member, err := s.Projects.IsMember(r.Context(), projectID, user.ID)
+			if err != nil {
+				return nil, problem.Internal("Failed to check project membership")
+			}
+			if !member {
+				return nil, problem.Field("assigned_to", "User is not a project member")
+			}
+		}
+	case bulkActionRelabel:
+		if len(req.AddLabels) == 0 && len(req.RemoveLabels) == 0 {
+			return nil, problem.Invalid("relabel requires add_labels or remove_labels")
+		}
+		if len(req.RemoveLabels) > 0 {
+			if err := db.Where("project_id = ? AND name IN ?", projectID, req.RemoveLabels).Find(&op.removeLabels).Error; err != nil {
+				return nil, problem.Internal("Failed to load labels")
+			}
+		}
+	case bulkActionReprioritize, bulkActionArchive, bulkActionDelete:
+	default:
+		return nil, problem.Field("action", fmt.Sprintf("Unknown action %q", req.Action))
+	}
+
+	return op, nil
+}
+
+// selectTasks resolves the request's task IDs or filter to tasks of the
+// project. Requested IDs that do not belong to the project are reported as
+// failed items.
+func (op *bulkOperation) selectTasks(db *gorm.DB) ([]models.Task, []BulkItemResult, error) {
+	query := db.Where("project_id = ?", op.projectID)
+
+	if len(op.req.TaskIDs) == 0 {
+		scope, err := parseTaskFilter(op.projectID, op.req.Filter)
+		if err != nil {
+			return nil, nil, problem.Field("filter", err.Error())
+		}
+		var tasks []models.Task
+		if err := query.Scopes(scope).Order("id").Limit(maxBulkTasks + 1).Find(&tasks).Error; err != nil {
+			return nil, nil, problem.Internal("Failed to fetch tasks")
+		}
+		if len(tasks) > maxBulkTasks {
+			return nil, nil, problem.Field("filter", fmt.Sprintf("Filter matches more than %d tasks", maxBulkTasks))
+		}
+		return tasks, nil, nil
+	}
+
+	var tasks []models.Task
+	if err := query.Where("id IN ?", op.req.TaskIDs).Order("id").Find(&tasks).Error; err != nil {
+		return nil, nil, problem.Internal("Failed to fetch tasks")
+	}
+
+	found := make(map[uint]bool, len(tasks))
+	for _, task := range tasks {
+		found[task.ID] = true
+	}
+	var missing []BulkItemResult
+	for _, id := range op.req.TaskIDs {
+		if !found[id] {
+			missing = append(missing, BulkItemResult{TaskID: id, Error: "Task not found"})
+			found[id] = true
+		}
+	}
+	return tasks, missing, nil
+}
+
+func (op *bulkOperation) apply(tx *gorm.DB, task models.Task) error {
+	req := op.req
+	switch req.Action {
+	case bulkActionMove:
+		updates := map[string]interface{}{}
+		if req.Status != "" {
+			updates["status"] = req.Status
+		}
+		if req.ColumnID != nil {
+			if *req.ColumnID == 0 {
+				updates["column_id"] = nil
+			} else {
+				updates["column_id"] = *req.ColumnID
+			}
+		}
+		return tx.Model(&task).Updates(updates).Error
+	case bulkActionAssign:
+		return tx.Model(&task).Update("assigned_to", *req.AssignedTo).Error
+	case bulkActionRelabel:
+		// Labels are created in the transaction so that a failed batch
+		// leaves none behind.
+		addLabels, err := findOrCreateLabels(tx, op.projectID, req.AddLabels)
+		if err != nil {
+			return err
+		}
+		if len(addLabels) > 0 {
+			if err := tx.Model(&task).Association("Labels").Append(addLabels); err != nil {
+				return err
+			}
+		}
+		if len(op.removeLabels) > 0 {
+			if err := tx.Model(&task).Association("Labels").Delete(op.removeLabels); err != nil {
+				return err
+			}
+		}
+		return nil
+	case bulkActionReprioritize:
+		return tx.Model(&task).Update("priority", req.Priority).Error
+	case bulkActionArchive:
+		archived := true
+		if req.Archived != nil {
+			archived = *req.Archived
+		}
+		return tx.Model(&task).Update("archived", archived).Error
+	case bulkActionDelete:
+		return gormstore.New(tx).DeleteTask(tx.Statement.Context, task.ID)
+	}
+	return fmt.Errorf("Unknown action %q", req.Action)
+}
+
+func findOrCreateLabels(db *gorm.DB, projectID uint, names []string) ([]models.Label, error) {
+	labels := make([]models.Label, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		label := models.Label{ProjectID: projectID, Name: name}
+		if err := db.Where(label).FirstOrCreate(&label).Error; err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+// parseTaskFilter turns a filter expression on the tasks of a project into a
+// query scope. The
+// expression is a space separated list of field:value terms that must all
+// match; a term may list several comma separated values, any of which may
+// match. Supported fields are status, priority, assigned_to, column_id,
+// label, archived, due_before and due_after, e.g.
+//
+//	status:todo,in_progress priority:high label:bug
+func parseTaskFilter(projectID uint, expr string) (func(*gorm.DB) *gorm.DB, error) {
+	type clause struct {
+		query string
+		args  []interface{}
+	}
+	var clauses []clause
+
+	for _, term := range strings.Fields(expr) {
+		field, value, ok := strings.Cut(term, ":")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("Invalid filter term %q, expected field:value", term)
+		}
+		values := strings.Split(value, ",")
+
+		switch field {
+		case "status", "priority":
+			allowed := models.TaskStatuses
+			if field == "priority" {
+				allowed = models.TaskPriorities
+			}
+			for _, v := range values {
+				if !contains(allowed, v) {
+					return nil, fmt.Errorf("Invalid %s %q in filter", field, v)
+				}
+			}
+			clauses = append(clauses, clause{field + " IN ?", []interface{}{values}})
+		case "assigned_to", "column_id":
+			ids := make([]uint, 0, len(values))
+			for _, v := range values {
+				id, err := strconv.ParseUint(v, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid %s %q in filter", field, v)
+				}
+				ids = append(ids, uint(id))
+			}
+			clauses = append(clauses, clause{field + " IN ?", []interface{}{ids}})
+		case "label":
+			clauses = append(clauses, clause{
+				"id IN (SELECT task_labels.task_id FROM task_labels JOIN labels ON labels.id = task_labels.label_id WHERE labels.project_id = ? AND labels.name IN ?)",
+				[]interface{}{projectID, values},
+			})
+		case "archived":
+			archived, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid archived %q in filter", value)
+			}
+			clauses = append(clauses, clause{"archived = ?", []interface{}{archived}})
+		case "due_before", "due_after":
+			due, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s %q in filter, expected YYYY-MM-DD", field, value)
+			}
+			op := "<"
+			if field == "due_after" {
+				op = ">"
+			}
+			clauses = append(clauses, clause{"due_date " + op + " ?", []interface{}{due}})
+		default:
+			return nil, fmt.Errorf("Unknown filter field %q", field)
+		}
+	}
+
+	if len(clauses) == 0 {
+		return nil, errors.New("Filter must contain at least one term")
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		for _, c := range clauses {
+			db = db.Where(c.query, c.args...)
+		}
+		return db
+	}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
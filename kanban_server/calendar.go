@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"kanban_server/ical"
+	"kanban_server/models"
+	"kanban_server/problem"
+	"kanban_server/service"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const calendarProdID = "-//Kanban Server//Task Due Dates//EN"
+
+type CalendarFeedResponse struct {
+	models.CalendarFeed
+	URL string `json:"url,omitempty"`
+}
+
+func (s *Server) calendarFeedURL(token string) string {
+	return s.Config.HTTP.PublicURL + "/calendar/" + token + ".ics"
+}
+
+func newCalendarToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// calendarFeedScope selects the current user's feed, for the project in the
+// route if there is one, reporting an error to the client if the user is not
+// a member of that project.
+func (s *Server) calendarFeedScope(w http.ResponseWriter, r *http.Request) (func(*gorm.DB) *gorm.DB, *uint, bool) {
+	userID := currentUserID(r)
+	if _, ok := mux.Vars(r)["id"]; !ok {
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where("user_id = ? AND project_id IS NULL", userID)
+		}, nil, true
+	}
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ? AND project_id = ?", userID, project.ID)
+	}, &project.ID, true
+}
+
+// createCalendarFeed issues a feed URL, replacing and so revoking any earlier
+// one for the same user and project.
+func (s *Server) createCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	scope, projectID, ok := s.calendarFeedScope(w, r)
+	if !ok {
+		return
+	}
+
+	feed := models.CalendarFeed{UserID: currentUserID(r), ProjectID: projectID, Token: newCalendarToken()}
+	err := s.db(r).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(scope).Delete(&models.CalendarFeed{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&feed).Error
+	})
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to create calendar feed"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Calendar feed created",
+		Data:    CalendarFeedResponse{CalendarFeed: feed, URL: s.calendarFeedURL(feed.Token)},
+	})
+}
+
+func (s *Server) getCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	scope, _, ok := s.calendarFeedScope(w, r)
+	if !ok {
+		return
+	}
+
+	var feed models.CalendarFeed
+	if err := s.db(r).Scopes(scope).First(&feed).Error; err != nil {
+		writeLookupError(w, r, err, "Calendar feed not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: CalendarFeedResponse{CalendarFeed: feed, URL: s.calendarFeedURL(feed.Token)},
+	})
+}
+
+func (s *Server) revokeCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	scope, _, ok := s.calendarFeedScope(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.db(r).Scopes(scope).Delete(&models.CalendarFeed{}).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to revoke calendar feed"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Calendar feed revoked",
+	})
+}
+
+// serveCalendarFeed serves the .ics feed for a token. It needs no login, so
+// calendar apps can subscribe to the URL, but shows only projects the feed's
+// owner is still a member of.
+func (s *Server) serveCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	var feed models.CalendarFeed
+	if err := s.db(r).Where("token = ?", mux.Vars(r)["token"]).First(&feed).Error; err != nil {
+		writeLookupError(w, r, err, "Calendar feed not found")
+		return
+	}
+
+	db := s.db(r).Where("due_date > ? AND status <> ? AND archived = ?", time.Time{}, models.TaskStatusDone, false)
+	name := "My tasks"
+	if feed.ProjectID != nil {
+		project, err := s.Kanban.FindMemberProject(r.Context(), feed.UserID, *feed.ProjectID)
+		if errors.Is(err, service.ErrProjectNotFound) {
+			problem.Write(w, r, problem.NotFound("Calendar feed not found"))
+			return
+		}
+		if err != nil {
+			writeError(w, r, err, "Failed to load calendar feed")
+			return
+		}
+		db = db.Where("project_id = ?", project.ID)
+		name = project.Title
+	} else {
+		db = db.Where("assigned_to = ? AND project_id IN (SELECT project_id FROM user_projects WHERE user_id = ?)", feed.UserID, feed.UserID)
+	}
+
+	var tasks []models.Task
+	if err := db.Order("due_date, id").Find(&tasks).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to load tasks"))
+		return
+	}
+
+	cal := ical.Calendar{ProdID: calendarProdID, Name: name}
+	host := s.calendarHost()
+	for _, task := range tasks {
+		cal.Events = append(cal.Events, ical.Event{
+			UID:          fmt.Sprintf("task-%d@%s", task.ID, host),
+			Summary:      task.Title,
+			Description:  task.Description,
+			URL:          fmt.Sprintf("%s/projects/%d/tasks/%d", s.Config.HTTP.PublicURL, task.ProjectID, task.ID),
+			Start:        task.DueDate,
+			LastModified: task.UpdatedAt,
+			Categories:   []string{task.Status, task.Priority},
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="tasks.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if err := cal.Write(w, time.Now()); err != nil {
+		log.Println("Failed to write calendar feed:", err)
+	}
+}
+
+// calendarHost is the domain part of event UIDs. It must not change, or
+// subscribed calendars would duplicate every event.
+func (s *Server) calendarHost() string {
+	if u, err := url.Parse(s.Config.HTTP.PublicURL); err == nil && u.Hostname() != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	return "kanban"
+}
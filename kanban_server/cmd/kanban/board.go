@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const boardQuery = `query Board($id: ID!) {
+  project(id: $id) {
+    id title status
+    columns { id name position }
+    tasks {
+      id title description status priority dueDate overdue archived
+      assignee { id name }
+      column { id }
+    }
+  }
+}`
+
+type boardColumn struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+// board is a project with its columns and unarchived tasks, as returned by
+// the GraphQL API.
+type board struct {
+	ID      string        `json:"id"`
+	Title   string        `json:"title"`
+	Status  string        `json:"status"`
+	Columns []boardColumn `json:"columns"`
+	Tasks   []graphTask   `json:"tasks"`
+}
+
+func fetchBoard(ctx context.Context, client *Client, projectID uint) (*board, error) {
+	var data struct {
+		Project *board `json:"project"`
+	}
+	vars := map[string]interface{}{"id": strconv.FormatUint(uint64(projectID), 10)}
+	if err := client.graphQL(ctx, boardQuery, vars, &data); err != nil {
+		return nil, err
+	}
+	if data.Project == nil {
+		return nil, errors.New("project not found")
+	}
+	sort.SliceStable(data.Project.Columns, func(i, j int) bool {
+		return data.Project.Columns[i].Position < data.Project.Columns[j].Position
+	})
+	return data.Project, nil
+}
+
+// column finds a column by ID or, ignoring case, by name.
+func (b *board) column(nameOrID string) (boardColumn, bool) {
+	for _, col := range b.Columns {
+		if col.ID == nameOrID {
+			return col, true
+		}
+	}
+	for _, col := range b.Columns {
+		if strings.EqualFold(col.Name, nameOrID) {
+			return col, true
+		}
+	}
+	return boardColumn{}, false
+}
+
+// lane is one column of the rendered board.
+type lane struct {
+	Name     string      `json:"name"`
+	ColumnID string      `json:"column_id,omitempty"`
+	Tasks    []graphTask `json:"tasks"`
+}
+
+// lanes places the tasks in the project's columns, in order. Tasks without
+// a column go in the lane matching their status; status lanes are always
+// shown for a project without columns and otherwise only when they hold
+// tasks.
+func (b *board) lanes() []lane {
+	lanes := make([]lane, 0, len(b.Columns)+len(taskStatuses))
+	byColumn := map[string]int{}
+	for _, col := range b.Columns {
+		byColumn[col.ID] = len(lanes)
+		lanes = append(lanes, lane{Name: col.Name, ColumnID: col.ID, Tasks: []graphTask{}})
+	}
+	byStatus := map[string]int{}
+	for _, status := range taskStatuses {
+		byStatus[status] = len(lanes)
+		lanes = append(lanes, lane{Name: status, Tasks: []graphTask{}})
+	}
+
+	for _, t := range b.Tasks {
+		i, ok := -1, false
+		if t.Column != nil {
+			i, ok = byColumn[t.Column.ID]
+		}
+		if !ok {
+			if i, ok = byStatus[t.Status]; !ok {
+				i = byStatus[taskStatuses[0]]
+			}
+		}
+		lanes[i].Tasks = append(lanes[i].Tasks, t)
+	}
+
+	shown := append([]lane{}, lanes[:len(b.Columns)]...)
+	for _, l := range lanes[len(b.Columns):] {
+		if len(b.Columns) == 0 || len(l.Tasks) > 0 {
+			shown = append(shown, l)
+		}
+	}
+	return shown
+}
+
+func (a *app) showBoard(ctx context.Context, args []string) error {
+	const usage = "board show PROJECT"
+	args, err := a.parse(a.flagSet(usage), usage, args, 1)
+	if err != nil {
+		return err
+	}
+	projectID, err := parseID("project", args[0])
+	if err != nil {
+		return err
+	}
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	b, err := fetchBoard(ctx, client, projectID)
+	if err != nil {
+		return err
+	}
+	lanes := b.lanes()
+	return a.render(map[string]interface{}{"id": b.ID, "title": b.Title, "status": b.Status, "lanes": lanes}, boardTable(lanes))
+}
+
+// boardTable renders the lanes side by side, one task per cell.
+func boardTable(lanes []lane) *table {
+	t := &table{}
+	rows := 0
+	for _, l := range lanes {
+		t.header = append(t.header, strings.ToUpper(l.Name)+" ("+strconv.Itoa(len(l.Tasks))+")")
+		if len(l.Tasks) > rows {
+			rows = len(l.Tasks)
+		}
+	}
+	for i := 0; i < rows; i++ {
+		row := make([]string, len(lanes))
+		for j, l := range lanes {
+			if i < len(l.Tasks) {
+				row[j] = "#" + l.Tasks[i].ID + " " + cell(truncate(l.Tasks[i].Title, 30))
+			}
+		}
+		t.add(row...)
+	}
+	return t
+}
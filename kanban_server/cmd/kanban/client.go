@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// Client calls the kanban HTTP API.
+type Client struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+func newClient(profile *Profile) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(profile.Server, "/"),
+		Token:   profile.Token,
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// envelope is the body of every successful REST response.
+type envelope struct {
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// problem is the body of a failed REST response (RFC 7807).
+type problem struct {
+	Detail    string `json:"detail"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id"`
+	Errors    []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// APIError is an error reported by the server.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details []string
+	// RequestID identifies the request in the server's logs.
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	if len(e.Details) == 0 {
+		return e.Message
+	}
+	return e.Message + ": " + strings.Join(e.Details, "; ")
+}
+
+var errNotLoggedIn = errors.New("not logged in, run kanban login first")
+
+// do sends a REST request with body encoded as JSON, or as a JSON merge
+// patch for PATCH, and decodes the data of the response into out.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		contentType := "application/json"
+		if method == http.MethodPatch {
+			contentType = mergePatchContentType
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", c.Token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return apiError(resp)
+	}
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("%s %s: unexpected response (%s)", method, path, resp.Status)
+	}
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(env.Data, out)
+}
+
+// apiError turns a problem response into an APIError, keeping the field
+// errors of a failed validation.
+func apiError(resp *http.Response) error {
+	err := &APIError{Status: resp.StatusCode, Message: resp.Status}
+	var p problem
+	if json.NewDecoder(resp.Body).Decode(&p) != nil {
+		return err
+	}
+	err.Code, err.RequestID = p.Code, p.RequestID
+	if p.Detail != "" {
+		err.Message = p.Detail
+	}
+	if p.Code == "unauthorized" {
+		err.Message += ", run kanban login"
+	}
+	for _, fe := range p.Errors {
+		// Single-field problems already carry the message as their detail.
+		if fe.Message != p.Detail {
+			err.Details = append(err.Details, strings.TrimSpace(fe.Field+" "+fe.Message))
+		}
+	}
+	return err
+}
+
+// graphQL runs a GraphQL query and decodes its data into out.
+func (c *Client) graphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/graphql", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", c.Token)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("POST /graphql: unexpected response (%s)", resp.Status)
+	}
+	if len(result.Errors) > 0 {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = e.Message
+		}
+		return &APIError{Status: resp.StatusCode, Message: strings.Join(messages, "; ")}
+	}
+	return json.Unmarshal(result.Data, out)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	defaultProfile = "default"
+	defaultServer  = "http://localhost:5000"
+)
+
+// Profile is one server the CLI can talk to and the token it logged in with.
+type Profile struct {
+	Server string `json:"server"`
+	Email  string `json:"email,omitempty"`
+	Token  string `json:"token,omitempty"`
+}
+
+// Config is the CLI's config file. It is JSON, stored at
+// $KANBAN_CONFIG or kanban/config.json under the user config directory:
+//
+//	{
+//	  "current": "staging",
+//	  "profiles": {
+//	    "default": {"server": "http://localhost:5000"},
+//	    "staging": {"server": "https://kanban.example.com", "token": "..."}
+//	  }
+//	}
+type Config struct {
+	Current  string              `json:"current,omitempty"`
+	Profiles map[string]*Profile `json:"profiles"`
+
+	path string
+}
+
+// configPath returns the config file to use when --config is not given.
+func configPath() (string, error) {
+	if path := os.Getenv("KANBAN_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kanban", "config.json"), nil
+}
+
+// loadConfig reads the config file at path. A missing file is an empty
+// config.
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: map[string]*Profile{}, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*Profile{}
+	}
+	return cfg, nil
+}
+
+// save writes the config file. It holds tokens, so only the owner may read
+// it.
+func (c *Config) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0o600)
+}
+
+// profileName resolves the profile to use: the given name, then
+// $KANBAN_PROFILE, then the current profile, then "default".
+func (c *Config) profileName(name string) string {
+	for _, candidate := range []string{name, os.Getenv("KANBAN_PROFILE"), c.Current} {
+		if candidate != "" {
+			return candidate
+		}
+	}
+	return defaultProfile
+}
+
+// profile returns the named profile, adding it if it does not exist yet.
+func (c *Config) profile(name string) *Profile {
+	p, ok := c.Profiles[name]
+	if !ok {
+		p = &Profile{Server: defaultServer}
+		c.Profiles[name] = p
+	}
+	return p
+}
+
+// profileNames returns the names of all profiles in order.
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// login exchanges an email and password for a token with POST /login and
+// stores it in the profile. --server given to login is saved with the
+// profile, which is how new profiles are added.
+func (a *app) login(ctx context.Context, args []string) error {
+	const usage = "login [--email EMAIL] [--password-stdin]"
+	fs := a.flagSet(usage)
+	email := fs.String("email", "", "account email (default the profile's last one)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from standard input")
+	if _, err := a.parse(fs, usage, args, 0); err != nil {
+		return err
+	}
+
+	name, profile := a.profile()
+	if a.server != "" {
+		profile.Server = strings.TrimRight(a.server, "/")
+	}
+	if *email == "" {
+		*email = profile.Email
+	}
+	if *email == "" {
+		fmt.Fprint(a.stderr, "Email: ")
+		line, err := a.readLine()
+		if err != nil {
+			return err
+		}
+		*email = strings.TrimSpace(line)
+	}
+	password, err := a.readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	client := newClient(profile)
+	var data struct {
+		Token string `json:"token"`
+	}
+	err = client.do(ctx, http.MethodPost, "/login", map[string]string{"email": *email, "password": password}, &data)
+	if err != nil {
+		return err
+	}
+	if data.Token == "" {
+		return errors.New("the server did not return a token")
+	}
+
+	profile.Email = *email
+	profile.Token = data.Token
+	if a.cfg.Current == "" {
+		a.cfg.Current = name
+	}
+	if err := a.cfg.save(); err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"PROFILE", "SERVER", "EMAIL"}}
+	t.add(name, profile.Server, profile.Email)
+	return a.render(map[string]string{"profile": name, "server": profile.Server, "email": profile.Email}, t)
+}
+
+// readPassword reads the password without echoing it from a terminal, or as
+// a line of standard input with --password-stdin.
+func (a *app) readPassword(fromStdin bool) (string, error) {
+	if fromStdin {
+		return a.readLine()
+	}
+	f, ok := a.stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return "", errors.New("standard input is not a terminal, use --password-stdin")
+	}
+	fmt.Fprint(a.stderr, "Password: ")
+	password, err := term.ReadPassword(int(f.Fd()))
+	fmt.Fprintln(a.stderr)
+	return string(password), err
+}
+
+func (a *app) logout(ctx context.Context, args []string) error {
+	const usage = "logout"
+	if _, err := a.parse(a.flagSet(usage), usage, args, 0); err != nil {
+		return err
+	}
+	name, profile := a.profile()
+	profile.Token = ""
+	if err := a.cfg.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "Logged out of profile %s\n", name)
+	return nil
+}
+
+func (a *app) listProfiles(ctx context.Context, args []string) error {
+	const usage = "profiles ls"
+	if _, err := a.parse(a.flagSet(usage), usage, args, 0); err != nil {
+		return err
+	}
+
+	type profileInfo struct {
+		Name     string `json:"name"`
+		Server   string `json:"server"`
+		Email    string `json:"email,omitempty"`
+		LoggedIn bool   `json:"logged_in"`
+		Current  bool   `json:"current"`
+	}
+	current := a.cfg.profileName(a.profileName)
+	profiles := []profileInfo{}
+	t := &table{header: []string{"CURRENT", "NAME", "SERVER", "EMAIL", "LOGGED IN"}}
+	for _, name := range a.cfg.profileNames() {
+		p := a.cfg.Profiles[name]
+		info := profileInfo{Name: name, Server: p.Server, Email: p.Email, LoggedIn: p.Token != "", Current: name == current}
+		profiles = append(profiles, info)
+
+		marker := ""
+		if info.Current {
+			marker = "*"
+		}
+		t.add(marker, name, p.Server, cell(p.Email), yesNo(info.LoggedIn))
+	}
+	return a.render(profiles, t)
+}
+
+func (a *app) useProfile(ctx context.Context, args []string) error {
+	const usage = "profiles use NAME"
+	args, err := a.parse(a.flagSet(usage), usage, args, 1)
+	if err != nil {
+		return err
+	}
+	if _, ok := a.cfg.Profiles[args[0]]; !ok {
+		return fmt.Errorf("no profile named %q, add it with kanban login --profile %s --server URL", args[0], args[0])
+	}
+	a.cfg.Current = args[0]
+	return a.cfg.save()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,238 @@
+// Command kanban is a command-line client for the kanban server. It talks to
+// the HTTP API and keeps a token per server profile in its config file, so
+// boards can be scripted from a terminal or CI:
+//
+//	kanban login --email me@example.com
+//	kanban projects ls
+//	kanban tasks add 3 "Write release notes" --column Doing --assignee me@example.com
+//	kanban board show 3 --output json
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+)
+
+// command is a leaf of the command tree, e.g. "tasks add".
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(a *app, ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"login", "login [--email EMAIL] [--password-stdin]", "log in and store the token in the profile", (*app).login},
+	{"logout", "logout", "forget the profile's token", (*app).logout},
+	{"profiles ls", "profiles ls", "list the server profiles", (*app).listProfiles},
+	{"profiles use", "profiles use NAME", "make NAME the current profile", (*app).useProfile},
+	{"projects ls", "projects ls [--status STATUS]", "list projects", (*app).listProjects},
+	{"projects create", "projects create TITLE [--description TEXT]", "create a project", (*app).createProject},
+	{"projects rm", "projects rm PROJECT", "delete a project", (*app).removeProject},
+	{"tasks ls", "tasks ls PROJECT [--status STATUS] [--all]", "list the tasks of a project", (*app).listTasks},
+	{"tasks add", "tasks add PROJECT TITLE [flags]", "add a task to a project", (*app).addTask},
+	{"tasks move", "tasks move PROJECT TASK COLUMN", "move a task to a column, by name or ID, or to a status lane", (*app).moveTask},
+	{"tasks assign", "tasks assign PROJECT TASK USER", "assign a task to a user, by ID or email, or to none", (*app).assignTask},
+	{"board show", "board show PROJECT", "show the board of a project", (*app).showBoard},
+}
+
+// app holds the global options and the streams of one invocation.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath  string
+	profileName string
+	server      string
+	output      string
+
+	cfg   *Config
+	input *bufio.Reader
+}
+
+// usageError reports a command line that cannot be run. It exits with
+// status 2.
+type usageError struct {
+	usage string
+	err   error
+}
+
+func (e *usageError) Error() string {
+	return fmt.Sprintf("%v\nusage: kanban %s", e.err, e.usage)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	err := a.run(ctx, os.Args[1:])
+	if err == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "kanban:", err)
+	var usage *usageError
+	if errors.As(err, &usage) {
+		os.Exit(2)
+	}
+	os.Exit(1)
+}
+
+// run parses the global flags and runs the command named by args.
+func (a *app) run(ctx context.Context, args []string) error {
+	fs := a.flagSet("[global flags] COMMAND")
+	fs.Usage = func() { a.printUsage(fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return &usageError{usage: "[global flags] COMMAND", err: err}
+	}
+	args = fs.Args()
+	if len(args) == 0 || args[0] == "help" {
+		a.printUsage(fs)
+		return nil
+	}
+
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			err := cmd.run(a, ctx, args[len(words):])
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return err
+		}
+	}
+	return &usageError{usage: "[global flags] COMMAND", err: fmt.Errorf("unknown command %q", strings.Join(args, " "))}
+}
+
+func (a *app) printUsage(fs *flag.FlagSet) {
+	fmt.Fprintln(a.stderr, "usage: kanban [global flags] COMMAND [flags]")
+	fmt.Fprintln(a.stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(a.stderr, "  %-46s %s\n", cmd.usage, cmd.summary)
+	}
+	fmt.Fprintln(a.stderr, "\nGlobal flags, accepted by every command:")
+	fs.PrintDefaults()
+}
+
+// flagSet returns a flag set with the global flags defined.
+func (a *app) flagSet(usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet("kanban", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "usage: kanban %s\n", usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&a.configPath, "config", a.configPath, "config `file` (default $KANBAN_CONFIG or the user config directory)")
+	fs.StringVar(&a.profileName, "profile", a.profileName, "server profile to use (default $KANBAN_PROFILE or the current profile)")
+	fs.StringVar(&a.server, "server", a.server, "server `URL`, overriding the profile's")
+	if a.output == "" {
+		a.output = outputTable
+	}
+	fs.StringVar(&a.output, "output", a.output, "output format, table or json")
+	fs.StringVar(&a.output, "o", a.output, "shorthand for --output")
+	return fs
+}
+
+// parse parses the flags of a command, which may come before, between or
+// after its want positional arguments, and loads the config.
+func (a *app) parse(fs *flag.FlagSet, usage string, args []string, want int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &usageError{usage: usage, err: err}
+		}
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	if len(positional) != want {
+		return nil, &usageError{usage: usage, err: fmt.Errorf("expected %d arguments, got %d", want, len(positional))}
+	}
+	if a.output != outputTable && a.output != outputJSON {
+		return nil, &usageError{usage: usage, err: fmt.Errorf("unknown output format %q", a.output)}
+	}
+	return positional, a.loadConfig()
+}
+
+func (a *app) loadConfig() error {
+	path := a.configPath
+	if path == "" {
+		var err error
+		if path, err = configPath(); err != nil {
+			return err
+		}
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	a.cfg = cfg
+	return nil
+}
+
+// profile returns the profile in use.
+func (a *app) profile() (string, *Profile) {
+	name := a.cfg.profileName(a.profileName)
+	return name, a.cfg.profile(name)
+}
+
+// client returns a client for the profile in use, which must be logged in.
+func (a *app) client() (*Client, error) {
+	_, profile := a.profile()
+	client := newClient(profile)
+	if a.server != "" {
+		client.BaseURL = strings.TrimRight(a.server, "/")
+	}
+	if client.Token == "" {
+		return nil, errNotLoggedIn
+	}
+	return client, nil
+}
+
+func (a *app) render(v interface{}, t *table) error {
+	return render(a.stdout, a.output, v, t)
+}
+
+// readLine reads a line of standard input.
+func (a *app) readLine() (string, error) {
+	if a.input == nil {
+		a.input = bufio.NewReader(a.stdin)
+	}
+	line, err := a.input.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// parseID parses the ID of a kind of resource given on the command line.
+func parseID(kind, s string) (uint, error) {
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid %s ID %q", kind, s)
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeServer answers the routes the CLI uses with canned data and records
+// the requests it received.
+type fakeServer struct {
+	*httptest.Server
+	requests []recordedRequest
+}
+
+type recordedRequest struct {
+	method, path, contentType, auth string
+	body                            map[string]interface{}
+}
+
+const fakeToken = "token-123"
+
+func newFakeServer(t *testing.T) *fakeServer {
+	f := &fakeServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := recordedRequest{method: r.Method, path: r.URL.Path, contentType: r.Header.Get("Content-Type"), auth: r.Header.Get("Authorization")}
+		json.NewDecoder(r.Body).Decode(&req.body)
+		f.requests = append(f.requests, req)
+
+		reply := func(data interface{}) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"message": "ok", "data": data})
+		}
+		fail := func(status int, code, detail string) {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "code": code, "detail": detail})
+		}
+		if r.URL.Path == "/login" {
+			if req.body["password"] != "secret" {
+				fail(http.StatusUnauthorized, "invalid_credentials", "Invalid credentials")
+				return
+			}
+			reply(map[string]string{"token": fakeToken})
+			return
+		}
+		if req.auth != fakeToken {
+			fail(http.StatusUnauthorized, "unauthorized", "Invalid token")
+			return
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /projects":
+			reply([]map[string]interface{}{
+				{"id": 1, "title": "Launch", "status": "active", "tasks": []map[string]interface{}{{"id": 10}}},
+				{"id": 2, "title": "Archive", "status": "archived"},
+			})
+		case "GET /projects/1/members":
+			reply([]map[string]interface{}{{"id": 4, "email": "ada@example.com", "name": "Ada"}})
+		case "PATCH /projects/1/tasks/10":
+			reply(map[string]interface{}{"id": 10, "title": "Ship", "status": "done", "priority": "high", "project_id": 1})
+		case "POST /graphql":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"project": map[string]interface{}{
+				"id": "1", "title": "Launch", "status": "active",
+				"columns": []map[string]interface{}{
+					{"id": "8", "name": "Review", "position": 2},
+					{"id": "7", "name": "Doing", "position": 1},
+				},
+				"tasks": []map[string]interface{}{
+					{"id": "10", "title": "Ship", "status": "in_progress", "column": map[string]string{"id": "7"}},
+					{"id": "11", "title": "Plan", "status": "todo"},
+				},
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// runCLI runs the CLI with the config file at configPath and returns what
+// it printed.
+func runCLI(t *testing.T, configPath, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var stdout bytes.Buffer
+	a := &app{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: io.Discard}
+	err := a.run(context.Background(), append([]string{"--config", configPath}, args...))
+	return stdout.String(), err
+}
+
+func loggedIn(t *testing.T, server *fakeServer) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if _, err := runCLI(t, path, "secret\n", "login", "--server", server.URL, "--email", "ada@example.com", "--password-stdin"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return path
+}
+
+func TestLogin(t *testing.T) {
+	server := newFakeServer(t)
+	path := filepath.Join(t.TempDir(), "kanban", "config.json")
+
+	if _, err := runCLI(t, path, "wrong\n", "login", "--server", server.URL, "--email", "ada@example.com", "--password-stdin"); err == nil || err.Error() != "Invalid credentials" {
+		t.Fatalf("login with a wrong password: %v", err)
+	}
+	if _, err := runCLI(t, path, "secret\n", "login", "--profile", "staging", "--server", server.URL+"/", "--password-stdin", "--email", "ada@example.com"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("config file mode = %v, want 0600", perm)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Profile{Server: server.URL, Email: "ada@example.com", Token: fakeToken}
+	if cfg.Current != "staging" || !reflect.DeepEqual(cfg.Profiles["staging"], want) {
+		t.Errorf("config = %+v, staging = %+v", cfg, cfg.Profiles["staging"])
+	}
+
+	if _, err := runCLI(t, path, "", "logout", "--profile", "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCLI(t, path, "", "projects", "ls"); !errors.Is(err, errNotLoggedIn) {
+		t.Errorf("projects ls after logout: %v, want %v", err, errNotLoggedIn)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	server := newFakeServer(t)
+	path := loggedIn(t, server)
+
+	if _, err := runCLI(t, path, "", "profiles", "use", "prod"); err == nil {
+		t.Error("profiles use accepted an unknown profile")
+	}
+	out, err := runCLI(t, path, "", "profiles", "ls", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var profiles []map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &profiles); err != nil {
+		t.Fatalf("profiles ls -o json printed %q: %v", out, err)
+	}
+	if len(profiles) != 1 || profiles[0]["name"] != "default" || profiles[0]["logged_in"] != true || profiles[0]["current"] != true {
+		t.Errorf("profiles = %v", profiles)
+	}
+}
+
+func TestListProjects(t *testing.T) {
+	server := newFakeServer(t)
+	path := loggedIn(t, server)
+
+	out, err := runCLI(t, path, "", "projects", "ls", "--status", "active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ID  TITLE   STATUS  TASKS\n1   Launch  active  1\n"
+	if out != want {
+		t.Errorf("projects ls printed\n%s\nwant\n%s", out, want)
+	}
+
+	out, err = runCLI(t, path, "", "--output", "json", "projects", "ls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var projects []project
+	if err := json.Unmarshal([]byte(out), &projects); err != nil || len(projects) != 2 {
+		t.Errorf("projects ls --output json printed %q", out)
+	}
+}
+
+func TestMoveTask(t *testing.T) {
+	tests := []struct {
+		target string
+		want   map[string]interface{}
+	}{
+		{target: "doing", want: map[string]interface{}{"column_id": float64(7)}},
+		{target: "8", want: map[string]interface{}{"column_id": float64(8)}},
+		{target: "done", want: map[string]interface{}{"column_id": nil, "status": "done"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			server := newFakeServer(t)
+			path := loggedIn(t, server)
+
+			if _, err := runCLI(t, path, "", "tasks", "move", "1", "10", tt.target); err != nil {
+				t.Fatal(err)
+			}
+			last := server.requests[len(server.requests)-1]
+			if last.method != http.MethodPatch || last.contentType != mergePatchContentType || !reflect.DeepEqual(last.body, tt.want) {
+				t.Errorf("sent %s %s %v, want a merge patch %v", last.method, last.contentType, last.body, tt.want)
+			}
+		})
+	}
+
+	server := newFakeServer(t)
+	path := loggedIn(t, server)
+	if _, err := runCLI(t, path, "", "tasks", "move", "1", "10", "Backlog"); err == nil {
+		t.Error("tasks move accepted an unknown column")
+	}
+}
+
+func TestAssignTask(t *testing.T) {
+	server := newFakeServer(t)
+	path := loggedIn(t, server)
+
+	for _, who := range []string{"ADA@example.com", "4"} {
+		if _, err := runCLI(t, path, "", "tasks", "assign", "1", "10", who); err != nil {
+			t.Fatal(err)
+		}
+		last := server.requests[len(server.requests)-1]
+		if !reflect.DeepEqual(last.body, map[string]interface{}{"assigned_to": float64(4)}) {
+			t.Errorf("assign %s sent %v", who, last.body)
+		}
+	}
+	if _, err := runCLI(t, path, "", "tasks", "assign", "1", "10", "bob@example.com"); err == nil {
+		t.Error("tasks assign accepted a non-member")
+	}
+}
+
+func TestShowBoard(t *testing.T) {
+	server := newFakeServer(t)
+	path := loggedIn(t, server)
+
+	out, err := runCLI(t, path, "", "board", "show", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "DOING (1)  REVIEW (0)  TODO (1)\n#10 Ship               #11 Plan\n"
+	if out != want {
+		t.Errorf("board show printed\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestBoardLanesWithoutColumns(t *testing.T) {
+	b := &board{Tasks: []graphTask{{ID: "1", Status: "done"}, {ID: "2", Status: "blocked"}}}
+	var got []string
+	for _, l := range b.lanes() {
+		got = append(got, l.Name+":"+taskIDs(l.Tasks))
+	}
+	want := []string{"todo:2", "in_progress:", "done:1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lanes = %v, want %v", got, want)
+	}
+}
+
+func taskIDs(tasks []graphTask) string {
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestUsageErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	for _, args := range [][]string{
+		{"tasks"},
+		{"projects", "rm"},
+		{"projects", "ls", "--output", "yaml"},
+		{"tasks", "move", "1", "2"},
+	} {
+		_, err := runCLI(t, path, "", args...)
+		var usage *usageError
+		if !errors.As(err, &usage) {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "field errors",
+			body: `{"status":422,"code":"validation_failed","detail":"Request validation failed","request_id":"r1","errors":[{"field":"title","in":"body","message":"property \"title\" is missing"}]}`,
+			want: `Request validation failed: title property "title" is missing`,
+		},
+		{
+			name: "single field",
+			body: `{"status":422,"code":"validation_failed","detail":"title is required","errors":[{"field":"title","in":"body","message":"title is required"}]}`,
+			want: "title is required",
+		},
+		{
+			name: "not a problem",
+			body: `<html>Bad Gateway</html>`,
+			want: "502 Bad Gateway",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := http.StatusUnprocessableEntity
+			if !strings.HasPrefix(tt.body, "{") {
+				status = http.StatusBadGateway
+			}
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(status)
+			rec.WriteString(tt.body)
+			resp := rec.Result()
+			resp.Status = fmt.Sprintf("%d %s", status, http.StatusText(status))
+
+			err := apiError(resp)
+			if err.Error() != tt.want {
+				t.Errorf("got %q, want %q", err, tt.want)
+			}
+			if apiErr := err.(*APIError); apiErr.Status != status {
+				t.Errorf("got status %d, want %d", apiErr.Status, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// project is a project as returned by the REST API.
+type project struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Tasks       []task    `json:"tasks,omitempty"`
+}
+
+func projectTable(projects ...project) *table {
+	t := &table{header: []string{"ID", "TITLE", "STATUS", "TASKS"}}
+	for _, p := range projects {
+		t.add(strconv.FormatUint(uint64(p.ID), 10), cell(truncate(p.Title, 50)), p.Status, strconv.Itoa(len(p.Tasks)))
+	}
+	return t
+}
+
+func (a *app) listProjects(ctx context.Context, args []string) error {
+	const usage = "projects ls [--status STATUS]"
+	fs := a.flagSet(usage)
+	status := fs.String("status", "", "only list projects with this status")
+	if _, err := a.parse(fs, usage, args, 0); err != nil {
+		return err
+	}
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	var projects []project
+	if err := client.do(ctx, http.MethodGet, "/projects", nil, &projects); err != nil {
+		return err
+	}
+	matching := []project{}
+	for _, p := range projects {
+		if *status == "" || p.Status == *status {
+			matching = append(matching, p)
+		}
+	}
+	return a.render(matching, projectTable(matching...))
+}
+
+func (a *app) createProject(ctx context.Context, args []string) error {
+	const usage = "projects create TITLE [--description TEXT]"
+	fs := a.flagSet(usage)
+	description := fs.String("description", "", "project description")
+	args, err := a.parse(fs, usage, args, 1)
+	if err != nil {
+		return err
+	}
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	var created project
+	body := map[string]string{"title": args[0], "description": *description}
+	if err := client.do(ctx, http.MethodPost, "/projects", body, &created); err != nil {
+		return err
+	}
+	return a.render(created, projectTable(created))
+}
+
+func (a *app) removeProject(ctx context.Context, args []string) error {
+	const usage = "projects rm PROJECT"
+	args, err := a.parse(a.flagSet(usage), usage, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID("project", args[0])
+	if err != nil {
+		return err
+	}
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(ctx, http.MethodDelete, fmt.Sprintf("/projects/%d", id), nil, nil); err != nil {
+		return err
+	}
+	t := &table{header: []string{"ID", "DELETED"}}
+	t.add(strconv.FormatUint(uint64(id), 10), "yes")
+	return a.render(map[string]interface{}{"id": id, "deleted": true}, t)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// taskStatuses are the status lanes that hold the tasks without a column.
+var taskStatuses = []string{"todo", "in_progress", "done"}
+
+// task is a task as returned by the REST API.
+type task struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	Priority    string    `json:"priority"`
+	DueDate     time.Time `json:"due_date"`
+	ProjectID   uint      `json:"project_id"`
+	ColumnID    *uint     `json:"column_id"`
+	AssignedTo  uint      `json:"assigned_to"`
+	Archived    bool      `json:"archived"`
+	Overdue     bool      `json:"overdue"`
+}
+
+type user struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+func taskTable(tasks ...task) *table {
+	t := &table{header: []string{"ID", "TITLE", "STATUS", "PRIORITY", "ASSIGNEE", "DUE", "COLUMN"}}
+	for _, tk := range tasks {
+		due := "-"
+		if !tk.DueDate.IsZero() {
+			due = tk.DueDate.Format("2006-01-02")
+			if tk.Overdue {
+				due += " (overdue)"
+			}
+		}
+		t.add(
+			strconv.FormatUint(uint64(tk.ID), 10),
+			cell(truncate(tk.Title, 50)),
+			tk.Status,
+			tk.Priority,
+			optionalID(tk.AssignedTo),
+			due,
+			optionalID(derefID(tk.ColumnID)),
+		)
+	}
+	return t
+}
+
+func optionalID(id uint) string {
+	if id == 0 {
+		return "-"
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func derefID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func (a *app) listTasks(ctx context.Context, args []string) error {
+	const usage = "tasks ls PROJECT [--status STATUS] [--all]"
+	fs := a.flagSet(usage)
+	status := fs.String("status", "", "only list tasks with this status")
+	all := fs.Bool("all", false, "include archived tasks")
+	args, err := a.parse(fs, usage, args, 1)
+	if err != nil {
+		return err
+	}
+	projectID, err := parseID("project", args[0])
+	if err != nil {
+		return err
+	}
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	var p project
+	if err := client.do(ctx, http.MethodGet, fmt.Sprintf("/projects/%d", projectID), nil, &p); err != nil {
+		return err
+	}
+	tasks := []task{}
+	for _, tk := range p.Tasks {
+		if (*status == "" || tk.Status == *status) && (*all || !tk.Archived) {
+			tasks = append(tasks, tk)
+		}
+	}
+	return a.render(tasks, taskTable(tasks...))
+}
+
+const createTaskMutation = `mutation CreateTask($projectId: ID!, $input: CreateTaskInput!) {
+  createTask(projectId: $projectId, input: $input) {
+    id title description status priority dueDate overdue archived
+    assignee { id }
+    column { id }
+  }
+}`
+
+// graphTask is a task as returned by the GraphQL API.
+type graphTask struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority"`
+	DueDate     *time.Time `json:"dueDate"`
+	Overdue     bool       `json:"overdue"`
+	Archived    bool       `json:"archived"`
+	Assignee    *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"assignee"`
+	Column *struct {
+		ID string `json:"id"`
+	} `json:"column"`
+}
+
+// rest converts t to the form the REST API returns.
+func (t graphTask) rest(projectID uint) task {
+	out := task{
+		ID:          atoID(t.ID),
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		Priority:    t.Priority,
+		ProjectID:   projectID,
+		Archived:    t.Archived,
+		Overdue:     t.Overdue,
+	}
+	if t.DueDate != nil {
+		out.DueDate = *t.DueDate
+	}
+	if t.Assignee != nil {
+		out.AssignedTo = atoID(t.Assignee.ID)
+	}
+	if t.Column != nil {
+		columnID := atoID(t.Column.ID)
+		out.ColumnID = &columnID
+	}
+	return out
+}
+
+func atoID(s string) uint {
+	id, _ := strconv.ParseUint(s, 10, 64)
+	return uint(id)
+}
+
+// addTask creates a task through GraphQL, as the REST API only creates
+// tasks in bulk.
+func (a *app) addTask(ctx context.Context, args []string) error {
+	const usage = "tasks add PROJECT TITLE [flags]"
+	fs := a.flagSet(usage)
+	description := fs.String("description", "", "task description")
+	status := fs.String("status", "", "todo, in_progress or done (default todo)")
+	priority := fs.String("priority", "", "low, medium or high (default medium)")
+	due := fs.String("due", "", "due `date`, as 2006-01-02 or RFC 3339")
+	assignee := fs.String("assignee", "", "assignee, by user ID or email")
+	column := fs.String("column", "", "column, by name or ID")
+	args, err := a.parse(fs, usage, args, 2)
+	if err != nil {
+		return err
+	}
+	projectID, err := parseID("project", args[0])
+	if err != nil {
+		return err
+	}
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	input := map[string]interface{}{"title": args[1]}
+	for key, value := range map[string]string{"description": *description, "status": *status, "priority": *priority} {
+		if value != "" {
+			input[key] = value
+		}
+	}
+	if *due != "" {
+		dueDate, err := parseDate(*due)
+		if err != nil {
+			return err
+		}
+		input["dueDate"] = dueDate.Format(time.RFC3339)
+	}
+	if *assignee != "" {
+		userID, err := findMember(ctx, client, projectID, *assignee)
+		if err != nil {
+			return err
+		}
+		input["assigneeId"] = strconv.FormatUint(uint64(userID), 10)
+	}
+	if *column != "" {
+		b, err := fetchBoard(ctx, client, projectID)
+		if err != nil {
+			return err
+		}
+		col, ok := b.column(*column)
+		if !ok {
+			return fmt.Errorf("project %d has no column %q", projectID, *column)
+		}
+		input["columnId"] = col.ID
+	}
+
+	var data struct {
+		CreateTask graphTask `json:"createTask"`
+	}
+	vars := map[string]interface{}{"projectId": strconv.FormatUint(uint64(projectID), 10), "input": input}
+	if err := client.graphQL(ctx, createTaskMutation, vars, &data); err != nil {
+		return err
+	}
+	created := data.CreateTask.rest(projectID)
+	return a.render(created, taskTable(created))
+}
+
+// moveTask moves a task to a column, or out of its column into the lane of
+// a status.
+func (a *app) moveTask(ctx context.Context, args []string) error {
+	const usage = "tasks move PROJECT TASK COLUMN"
+	args, err := a.parse(a.flagSet(usage), usage, args, 3)
+	if err != nil {
+		return err
+	}
+	projectID, err := parseID("project", args[0])
+	if err != nil {
+		return err
+	}
+	taskID, err := parseID("task", args[1])
+	if err != nil {
+		return err
+	}
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	b, err := fetchBoard(ctx, client, projectID)
+	if err != nil {
+		return err
+	}
+	var patch map[string]interface{}
+	if col, ok := b.column(args[2]); ok {
+		patch = map[string]interface{}{"column_id": atoID(col.ID)}
+	} else if status, ok := statusLane(args[2]); ok {
+		patch = map[string]interface{}{"column_id": nil, "status": status}
+	} else {
+		return fmt.Errorf("project %d has no column or status %q", projectID, args[2])
+	}
+	return a.patchTask(ctx, client, projectID, taskID, patch)
+}
+
+// assignTask assigns a task to a project member, or unassigns it for "none".
+func (a *app) assignTask(ctx context.Context, args []string) error {
+	const usage = "tasks assign PROJECT TASK USER"
+	args, err := a.parse(a.flagSet(usage), usage, args, 3)
+	if err != nil {
+		return err
+	}
+	projectID, err := parseID("project", args[0])
+	if err != nil {
+		return err
+	}
+	taskID, err := parseID("task", args[1])
+	if err != nil {
+		return err
+	}
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	patch := map[string]interface{}{"assigned_to": nil}
+	if !strings.EqualFold(args[2], "none") {
+		userID, err := findMember(ctx, client, projectID, args[2])
+		if err != nil {
+			return err
+		}
+		patch["assigned_to"] = userID
+	}
+	return a.patchTask(ctx, client, projectID, taskID, patch)
+}
+
+func (a *app) patchTask(ctx context.Context, client *Client, projectID, taskID uint, patch map[string]interface{}) error {
+	var updated task
+	path := fmt.Sprintf("/projects/%d/tasks/%d", projectID, taskID)
+	if err := client.do(ctx, http.MethodPatch, path, patch, &updated); err != nil {
+		return err
+	}
+	return a.render(updated, taskTable(updated))
+}
+
+// findMember resolves a user ID or email to a member of the project.
+func findMember(ctx context.Context, client *Client, projectID uint, who string) (uint, error) {
+	var members []user
+	if err := client.do(ctx, http.MethodGet, fmt.Sprintf("/projects/%d/members", projectID), nil, &members); err != nil {
+		return 0, err
+	}
+	for _, m := range members {
+		if strconv.FormatUint(uint64(m.ID), 10) == who || strings.EqualFold(m.Email, who) {
+			return m.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not a member of project %d", who, projectID)
+}
+
+func statusLane(s string) (string, bool) {
+	for _, status := range taskStatuses {
+		if strings.EqualFold(s, status) {
+			return status, true
+		}
+	}
+	return "", false
+}
+
+// parseDate parses a date as given on the command line: a day, taken as
+// midnight UTC, or an RFC 3339 time.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use 2006-01-02 or RFC 3339", s)
+	}
+	return t, nil
+}
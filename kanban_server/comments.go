@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kanban_server/problem"
+)
+
+type CommentRequest struct {
+	Body string `json:"body" validate:"required,notblank,max=10000"`
+}
+
+func (s *Server) getComments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := s.Tasks.ListComments(r.Context(), task.ID)
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch comments"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: comments,
+	})
+}
+
+// createComment adds a comment to a task. Project members mentioned in the
+// body are notified, and so is the assignee if they were not mentioned.
+func (s *Server) createComment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req CommentRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+
+	comment, err := s.Kanban.AddComment(r.Context(), currentUserID(r), task, req.Body)
+	if err != nil {
+		writeError(w, r, err, "Failed to create comment")
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Comment created successfully",
+		Data:    comment,
+	})
+}
@@ -0,0 +1,359 @@
+// Package config holds the server settings and opens the database.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config holds every server setting. Load reads it from, each overriding the
+// last, the defaults, a YAML file, environment variables and flags. Each
+// setting has a yaml key, an environment variable and a flag named after
+// the key: db.max_open_conns, DB_MAX_OPEN_CONNS and -db-max-open-conns.
+type Config struct {
+	// Env names the deployment, e.g. "production" or "test". In "test" the
+	// responses are checked against the OpenAPI document too.
+	Env     string  `yaml:"env" env:"APP_ENV" usage:"deployment name; \"test\" also checks responses against the OpenAPI document"`
+	HTTP    HTTP    `yaml:"http"`
+	GRPC    GRPC    `yaml:"grpc"`
+	DB      DB      `yaml:"db"`
+	Auth    Auth    `yaml:"auth"`
+	CORS    CORS    `yaml:"cors"`
+	Log     Log     `yaml:"log"`
+	Mail    Mail    `yaml:"mail"`
+	Jobs    Jobs    `yaml:"jobs"`
+	Tracing Tracing `yaml:"tracing"`
+}
+
+// HTTP configures the REST and GraphQL server.
+type HTTP struct {
+	Addr string `yaml:"addr" env:"HTTP_ADDR" usage:"address the HTTP API listens on"`
+	// PublicURL is the externally reachable base URL of the server, used for
+	// links in emails and calendar feeds.
+	PublicURL string `yaml:"public_url" env:"PUBLIC_URL" usage:"externally reachable base URL, used in links"`
+	// IdempotencyTTL is how long stored responses are replayed for a reused
+	// Idempotency-Key.
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" env:"IDEMPOTENCY_TTL" usage:"how long responses are replayed for a reused Idempotency-Key"`
+
+	// The timeouts bound how long a client may take over each part of a
+	// request, so that slow clients cannot hold connections open.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" usage:"time limit for reading request headers"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" usage:"time limit for reading a whole request"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" usage:"time limit for writing a response"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" usage:"how long an idle keep-alive connection stays open"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" env:"HTTP_MAX_HEADER_BYTES" usage:"largest request headers accepted"`
+	// MaxBodyBytes caps request bodies, except on routes such as the Trello
+	// import that set a limit of their own.
+	MaxBodyBytes int `yaml:"max_body_bytes" env:"HTTP_MAX_BODY_BYTES" usage:"largest request body accepted"`
+	// ShutdownTimeout is how long in-flight requests, websocket connections
+	// and workers get to finish once the server is asked to stop.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" usage:"time allowed for a graceful shutdown"`
+	// ShutdownDelay is how long the server keeps serving, with /readyz
+	// failing, before it starts to shut down, so that load balancers stop
+	// sending it requests first.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY" usage:"time readiness fails before shutting down"`
+}
+
+// GRPC configures the gRPC API, served next to the HTTP server.
+type GRPC struct {
+	Addr string `yaml:"addr" env:"GRPC_ADDR" usage:"address the gRPC API listens on"`
+}
+
+// DB configures the database connection and its pool.
+type DB struct {
+	// Driver is DriverPostgres or DriverSQLite.
+	Driver string `yaml:"driver" env:"DB_DRIVER" usage:"database driver, postgres or sqlite"`
+	// DSN is a PostgreSQL connection string or the path of the SQLite file.
+	// When it is empty, Load builds it from the older DB_HOST, DB_PORT,
+	// DB_USER, DB_PASSWORD, DB_NAME and DB_PATH variables.
+	DSN             string        `yaml:"dsn" env:"DB_DSN" usage:"PostgreSQL connection string or SQLite file"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" usage:"most open connections, 0 for no limit"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" usage:"most idle connections kept in the pool"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" usage:"how long a connection is reused, 0 for ever"`
+}
+
+// Auth configures the JWTs issued by login.
+type Auth struct {
+	// JWTSecret signs the tokens. It must be at least MinSecretLength bytes.
+	JWTSecret string        `yaml:"jwt_secret" env:"JWT_SECRET" usage:"key signing login tokens (required)"`
+	TokenTTL  time.Duration `yaml:"token_ttl" env:"JWT_TTL" usage:"how long login tokens are valid"`
+}
+
+// CORS lets browser apps on other origins call the API. It is off while
+// AllowedOrigins is empty.
+type CORS struct {
+	// AllowedOrigins are origins such as "https://board.example.com", or "*"
+	// for any.
+	AllowedOrigins []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" usage:"comma-separated origins allowed to call the API, or *"`
+	MaxAge         time.Duration `yaml:"max_age" env:"CORS_MAX_AGE" usage:"how long browsers may cache a preflight response"`
+}
+
+// Log configures the server log. At debug level every request is logged
+// with its headers.
+type Log struct {
+	Level  slog.Level `yaml:"level" env:"LOG_LEVEL" usage:"least severe level logged: debug, info, warn or error"`
+	Format string     `yaml:"format" env:"LOG_FORMAT" usage:"log record format: json or text"`
+}
+
+// Mail configures notification emails. Driver selects "smtp", "file" (a
+// maildir under Dir, for development) or "none", which disables email.
+type Mail struct {
+	Driver string `yaml:"driver" env:"MAIL_DRIVER" usage:"email transport: smtp, file or none"`
+	From   string `yaml:"from" env:"MAIL_FROM" usage:"sender of notification emails"`
+	Dir    string `yaml:"dir" env:"MAIL_DIR" usage:"maildir written by the file driver"`
+	SMTP   SMTP   `yaml:"smtp"`
+}
+
+// SMTP configures the smtp mail driver.
+type SMTP struct {
+	Host     string `yaml:"host" env:"SMTP_HOST" usage:"SMTP server host"`
+	Port     string `yaml:"port" env:"SMTP_PORT" usage:"SMTP server port"`
+	Username string `yaml:"username" env:"SMTP_USERNAME" usage:"SMTP user"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD" usage:"SMTP password"`
+}
+
+// Jobs configures the background workers.
+type Jobs struct {
+	// RecurrenceInterval is how often the recurrence scheduler looks for
+	// occurrences to create.
+	RecurrenceInterval time.Duration `yaml:"recurrence_interval" env:"RECURRENCE_INTERVAL" usage:"how often recurring tasks are created"`
+	// ReminderInterval is how often due dates are checked for reminders and
+	// overdue tasks.
+	ReminderInterval time.Duration `yaml:"reminder_interval" env:"REMINDER_INTERVAL" usage:"how often due dates are checked for reminders"`
+	// ReminderEscalateAfter is how long a task stays overdue before the
+	// project members are notified too.
+	ReminderEscalateAfter time.Duration `yaml:"reminder_escalate_after" env:"REMINDER_ESCALATE_AFTER" usage:"how long a task is overdue before its members are notified"`
+	// WebhookInterval is how often the webhook queue is checked for due
+	// deliveries.
+	WebhookInterval time.Duration `yaml:"webhook_interval" env:"WEBHOOK_INTERVAL" usage:"how often the webhook queue is checked"`
+	// WebhookTimeout bounds a single webhook request.
+	WebhookTimeout time.Duration `yaml:"webhook_timeout" env:"WEBHOOK_TIMEOUT" usage:"time limit of a webhook request"`
+	// DigestInterval is how often users receive a digest of their unread
+	// notifications.
+	DigestInterval time.Duration `yaml:"digest_interval" env:"DIGEST_INTERVAL" usage:"how often notification digests are sent"`
+	// IdempotencyPurgeInterval is how often expired idempotency keys are
+	// deleted.
+	IdempotencyPurgeInterval time.Duration `yaml:"idempotency_purge_interval" env:"IDEMPOTENCY_PURGE_INTERVAL" usage:"how often expired idempotency keys are deleted"`
+}
+
+// Tracing configures the OpenTelemetry spans recorded for requests and
+// database queries. Exporter selects "otlp", which sends them over gRPC to
+// Endpoint, or "none", which records nothing.
+type Tracing struct {
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" usage:"span exporter: otlp or none"`
+	// Endpoint is the URL of the OTLP collector. An http URL sends spans
+	// without TLS.
+	Endpoint    string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" usage:"URL of the OTLP gRPC collector"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME" usage:"service name spans are reported under"`
+	// SampleRatio is the fraction of traces started here that are recorded.
+	// Requests that arrive with a trace follow its sampling decision.
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"fraction of new traces recorded, 0 to 1"`
+}
+
+// Default returns the settings used where nothing else is configured. It
+// has no JWT secret, which must always be given.
+func Default() *Config {
+	return &Config{
+		Env: "development",
+		HTTP: HTTP{
+			Addr:              ":5000",
+			PublicURL:         "http://localhost:5000",
+			IdempotencyTTL:    24 * time.Hour,
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       time.Minute,
+			WriteTimeout:      time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      10 << 20,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		GRPC: GRPC{Addr: ":5001"},
+		DB: DB{
+			Driver:          DriverPostgres,
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+		},
+		Auth: Auth{TokenTTL: 24 * time.Hour},
+		CORS: CORS{MaxAge: 10 * time.Minute},
+		Log:  Log{Level: slog.LevelInfo, Format: "json"},
+		Mail: Mail{
+			Driver: "none",
+			From:   "Kanban <no-reply@localhost>",
+			Dir:    "tmp/mail",
+			SMTP:   SMTP{Host: "localhost", Port: "587"},
+		},
+		Jobs: Jobs{
+			RecurrenceInterval:       time.Minute,
+			ReminderInterval:         time.Minute,
+			ReminderEscalateAfter:    24 * time.Hour,
+			WebhookInterval:          10 * time.Second,
+			WebhookTimeout:           10 * time.Second,
+			DigestInterval:           24 * time.Hour,
+			IdempotencyPurgeInterval: time.Hour,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4317",
+			ServiceName: "kanban_server",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// MinSecretLength is the shortest JWT secret accepted, 256 bits as HS256
+// expects.
+const MinSecretLength = 32
+
+// publishedSecrets have appeared in examples and in earlier versions of
+// this repository, so they protect nothing.
+var publishedSecrets = []string{
+	"your-secret-key",
+	"secret",
+	"changeme",
+	"change-me",
+	"password",
+	"Charles#123",
+}
+
+func published(secret string) bool {
+	for _, s := range publishedSecrets {
+		if strings.EqualFold(secret, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate reports every setting that is missing, malformed or insecure.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.HTTP.Addr == "" {
+		fail("http.addr (HTTP_ADDR) is required")
+	}
+	if u, err := url.Parse(c.HTTP.PublicURL); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("http.public_url (PUBLIC_URL) must be an absolute URL, got %q", c.HTTP.PublicURL)
+	}
+	if c.HTTP.MaxHeaderBytes <= 0 || c.HTTP.MaxBodyBytes <= 0 {
+		fail("http.max_header_bytes and http.max_body_bytes must be positive")
+	}
+	if c.HTTP.ShutdownDelay < 0 {
+		fail("http.shutdown_delay (SHUTDOWN_DELAY) must not be negative")
+	}
+	if c.GRPC.Addr == "" {
+		fail("grpc.addr (GRPC_ADDR) is required")
+	}
+
+	switch c.DB.Driver {
+	case DriverPostgres, DriverSQLite:
+	default:
+		fail("db.driver (DB_DRIVER) must be %s or %s, got %q", DriverPostgres, DriverSQLite, c.DB.Driver)
+	}
+	if c.DB.DSN == "" {
+		fail("db.dsn (DB_DSN) is required")
+	} else if published(dsnPassword(c.DB.DSN)) {
+		fail("db.dsn (DB_DSN) uses a published password, choose another")
+	}
+	if c.DB.MaxOpenConns < 0 || c.DB.MaxIdleConns < 0 || c.DB.ConnMaxLifetime < 0 {
+		fail("db pool settings must not be negative")
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		fail("db.max_idle_conns (%d) must not exceed db.max_open_conns (%d)", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	}
+
+	switch {
+	case c.Auth.JWTSecret == "":
+		fail("auth.jwt_secret (JWT_SECRET) is required")
+	case published(c.Auth.JWTSecret):
+		fail("auth.jwt_secret (JWT_SECRET) is a published example, choose a random one")
+	case len(c.Auth.JWTSecret) < MinSecretLength:
+		fail("auth.jwt_secret (JWT_SECRET) must be at least %d bytes, got %d", MinSecretLength, len(c.Auth.JWTSecret))
+	}
+	if published(c.Mail.SMTP.Password) {
+		fail("mail.smtp.password (SMTP_PASSWORD) is a published example, choose another")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			fail("cors.allowed_origins (CORS_ALLOWED_ORIGINS) has %q, which is not an origin such as https://example.com", origin)
+		}
+	}
+
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		fail("log.format (LOG_FORMAT) must be json or text, got %q", c.Log.Format)
+	}
+
+	switch c.Mail.Driver {
+	case "smtp", "file", "none":
+	default:
+		fail("mail.driver (MAIL_DRIVER) must be smtp, file or none, got %q", c.Mail.Driver)
+	}
+
+	switch c.Tracing.Exporter {
+	case "otlp":
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("tracing.endpoint (OTEL_EXPORTER_OTLP_ENDPOINT) must be an http or https URL, got %q", c.Tracing.Endpoint)
+		}
+	case "none":
+	default:
+		fail("tracing.exporter (TRACING_EXPORTER) must be otlp or none, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	for _, d := range []struct {
+		setting string
+		value   time.Duration
+	}{
+		{"http.idempotency_ttl (IDEMPOTENCY_TTL)", c.HTTP.IdempotencyTTL},
+		{"http.read_header_timeout (HTTP_READ_HEADER_TIMEOUT)", c.HTTP.ReadHeaderTimeout},
+		{"http.read_timeout (HTTP_READ_TIMEOUT)", c.HTTP.ReadTimeout},
+		{"http.write_timeout (HTTP_WRITE_TIMEOUT)", c.HTTP.WriteTimeout},
+		{"http.idle_timeout (HTTP_IDLE_TIMEOUT)", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout (SHUTDOWN_TIMEOUT)", c.HTTP.ShutdownTimeout},
+		{"auth.token_ttl (JWT_TTL)", c.Auth.TokenTTL},
+		{"jobs.recurrence_interval (RECURRENCE_INTERVAL)", c.Jobs.RecurrenceInterval},
+		{"jobs.reminder_interval (REMINDER_INTERVAL)", c.Jobs.ReminderInterval},
+		{"jobs.reminder_escalate_after (REMINDER_ESCALATE_AFTER)", c.Jobs.ReminderEscalateAfter},
+		{"jobs.webhook_interval (WEBHOOK_INTERVAL)", c.Jobs.WebhookInterval},
+		{"jobs.webhook_timeout (WEBHOOK_TIMEOUT)", c.Jobs.WebhookTimeout},
+		{"jobs.digest_interval (DIGEST_INTERVAL)", c.Jobs.DigestInterval},
+		{"jobs.idempotency_purge_interval (IDEMPOTENCY_PURGE_INTERVAL)", c.Jobs.IdempotencyPurgeInterval},
+	} {
+		if d.value <= 0 {
+			fail("%s must be positive, got %s", d.setting, d.value)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// dsnPassword returns the password in a PostgreSQL URL or key=value
+// connection string.
+func dsnPassword(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		password, _ := u.User.Password()
+		return password
+	}
+	for _, field := range strings.Fields(dsn) {
+		if password, ok := strings.CutPrefix(field, "password="); ok {
+			return strings.Trim(password, "'")
+		}
+	}
+	return ""
+}
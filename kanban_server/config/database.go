@@ -1,50 +1,159 @@
 package config
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"os"
+	"strings"
+	"time"
 
 	"kanban_server/models"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var DB *gorm.DB
+// Database drivers for DB.Driver.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
 
-func InitDB() {
-	// Get database configuration from environment variables
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbUser := getEnv("DB_USER", "postgres")
-	dbPassword := getEnv("DB_PASSWORD", "Charles#123")
-	dbName := getEnv("DB_NAME", "kanban")
-	dbPort := getEnv("DB_PORT", "5432")
+// OpenDB connects to the configured database, sizes its connection pool and
+// migrates it.
+func OpenDB(cfg DB) (*gorm.DB, error) {
+	db, err := Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return db, nil
+}
 
-	// Create connection string
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		dbHost, dbUser, dbPassword, dbName, dbPort)
+// Open connects to dsn with the named driver and migrates the schema.
+func Open(driver, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	case DriverSQLite:
+		// SQLite leaves foreign keys off unless asked, and allows a single
+		// writer, so writers wait for the lock instead of failing.
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dialector = sqlite.Open(dsn + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
 
-	// Open database connection
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return nil, fmt.Errorf("connecting to the database: %w", err)
+	}
+	if err := Migrate(db); err != nil {
+		return nil, fmt.Errorf("migrating the database: %w", err)
 	}
+	return db, nil
+}
+
+// SchemaVersion is the version of the schema Migrate creates. Raise it
+// whenever a model changes, so that a database not yet migrated by this
+// build is reported as not ready.
+const SchemaVersion = 3
 
-	// Auto migrate the schema
-	err = db.AutoMigrate(&models.User{}, &models.Project{}, &models.Task{})
+// upgrades change existing data for a new schema version. Each runs once,
+// in order, on a database migrated to an earlier version, after the tables
+// were updated.
+var upgrades = []struct {
+	version int
+	run     func(tx *gorm.DB) error
+}{
+	{3, backfillMembers},
+}
+
+// Migrate creates or updates the tables of every model, runs the pending
+// upgrades and records SchemaVersion.
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&models.SchemaMigration{},
+		&models.User{},
+		&models.Project{},
+		&models.Task{},
+		&models.Column{},
+		&models.Label{},
+		&models.Comment{},
+		&models.ProjectTemplate{},
+		&models.Recurrence{},
+		&models.ReminderPreference{},
+		&models.ReminderLog{},
+		&models.Notification{},
+		&models.NotificationMute{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.EmailPreference{},
+		&models.CalendarFeed{},
+		&models.IdempotencyKey{},
+	)
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		return err
 	}
 
-	DB = db
-	log.Println("Database connection established")
+	migrated, err := MigratedVersion(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	for _, upgrade := range upgrades {
+		if upgrade.version <= migrated {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := upgrade.run(tx); err != nil {
+				return err
+			}
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.SchemaMigration{Version: upgrade.version, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("upgrading to version %d: %w", upgrade.version, err)
+		}
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.SchemaMigration{Version: SchemaVersion, AppliedAt: time.Now()}).Error
 }
 
-// Helper function to get environment variables with fallback
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// backfillMembers gives members to the projects created before membership
+// was required, which have none and could be opened by every user. Who
+// created them was not recorded, so the users assigned to their tasks become
+// members, or every user for projects without assignees.
+func backfillMembers(tx *gorm.DB) error {
+	const memberless = "projects.id NOT IN (SELECT project_id FROM user_projects)"
+	err := tx.Exec(`INSERT INTO user_projects (project_id, user_id)
+		SELECT DISTINCT projects.id, users.id FROM projects
+		JOIN tasks ON tasks.project_id = projects.id
+		JOIN users ON users.id = tasks.assigned_to
+		WHERE ` + memberless).Error
+	if err != nil {
+		return err
 	}
-	return fallback
+	return tx.Exec(`INSERT INTO user_projects (project_id, user_id)
+		SELECT projects.id, users.id FROM projects CROSS JOIN users
+		WHERE ` + memberless).Error
+}
+
+// MigratedVersion returns the latest schema version the database was
+// migrated to, or 0 if it never was.
+func MigratedVersion(ctx context.Context, db *gorm.DB) (int, error) {
+	var version int
+	err := db.WithContext(ctx).Model(&models.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
 }
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the variable holding the path of the YAML config file, which
+// the -config flag overrides.
+const FileEnv = "CONFIG_FILE"
+
+// Load reads the settings from, each overriding the last, the defaults, the
+// YAML file named by -config or CONFIG_FILE, environment variables and the
+// flags in args. It fails when a setting is malformed, or when Validate
+// does. With -h it prints the flags and returns flag.ErrHelp.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	flags := flag.NewFlagSet("kanban_server", flag.ContinueOnError)
+	path := flags.String("config", os.Getenv(FileEnv), "YAML file to read settings from (env "+FileEnv+")")
+	for _, s := range settings {
+		flags.String(s.flag(), s.String(), s.usage+" (env "+s.env+")")
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	if *path != "" {
+		if err := cfg.readFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if flagErr == nil && f.Name == s.flag() {
+				if err := s.set(f.Value.String()); err != nil {
+					flagErr = fmt.Errorf("-%s: %w", f.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if cfg.DB.DSN == "" {
+		cfg.DB.DSN = legacyDSN(cfg.DB.Driver)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readFile decodes the YAML file at path over c. Unknown keys are errors, so
+// that typos do not go unnoticed.
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
+
+// legacyDSN builds the connection string from the variables used before
+// DB_DSN existed. There is no default password.
+func legacyDSN(driver string) string {
+	if driver == DriverSQLite {
+		return getEnv("DB_PATH", "kanban.db")
+	}
+	dsn := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable",
+		getEnv("DB_HOST", "localhost"),
+		getEnv("DB_PORT", "5432"),
+		getEnv("DB_USER", "postgres"),
+		getEnv("DB_NAME", "kanban"))
+	if password := os.Getenv("DB_PASSWORD"); password != "" {
+		dsn += " password=" + password
+	}
+	return dsn
+}
+
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return fallback
+}
+
+// setting is one leaf of Config, found through its struct tags.
+type setting struct {
+	key   string // yaml path, e.g. "db.max_open_conns"
+	env   string
+	usage string
+	value reflect.Value
+}
+
+// settings lists the leaves of c in declaration order.
+func (c *Config) settings() []setting {
+	var settings []setting
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			key := prefix + field.Tag.Get("yaml")
+			if env, ok := field.Tag.Lookup("env"); ok {
+				settings = append(settings, setting{key: key, env: env, usage: field.Tag.Get("usage"), value: v.Field(i)})
+			} else if field.Type.Kind() == reflect.Struct {
+				walk(key+".", v.Field(i))
+			}
+		}
+	}
+	walk("", reflect.ValueOf(c).Elem())
+	return settings
+}
+
+// flag returns the flag name, the key with dashes: "db-max-open-conns".
+func (s setting) flag() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// String formats the current value the way set parses it.
+func (s setting) String() string {
+	switch v := s.value.Addr().Interface().(type) {
+	case *[]string:
+		return strings.Join(*v, ",")
+	case encoding.TextMarshaler:
+		text, _ := v.MarshalText()
+		return string(text)
+	}
+	return fmt.Sprint(s.value.Interface())
+}
+
+// set parses value into the setting. Lists are comma-separated.
+func (s setting) set(value string) error {
+	switch v := s.value.Addr().Interface().(type) {
+	case *string:
+		*v = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*v = n
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*v = f
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 24h", value)
+		}
+		*v = d
+	case *[]string:
+		*v = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*v = append(*v, item)
+			}
+		}
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText([]byte(value))
+	default:
+		panic("config: unsupported setting type " + s.value.Type().String())
+	}
+	return nil
+}
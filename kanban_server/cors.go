@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"kanban_server/config"
+)
+
+// corsMiddleware lets browser apps served from the allowed origins call the
+// API. It answers preflight requests itself, since the router has no OPTIONS
+// routes, and passes everything else on. Tokens travel in the Authorization
+// header rather than cookies, so credentials are never allowed.
+func corsMiddleware(cfg config.CORS) func(http.Handler) http.Handler {
+	anyOrigin := false
+	allowed := map[string]bool{}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+			if origin == "" || !(anyOrigin || allowed[origin]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			if anyOrigin {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			h.Set("Access-Control-Expose-Headers", "Content-Disposition, Idempotent-Replayed, X-Request-ID")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
+				h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Idempotency-Key, X-Request-ID")
+				if cfg.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"kanban_server/config"
+	"kanban_server/mailer"
+	"kanban_server/problem"
+)
+
+// EmailPreferenceRequest changes the email settings it includes; the others
+// are left as they are.
+type EmailPreferenceRequest struct {
+	Assignments *bool `json:"assignments"`
+	Mentions    *bool `json:"mentions"`
+	Digest      *bool `json:"digest"`
+}
+
+// newMailer returns the transport selected by cfg.Driver, or nil when email
+// is disabled.
+func newMailer(cfg config.Mail) (mailer.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mailer.SMTPMailer{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+		}, nil
+	case "file":
+		return &mailer.FileMailer{Dir: cfg.Dir}, nil
+	case "none", "":
+		return nil, nil
+	}
+	return nil, errors.New("mail driver must be smtp, file or none")
+}
+
+func (s *Server) getEmailPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pref, err := mailer.Preference(s.db(r), currentUserID(r))
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch email preferences"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: pref,
+	})
+}
+
+func (s *Server) updateEmailPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req EmailPreferenceRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	pref, err := mailer.Preference(s.db(r), currentUserID(r))
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to update email preferences"))
+		return
+	}
+	updates := map[string]interface{}{}
+	for column, value := range map[string]*bool{
+		"assignments": req.Assignments,
+		"mentions":    req.Mentions,
+		"digest":      req.Digest,
+	} {
+		if value != nil {
+			updates[column] = *value
+		}
+	}
+	if len(updates) > 0 {
+		err = s.db(r).Model(&pref).Updates(updates).Error
+		if err != nil {
+			problem.Write(w, r, problem.Internal("Failed to update email preferences"))
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Email preferences updated",
+		Data:    pref,
+	})
+}
+
+// unsubscribe handles the links in emails. It needs no login; the token
+// identifies the user. A GET only shows a page asking to confirm, so that mail
+// scanners following the link do not unsubscribe anyone; the page, and
+// one-click List-Unsubscribe clients (RFC 8058), POST to unsubscribe.
+func (s *Server) unsubscribe(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	token, kind := query.Get("token"), query.Get("type")
+
+	var err error
+	if r.Method == http.MethodPost {
+		err = mailer.Unsubscribe(s.db(r), token, kind)
+	} else {
+		err = mailer.CheckUnsubscribe(s.db(r), token, kind)
+	}
+	if errors.Is(err, mailer.ErrInvalidUnsubscribe) {
+		problem.Write(w, r, problem.Param("query", "token", "Invalid unsubscribe link"))
+		return
+	}
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to unsubscribe"))
+		return
+	}
+
+	// One-click clients get JSON; browsers get a page.
+	if r.Method == http.MethodPost && !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RouteResponse{
+			Message: "You have been unsubscribed",
+		})
+		return
+	}
+
+	page, err := mailer.RenderUnsubscribePage(mailer.UnsubscribePage{Kind: kind, Done: r.Method == http.MethodPost})
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to render page"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, page)
+}
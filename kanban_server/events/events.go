@@ -0,0 +1,106 @@
+// Package events publishes board changes to in-process subscribers such as
+// the webhook dispatcher.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	ProjectUpdated = "project.updated"
+	ProjectDeleted = "project.deleted"
+	TaskCreated    = "task.created"
+	TaskUpdated    = "task.updated"
+	TaskDeleted    = "task.deleted"
+	CommentCreated = "comment.created"
+	MemberAdded    = "member.added"
+	MemberRemoved  = "member.removed"
+)
+
+// Types lists every event type that can be subscribed to.
+var Types = []string{
+	ProjectUpdated,
+	ProjectDeleted,
+	TaskCreated,
+	TaskUpdated,
+	TaskDeleted,
+	CommentCreated,
+	MemberAdded,
+	MemberRemoved,
+}
+
+// Event is a change to one project.
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	ProjectID  uint        `json:"project_id"`
+	ActorID    uint        `json:"actor_id,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// New returns an event with a fresh ID and the current time.
+func New(eventType string, projectID, actorID uint, data interface{}) Event {
+	return Event{
+		ID:         newID(),
+		Type:       eventType,
+		ProjectID:  projectID,
+		ActorID:    actorID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Handler receives published events. Handlers run synchronously in the
+// publishing goroutine and must not block for long.
+type Handler func(Event)
+
+// Bus fans events out to its subscribers. The zero value is not usable, use
+// NewBus.
+type Bus struct {
+	mu       sync.RWMutex
+	next     int
+	handlers map[int]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[int]Handler)}
+}
+
+// Subscribe registers h and returns a function that removes it again.
+func (b *Bus) Subscribe(h Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.handlers[id] = h
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish delivers e to every current subscriber.
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers))
+	for _, h := range b.handlers {
+		handlers = append(handlers, h)
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
@@ -0,0 +1,29 @@
+package events
+
+import "testing"
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+
+	var first, second []string
+	unsubscribe := bus.Subscribe(func(e Event) { first = append(first, e.Type) })
+	bus.Subscribe(func(e Event) { second = append(second, e.Type) })
+
+	bus.Publish(New(TaskCreated, 1, 2, nil))
+	unsubscribe()
+	bus.Publish(New(TaskDeleted, 1, 2, nil))
+
+	if len(first) != 1 || first[0] != TaskCreated {
+		t.Errorf("first subscriber got %v, want [%s]", first, TaskCreated)
+	}
+	if len(second) != 2 {
+		t.Errorf("second subscriber got %v, want two events", second)
+	}
+}
+
+func TestNewAssignsUniqueIDs(t *testing.T) {
+	a, b := New(TaskCreated, 1, 0, nil), New(TaskCreated, 1, 0, nil)
+	if a.ID == "" || a.ID == b.ID {
+		t.Errorf("event IDs %q and %q are not unique", a.ID, b.ID)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/problem"
+	"kanban_server/transfer"
+	"kanban_server/trello"
+
+	"gorm.io/gorm"
+)
+
+const csvExportBatchSize = 200
+
+var taskCSVHeader = []string{
+	"id", "title", "description", "status", "priority", "due_date", "column",
+	"assignee_email", "labels", "archived", "created_at", "updated_at",
+}
+
+func (s *Server) exportProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		format = "csv"
+	}
+
+	switch format {
+	case "", "json":
+		doc, err := transfer.Export(s.db(r), project.ID)
+		if err != nil {
+			problem.Write(w, r, problem.Internal("Failed to export project"))
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="project-%d.json"`, project.ID))
+		json.NewEncoder(w).Encode(doc)
+	case "csv":
+		s.exportTasksCSV(w, r, project)
+	default:
+		problem.Write(w, r, problem.Param("query", "format", "Unsupported export format, use json or csv"))
+	}
+}
+
+// exportTasksCSV streams the project's tasks in batches so that large
+// boards are never held in memory at once.
+func (s *Server) exportTasksCSV(w http.ResponseWriter, r *http.Request, project models.Project) {
+	var columns []models.Column
+	s.db(r).Where("project_id = ?", project.ID).Find(&columns)
+	columnNames := make(map[uint]string, len(columns))
+	for _, column := range columns {
+		columnNames[column.ID] = column.Name
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="project-%d-tasks.csv"`, project.ID))
+
+	writer := csv.NewWriter(w)
+	writer.Write(taskCSVHeader)
+
+	emails := make(map[uint]string)
+	var tasks []models.Task
+	s.db(r).Preload("Labels").Where("project_id = ?", project.ID).Order("id").
+		FindInBatches(&tasks, csvExportBatchSize, func(tx *gorm.DB, batch int) error {
+			if _, err := transfer.UserEmails(s.db(r), tasks, emails); err != nil {
+				return err
+			}
+			for _, task := range tasks {
+				writer.Write(taskCSVRecord(task, columnNames, emails))
+			}
+			writer.Flush()
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			return writer.Error()
+		})
+	writer.Flush()
+}
+
+func taskCSVRecord(task models.Task, columnNames map[uint]string, emails map[uint]string) []string {
+	var dueDate, column string
+	if !task.DueDate.IsZero() {
+		dueDate = task.DueDate.UTC().Format(time.RFC3339)
+	}
+	if task.ColumnID != nil {
+		column = columnNames[*task.ColumnID]
+	}
+	labels := make([]string, 0, len(task.Labels))
+	for _, label := range task.Labels {
+		labels = append(labels, label.Name)
+	}
+
+	return []string{
+		strconv.FormatUint(uint64(task.ID), 10),
+		task.Title,
+		task.Description,
+		task.Status,
+		task.Priority,
+		dueDate,
+		column,
+		emails[task.AssignedTo],
+		strings.Join(labels, ";"),
+		strconv.FormatBool(task.Archived),
+		task.CreatedAt.UTC().Format(time.RFC3339),
+		task.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func (s *Server) importProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var doc transfer.Document
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		problem.Write(w, r, problem.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := doc.Validate(); err != nil {
+		problem.Write(w, r, problem.Invalid("Invalid export document: "+err.Error()))
+		return
+	}
+
+	report, err := transfer.Import(s.db(r), &doc, currentUserID(r))
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to import project"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Project imported successfully",
+		Data:    report,
+	})
+}
+
+const maxTrelloExportSize = 50 << 20
+
+// importTrelloBoard imports a Trello board export. The export is sent as the
+// "file" field of a multipart form, optionally with a "members" field holding
+// a JSON object that maps Trello usernames (or member IDs) to emails, or as a
+// plain JSON body. With ?dry_run=true nothing is written and only the report
+// is returned.
+func (s *Server) importTrelloBoard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	r.Body = http.MaxBytesReader(w, r.Body, maxTrelloExportSize)
+
+	var board *trello.Board
+	emails := map[string]string{}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			problem.Write(w, r, problem.Field("file", "Trello export file is required"))
+			return
+		}
+		defer file.Close()
+
+		if board, err = trello.Parse(file); err != nil {
+			problem.Write(w, r, problem.Field("file", err.Error()))
+			return
+		}
+		if members := r.FormValue("members"); members != "" {
+			if err := json.Unmarshal([]byte(members), &emails); err != nil {
+				problem.Write(w, r, problem.Field("members", "members must be a JSON object of Trello usernames to emails"))
+				return
+			}
+		}
+	} else {
+		var err error
+		if board, err = trello.Parse(r.Body); err != nil {
+			problem.Write(w, r, problem.Invalid(err.Error()))
+			return
+		}
+	}
+
+	doc, report := trello.Convert(board, emails)
+	if err := doc.Validate(); err != nil {
+		problem.Write(w, r, problem.Invalid("Trello export could not be converted: "+err.Error()))
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	importer := transfer.Import
+	if dryRun {
+		importer = transfer.DryRun
+	}
+
+	result, err := importer(s.db(r), doc, currentUserID(r))
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to import Trello board"))
+		return
+	}
+	report.Import = result
+
+	message := "Trello board imported successfully"
+	if dryRun {
+		message = "Dry run completed, nothing was imported"
+	}
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: message,
+		Data:    report,
+	})
+}
@@ -0,0 +1,88 @@
+// Package graph serves the GraphQL API. Every operation runs as the user
+// authenticated by the handler and only sees projects that user is a member
+// of. Related records are fetched through per-request dataloaders, so a query
+// costs one SQL statement per level rather than one per object.
+package graph
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"strconv"
+
+	"kanban_server/service"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxDepth limits how deeply queries may nest.
+const maxDepth = 10
+
+var (
+	errUnauthenticated = errors.New("authentication required")
+	errProjectNotFound = service.ErrProjectNotFound
+	errTaskNotFound    = service.ErrTaskNotFound
+	errInvalidID       = errors.New("invalid ID")
+)
+
+// Resolver is the root resolver. Project, task, comment and member changes
+// go through the embedded service, which the REST and gRPC APIs share;
+// labels are written to DB directly.
+type Resolver struct {
+	*service.Service
+	DB *gorm.DB
+}
+
+// NewSchema parses the schema and binds it to r.
+func NewSchema(r *Resolver) (*graphql.Schema, error) {
+	return graphql.ParseSchema(schemaSDL, r,
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(maxDepth),
+	)
+}
+
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	loadersKey
+)
+
+// WithUserID returns a context for operations run as userID.
+func WithUserID(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+func currentUserID(ctx context.Context) (uint, error) {
+	userID, _ := ctx.Value(userIDKey).(uint)
+	if userID == 0 {
+		return 0, errUnauthenticated
+	}
+	return userID, nil
+}
+
+// authorize reports errProjectNotFound unless the current user is a member of
+// the project, applying the rule the REST and gRPC APIs share.
+func (r *Resolver) authorize(ctx context.Context, projectID uint) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return r.Authorize(ctx, userID, projectID)
+}
+
+func parseID(id graphql.ID) (uint, error) {
+	n, err := strconv.ParseUint(string(id), 10, 64)
+	if err != nil {
+		return 0, errInvalidID
+	}
+	return uint(n), nil
+}
+
+func toID(id uint) graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(id), 10))
+}
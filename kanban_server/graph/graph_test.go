@@ -0,0 +1,197 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+func TestSchemaMatchesResolvers(t *testing.T) {
+	if _, err := NewSchema(&Resolver{}); err != nil {
+		t.Fatalf("NewSchema: %v", err)
+	}
+}
+
+// counterResolver is a minimal schema for exercising the transports without
+// a database.
+type counterResolver struct{}
+
+func (counterResolver) Whoami(ctx context.Context) (int32, error) {
+	userID, err := currentUserID(ctx)
+	return int32(userID), err
+}
+
+func (counterResolver) Count(ctx context.Context, args struct{ To int32 }) <-chan int32 {
+	c := make(chan int32)
+	go func() {
+		defer close(c)
+		for i := int32(1); i <= args.To; i++ {
+			select {
+			case c <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	schema := graphql.MustParseSchema(`
+		schema { query: Query subscription: Subscription }
+		type Query { whoami: Int! }
+		type Subscription { count(to: Int!): Int! }
+	`, &counterResolver{})
+	authenticate := func(token string) (uint, error) {
+		if token != "Bearer good" {
+			return 0, errors.New("invalid token")
+		}
+		return 7, nil
+	}
+	server := httptest.NewServer(NewHandler(schema, nil, authenticate))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHandlerPost(t *testing.T) {
+	server := newTestServer(t)
+
+	post := func(token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"query":"{ whoami }"}`))
+		req.Header.Set("Authorization", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post("Bearer bad")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("bad token: status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	resp = post("Bearer good")
+	defer resp.Body.Close()
+	var body struct {
+		Data struct{ Whoami int }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.Whoami != 7 {
+		t.Errorf("whoami = %d, want 7", body.Data.Whoami)
+	}
+}
+
+func dial(t *testing.T, server *httptest.Server) *websocket.Conn {
+	t.Helper()
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestWebsocketSubscription(t *testing.T) {
+	conn := dial(t, newTestServer(t))
+
+	conn.WriteJSON(wsMessage{Type: msgConnectionInit, Payload: json.RawMessage(`{"Authorization":"Bearer good"}`)})
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != msgConnectionAck {
+		t.Fatalf("after connection_init got %+v (%v), want %s", msg, err, msgConnectionAck)
+	}
+
+	conn.WriteJSON(wsMessage{Type: msgPing})
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != msgPong {
+		t.Fatalf("after ping got %+v (%v), want %s", msg, err, msgPong)
+	}
+
+	conn.WriteJSON(wsMessage{ID: "1", Type: msgSubscribe, Payload: json.RawMessage(`{"query":"subscription { count(to: 3) }"}`)})
+	var counts []int
+	for {
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.ID != "1" {
+			t.Fatalf("message for operation %q, want 1", msg.ID)
+		}
+		if msg.Type == msgComplete {
+			break
+		}
+		if msg.Type != msgNext {
+			t.Fatalf("got %s message: %s", msg.Type, msg.Payload)
+		}
+		var resp struct {
+			Data struct{ Count int }
+		}
+		json.Unmarshal(msg.Payload, &resp)
+		counts = append(counts, resp.Data.Count)
+	}
+	if len(counts) != 3 || counts[0] != 1 || counts[2] != 3 {
+		t.Errorf("counts = %v, want [1 2 3]", counts)
+	}
+
+	conn.WriteJSON(wsMessage{ID: "2", Type: msgSubscribe, Payload: json.RawMessage(`{"query":"subscription { nope }"}`)})
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != msgError || msg.ID != "2" {
+		t.Errorf("invalid subscription got %+v (%v), want %s", msg, err, msgError)
+	}
+}
+
+func TestWebsocketRejectsBadToken(t *testing.T) {
+	conn := dial(t, newTestServer(t))
+
+	conn.WriteJSON(wsMessage{Type: msgConnectionInit, Payload: json.RawMessage(`{"Authorization":"Bearer bad"}`)})
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != closeForbidden {
+		t.Errorf("read after bad token = %v, want close %d", err, closeForbidden)
+	}
+}
+
+func TestShutdownClosesWebsockets(t *testing.T) {
+	server := newTestServer(t)
+	handler := server.Config.Handler.(*Handler)
+	conn := dial(t, server)
+
+	conn.WriteJSON(wsMessage{Type: msgConnectionInit, Payload: json.RawMessage(`{"Authorization":"Bearer good"}`)})
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != msgConnectionAck {
+		t.Fatalf("after connection_init got %+v (%v), want %s", msg, err, msgConnectionAck)
+	}
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- handler.Shutdown(ctx)
+	}()
+
+	// Reading the close message makes the client answer it, which lets
+	// Shutdown return.
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Errorf("read during shutdown = %v, want close %d", err, websocket.CloseGoingAway)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+
+	if _, _, err := dial(t, server).ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Errorf("read on a new connection after shutdown = %v, want close %d", err, websocket.CloseGoingAway)
+	}
+}
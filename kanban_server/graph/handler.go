@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"kanban_server/logging"
+
+	"github.com/gorilla/websocket"
+	graphql "github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+)
+
+// maxRequestSize bounds the body of a GraphQL request.
+const maxRequestSize = 1 << 20
+
+// Authenticator validates the Authorization header value or the token sent
+// when a websocket connection is initialised and returns the user ID.
+type Authenticator func(token string) (uint, error)
+
+// Handler serves GraphQL over HTTP POST and, for subscriptions, over
+// websockets using the graphql-transport-ws protocol.
+type Handler struct {
+	schema       *graphql.Schema
+	db           *gorm.DB
+	authenticate Authenticator
+	upgrader     websocket.Upgrader
+
+	// conns are the open websockets, which Shutdown closes.
+	mu       sync.Mutex
+	conns    map[*websocket.Conn]struct{}
+	closing  bool
+	draining sync.WaitGroup
+}
+
+func NewHandler(schema *graphql.Schema, db *gorm.DB, authenticate Authenticator) *Handler {
+	return &Handler{
+		schema:       schema,
+		db:           db,
+		authenticate: authenticate,
+		upgrader:     websocket.Upgrader{Subprotocols: []string{subprotocol}},
+		conns:        make(map[*websocket.Conn]struct{}),
+	}
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebsocket(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "GraphQL requests must use POST")
+		return
+	}
+
+	userID, err := h.authenticate(r.Header.Get("Authorization"))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	logging.SetUserID(r.Context(), userID)
+
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	ctx := WithLoaders(WithUserID(r.Context(), userID), h.db, true)
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"message": message}},
+	})
+}
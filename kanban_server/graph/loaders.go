@@ -0,0 +1,143 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"kanban_server/models"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"gorm.io/gorm"
+)
+
+// batchWait is how long a loader collects keys before querying.
+const batchWait = 2 * time.Millisecond
+
+// loaders batch the lookups of one operation.
+type loaders struct {
+	users            *dataloader.Loader[uint, *models.User]
+	projects         *dataloader.Loader[uint, *models.Project]
+	columns          *dataloader.Loader[uint, *models.Column]
+	tasksByProject   *dataloader.Loader[uint, []models.Task]
+	membersByProject *dataloader.Loader[uint, []models.User]
+	columnsByProject *dataloader.Loader[uint, []models.Column]
+	labelsByProject  *dataloader.Loader[uint, []models.Label]
+	labelsByTask     *dataloader.Loader[uint, []models.Label]
+	commentsByTask   *dataloader.Loader[uint, []models.Comment]
+}
+
+// WithLoaders returns a context with fresh loaders. Loaders cache results
+// for the lifetime of ctx unless cache is false, which subscriptions use so
+// that every event sees current data.
+func WithLoaders(ctx context.Context, db *gorm.DB, cache bool) context.Context {
+	return context.WithValue(ctx, loadersKey, newLoaders(db, cache))
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey).(*loaders)
+}
+
+func newLoaders(db *gorm.DB, cache bool) *loaders {
+	return &loaders{
+		users:    newLoader(cache, byID(db, func(u models.User) uint { return u.ID })),
+		projects: newLoader(cache, byID(db, func(p models.Project) uint { return p.ID })),
+		columns:  newLoader(cache, byID(db, func(c models.Column) uint { return c.ID })),
+		tasksByProject: newLoader(cache, grouped(func(ctx context.Context, keys []uint) ([]models.Task, error) {
+			var tasks []models.Task
+			err := db.WithContext(ctx).Where("project_id IN ?", keys).Order("id").Find(&tasks).Error
+			return tasks, err
+		}, func(t models.Task) uint { return t.ProjectID })),
+		membersByProject: newLoader(cache, groupedAs(func(ctx context.Context, keys []uint) ([]projectMember, error) {
+			var rows []projectMember
+			err := db.WithContext(ctx).Table("users").
+				Select("users.*, user_projects.project_id").
+				Joins("JOIN user_projects ON user_projects.user_id = users.id").
+				Where("user_projects.project_id IN ?", keys).
+				Order("users.id").Scan(&rows).Error
+			return rows, err
+		}, func(m projectMember) uint { return m.ProjectID }, func(m projectMember) models.User { return m.User })),
+		columnsByProject: newLoader(cache, grouped(func(ctx context.Context, keys []uint) ([]models.Column, error) {
+			var columns []models.Column
+			err := db.WithContext(ctx).Where("project_id IN ?", keys).Order("position, id").Find(&columns).Error
+			return columns, err
+		}, func(c models.Column) uint { return c.ProjectID })),
+		labelsByProject: newLoader(cache, grouped(func(ctx context.Context, keys []uint) ([]models.Label, error) {
+			var labels []models.Label
+			err := db.WithContext(ctx).Where("project_id IN ?", keys).Order("name").Find(&labels).Error
+			return labels, err
+		}, func(l models.Label) uint { return l.ProjectID })),
+		labelsByTask: newLoader(cache, groupedAs(func(ctx context.Context, keys []uint) ([]taskLabel, error) {
+			var rows []taskLabel
+			err := db.WithContext(ctx).Table("labels").
+				Select("labels.*, task_labels.task_id").
+				Joins("JOIN task_labels ON task_labels.label_id = labels.id").
+				Where("task_labels.task_id IN ?", keys).
+				Order("labels.name").Scan(&rows).Error
+			return rows, err
+		}, func(l taskLabel) uint { return l.TaskID }, func(l taskLabel) models.Label { return l.Label })),
+		commentsByTask: newLoader(cache, grouped(func(ctx context.Context, keys []uint) ([]models.Comment, error) {
+			var comments []models.Comment
+			err := db.WithContext(ctx).Where("task_id IN ?", keys).Order("created_at, id").Find(&comments).Error
+			return comments, err
+		}, func(c models.Comment) uint { return c.TaskID })),
+	}
+}
+
+type projectMember struct {
+	models.User
+	ProjectID uint
+}
+
+type taskLabel struct {
+	models.Label
+	TaskID uint
+}
+
+func newLoader[V any](cache bool, batch dataloader.BatchFunc[uint, V]) *dataloader.Loader[uint, V] {
+	opts := []dataloader.Option[uint, V]{dataloader.WithWait[uint, V](batchWait)}
+	if !cache {
+		opts = append(opts, dataloader.WithCache[uint, V](&dataloader.NoCache[uint, V]{}))
+	}
+	return dataloader.NewBatchedLoader(batch, opts...)
+}
+
+// byID loads records by primary key. Missing records resolve to nil.
+func byID[T any](db *gorm.DB, id func(T) uint) dataloader.BatchFunc[uint, *T] {
+	return func(ctx context.Context, keys []uint) []*dataloader.Result[*T] {
+		var records []T
+		err := db.WithContext(ctx).Where("id IN ?", keys).Find(&records).Error
+
+		found := make(map[uint]*T, len(records))
+		for i := range records {
+			found[id(records[i])] = &records[i]
+		}
+		results := make([]*dataloader.Result[*T], len(keys))
+		for i, key := range keys {
+			results[i] = &dataloader.Result[*T]{Data: found[key], Error: err}
+		}
+		return results
+	}
+}
+
+// grouped loads the rows for all keys in one query and groups them by key.
+func grouped[V any](fetch func(context.Context, []uint) ([]V, error), key func(V) uint) dataloader.BatchFunc[uint, []V] {
+	return groupedAs(fetch, key, func(v V) V { return v })
+}
+
+// groupedAs is grouped for queries whose rows carry the key next to the
+// value, such as rows of a join table.
+func groupedAs[R, V any](fetch func(context.Context, []uint) ([]R, error), key func(R) uint, value func(R) V) dataloader.BatchFunc[uint, []V] {
+	return func(ctx context.Context, keys []uint) []*dataloader.Result[[]V] {
+		rows, err := fetch(ctx, keys)
+
+		groups := make(map[uint][]V, len(keys))
+		for _, row := range rows {
+			groups[key(row)] = append(groups[key(row)], value(row))
+		}
+		results := make([]*dataloader.Result[[]V], len(keys))
+		for i, k := range keys {
+			results[i] = &dataloader.Result[[]V]{Data: groups[k], Error: err}
+		}
+		return results
+	}
+}
@@ -0,0 +1,309 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"kanban_server/models"
+	"kanban_server/service"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+type createProjectInput struct {
+	Title       string
+	Description *string
+}
+
+type updateProjectInput struct {
+	Title       *string
+	Description *string
+	Status      *string
+}
+
+type createTaskInput struct {
+	Title       string
+	Description *string
+	Status      *string
+	Priority    *string
+	DueDate     *graphql.Time
+	AssigneeID  *graphql.ID
+	ColumnID    *graphql.ID
+	LabelIDs    *[]graphql.ID
+}
+
+type updateTaskInput struct {
+	Title        *string
+	Description  *string
+	Status       *string
+	Priority     *string
+	DueDate      *graphql.Time
+	ClearDueDate *bool
+	AssigneeID   *graphql.ID
+	ColumnID     *graphql.ID
+	Archived     *bool
+}
+
+func (r *Resolver) CreateProject(ctx context.Context, args struct{ Input createProjectInput }) (*projectResolver, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	in := service.NewProject{Title: args.Input.Title}
+	if args.Input.Description != nil {
+		in.Description = *args.Input.Description
+	}
+	project, err := r.Service.CreateProject(ctx, userID, in)
+	if err != nil {
+		return nil, err
+	}
+	return &projectResolver{project}, nil
+}
+
+func (r *Resolver) UpdateProject(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input updateProjectInput
+}) (*projectResolver, error) {
+	project, err := r.findProject(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	in := args.Input
+	updates, err := service.ProjectChanges{Title: in.Title, Description: in.Description, Status: in.Status}.Updates()
+	if err != nil {
+		return nil, err
+	}
+
+	actorID, _ := currentUserID(ctx)
+	updated, err := r.Service.UpdateProject(ctx, actorID, *project, updates)
+	if err != nil {
+		return nil, err
+	}
+	loadersFrom(ctx).projects.Clear(ctx, project.ID)
+	return &projectResolver{updated}, nil
+}
+
+func (r *Resolver) DeleteProject(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	project, err := r.findProject(ctx, args.ID)
+	if err != nil {
+		return false, err
+	}
+	actorID, _ := currentUserID(ctx)
+	if err := r.Service.DeleteProject(ctx, actorID, *project); err != nil {
+		return false, err
+	}
+	loadersFrom(ctx).projects.Clear(ctx, project.ID)
+	return true, nil
+}
+
+func (r *Resolver) CreateTask(ctx context.Context, args struct {
+	ProjectID graphql.ID
+	Input     createTaskInput
+}) (*taskResolver, error) {
+	project, err := r.findProject(ctx, args.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	in := args.Input
+
+	task := service.NewTask{Title: in.Title}
+	if in.Description != nil {
+		task.Description = *in.Description
+	}
+	if in.Status != nil {
+		task.Status = *in.Status
+	}
+	if in.Priority != nil {
+		task.Priority = *in.Priority
+	}
+	if in.DueDate != nil {
+		task.DueDate = in.DueDate.Time
+	}
+	if in.AssigneeID != nil {
+		if task.AssignedTo, err = parseID(*in.AssigneeID); err != nil {
+			return nil, err
+		}
+	}
+	if in.ColumnID != nil {
+		column, err := parseID(*in.ColumnID)
+		if err != nil {
+			return nil, err
+		}
+		task.ColumnID = &column
+	}
+	if in.LabelIDs != nil {
+		for _, id := range *in.LabelIDs {
+			labelID, err := parseID(id)
+			if err != nil {
+				return nil, err
+			}
+			task.LabelIDs = append(task.LabelIDs, labelID)
+		}
+	}
+
+	actorID, _ := currentUserID(ctx)
+	created, err := r.Service.CreateTask(ctx, actorID, project.ID, task)
+	if err != nil {
+		return nil, err
+	}
+	loadersFrom(ctx).tasksByProject.Clear(ctx, project.ID)
+	return &taskResolver{created}, nil
+}
+
+func (r *Resolver) UpdateTask(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input updateTaskInput
+}) (*taskResolver, error) {
+	task, err := r.findTask(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	in := args.Input
+
+	changes := service.TaskChanges{
+		Title:        in.Title,
+		Description:  in.Description,
+		Status:       in.Status,
+		Priority:     in.Priority,
+		ClearDueDate: in.ClearDueDate != nil && *in.ClearDueDate,
+		Archived:     in.Archived,
+	}
+	if in.DueDate != nil {
+		changes.DueDate = &in.DueDate.Time
+	}
+	if in.AssigneeID != nil {
+		assignee, err := parseID(*in.AssigneeID)
+		if err != nil {
+			return nil, err
+		}
+		changes.AssignedTo = &assignee
+	}
+	if in.ColumnID != nil {
+		column, err := parseID(*in.ColumnID)
+		if err != nil {
+			return nil, err
+		}
+		changes.ColumnID = &column
+	}
+	updates, err := changes.Updates()
+	if err != nil {
+		return nil, err
+	}
+
+	actorID, _ := currentUserID(ctx)
+	updated, err := r.Service.UpdateTask(ctx, actorID, *task, updates)
+	if err != nil {
+		return nil, err
+	}
+	loadersFrom(ctx).tasksByProject.Clear(ctx, task.ProjectID)
+	return &taskResolver{updated}, nil
+}
+
+func (r *Resolver) DeleteTask(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	task, err := r.findTask(ctx, args.ID)
+	if err != nil {
+		return false, err
+	}
+	actorID, _ := currentUserID(ctx)
+	if err := r.Service.DeleteTask(ctx, actorID, *task); err != nil {
+		return false, err
+	}
+	loadersFrom(ctx).tasksByProject.Clear(ctx, task.ProjectID)
+	return true, nil
+}
+
+func (r *Resolver) AddComment(ctx context.Context, args struct {
+	TaskID graphql.ID
+	Body   string
+}) (*commentResolver, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task, err := r.findTask(ctx, args.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := r.Service.AddComment(ctx, userID, *task, args.Body)
+	if err != nil {
+		return nil, err
+	}
+	loadersFrom(ctx).commentsByTask.Clear(ctx, task.ID)
+	return &commentResolver{comment}, nil
+}
+
+func (r *Resolver) AddMember(ctx context.Context, args struct {
+	ProjectID graphql.ID
+	Email     string
+}) (*userResolver, error) {
+	project, err := r.findProject(ctx, args.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.Service.FindUserByEmail(ctx, strings.TrimSpace(args.Email))
+	if err != nil {
+		return nil, err
+	}
+	actorID, _ := currentUserID(ctx)
+	if err := r.Service.AddMember(ctx, actorID, *project, user); err != nil {
+		return nil, err
+	}
+	loadersFrom(ctx).membersByProject.Clear(ctx, project.ID)
+	return &userResolver{user}, nil
+}
+
+func (r *Resolver) RemoveMember(ctx context.Context, args struct {
+	ProjectID graphql.ID
+	UserID    graphql.ID
+}) (bool, error) {
+	project, err := r.findProject(ctx, args.ProjectID)
+	if err != nil {
+		return false, err
+	}
+	userID, err := parseID(args.UserID)
+	if err != nil {
+		return false, err
+	}
+	actorID, _ := currentUserID(ctx)
+	if _, err := r.Service.RemoveMember(ctx, actorID, *project, userID); err != nil {
+		return false, err
+	}
+	loadersFrom(ctx).membersByProject.Clear(ctx, project.ID)
+	return true, nil
+}
+
+func (r *Resolver) CreateLabel(ctx context.Context, args struct {
+	ProjectID graphql.ID
+	Name      string
+	Color     *string
+}) (*labelResolver, error) {
+	project, err := r.findProject(ctx, args.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	label := models.Label{ProjectID: project.ID, Name: strings.TrimSpace(args.Name)}
+	if label.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if args.Color != nil {
+		label.Color = *args.Color
+	}
+	if err := r.DB.WithContext(ctx).Create(&label).Error; err != nil {
+		return nil, fmt.Errorf("label %q already exists", label.Name)
+	}
+	loadersFrom(ctx).labelsByProject.Clear(ctx, project.ID)
+	return &labelResolver{label}, nil
+}
+
+func commentNotification(task models.Task, comment models.Comment, userID uint, kind, message string) models.Notification {
+	return models.Notification{
+		UserID:    userID,
+		Type:      kind,
+		Message:   message,
+		ProjectID: task.ProjectID,
+		TaskID:    task.ID,
+		CommentID: comment.ID,
+	}
+}
@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"kanban_server/models"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+func (r *Resolver) Me(ctx context.Context) (*userResolver, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := loadUser(ctx, userID)
+	if err == nil && user == nil {
+		err = errUnauthenticated
+	}
+	return user, err
+}
+
+func (r *Resolver) Projects(ctx context.Context, args struct{ Status *string }) ([]*projectResolver, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var status string
+	if args.Status != nil {
+		status = *args.Status
+	}
+	projects, err := r.ListProjects(ctx, userID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*projectResolver, len(projects))
+	for i, project := range projects {
+		loadersFrom(ctx).projects.Prime(ctx, project.ID, &projects[i])
+		resolvers[i] = &projectResolver{project}
+	}
+	return resolvers, nil
+}
+
+func (r *Resolver) Project(ctx context.Context, args struct{ ID graphql.ID }) (*projectResolver, error) {
+	project, err := r.findProject(ctx, args.ID)
+	if errors.Is(err, errProjectNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &projectResolver{*project}, nil
+}
+
+func (r *Resolver) Task(ctx context.Context, args struct{ ID graphql.ID }) (*taskResolver, error) {
+	task, err := r.findTask(ctx, args.ID)
+	if errors.Is(err, errTaskNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &taskResolver{*task}, nil
+}
+
+// findProject loads a project the current user is a member of.
+func (r *Resolver) findProject(ctx context.Context, id graphql.ID) (*models.Project, error) {
+	projectID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.authorize(ctx, projectID); err != nil {
+		return nil, err
+	}
+	project, err := loadersFrom(ctx).projects.Load(ctx, projectID)()
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errProjectNotFound
+	}
+	return project, nil
+}
+
+// findTask loads a task in a project the current user is a member of.
+func (r *Resolver) findTask(ctx context.Context, id graphql.ID) (*models.Task, error) {
+	taskID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task, err := r.FindMemberTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
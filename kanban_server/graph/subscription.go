@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"context"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+func (r *Resolver) ProjectEvents(ctx context.Context, args struct{ ProjectID graphql.ID }) (<-chan *eventResolver, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	project, err := r.findProject(ctx, args.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if r.Bus == nil {
+		return nil, errProjectNotFound
+	}
+
+	incoming := r.Watch(ctx, project.ID, userID)
+	out := make(chan *eventResolver)
+	go func() {
+		defer close(out)
+		for e := range incoming {
+			select {
+			case out <- &eventResolver{e}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
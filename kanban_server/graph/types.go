@@ -0,0 +1,233 @@
+package graph
+
+import (
+	"context"
+
+	"kanban_server/events"
+	"kanban_server/models"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+type userResolver struct {
+	user models.User
+}
+
+func (u *userResolver) ID() graphql.ID { return toID(u.user.ID) }
+func (u *userResolver) Email() string  { return u.user.Email }
+func (u *userResolver) Name() string   { return u.user.Name }
+
+// loadUser resolves an optional user reference.
+func loadUser(ctx context.Context, id uint) (*userResolver, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	user, err := loadersFrom(ctx).users.Load(ctx, id)()
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &userResolver{*user}, nil
+}
+
+type projectResolver struct {
+	project models.Project
+}
+
+func (p *projectResolver) ID() graphql.ID          { return toID(p.project.ID) }
+func (p *projectResolver) Title() string           { return p.project.Title }
+func (p *projectResolver) Description() string     { return p.project.Description }
+func (p *projectResolver) Status() string          { return p.project.Status }
+func (p *projectResolver) CreatedAt() graphql.Time { return graphql.Time{Time: p.project.CreatedAt} }
+func (p *projectResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: p.project.UpdatedAt} }
+
+func (p *projectResolver) Members(ctx context.Context) ([]*userResolver, error) {
+	members, err := loadersFrom(ctx).membersByProject.Load(ctx, p.project.ID)()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*userResolver, len(members))
+	for i, member := range members {
+		resolvers[i] = &userResolver{member}
+	}
+	return resolvers, nil
+}
+
+func (p *projectResolver) Tasks(ctx context.Context, args struct {
+	Status          *string
+	IncludeArchived bool
+}) ([]*taskResolver, error) {
+	tasks, err := loadersFrom(ctx).tasksByProject.Load(ctx, p.project.ID)()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := []*taskResolver{}
+	for _, task := range tasks {
+		if (args.Status != nil && task.Status != *args.Status) || (task.Archived && !args.IncludeArchived) {
+			continue
+		}
+		resolvers = append(resolvers, &taskResolver{task})
+	}
+	return resolvers, nil
+}
+
+func (p *projectResolver) Columns(ctx context.Context) ([]*columnResolver, error) {
+	columns, err := loadersFrom(ctx).columnsByProject.Load(ctx, p.project.ID)()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*columnResolver, len(columns))
+	for i, column := range columns {
+		resolvers[i] = &columnResolver{column}
+	}
+	return resolvers, nil
+}
+
+func (p *projectResolver) Labels(ctx context.Context) ([]*labelResolver, error) {
+	labels, err := loadersFrom(ctx).labelsByProject.Load(ctx, p.project.ID)()
+	return labelResolvers(labels), err
+}
+
+type columnResolver struct {
+	column models.Column
+}
+
+func (c *columnResolver) ID() graphql.ID { return toID(c.column.ID) }
+func (c *columnResolver) Name() string   { return c.column.Name }
+func (c *columnResolver) Position() int32 {
+	return int32(c.column.Position)
+}
+
+type labelResolver struct {
+	label models.Label
+}
+
+func (l *labelResolver) ID() graphql.ID { return toID(l.label.ID) }
+func (l *labelResolver) Name() string   { return l.label.Name }
+func (l *labelResolver) Color() string  { return l.label.Color }
+
+func labelResolvers(labels []models.Label) []*labelResolver {
+	resolvers := make([]*labelResolver, len(labels))
+	for i, label := range labels {
+		resolvers[i] = &labelResolver{label}
+	}
+	return resolvers
+}
+
+type taskResolver struct {
+	task models.Task
+}
+
+func (t *taskResolver) ID() graphql.ID          { return toID(t.task.ID) }
+func (t *taskResolver) Title() string           { return t.task.Title }
+func (t *taskResolver) Description() string     { return t.task.Description }
+func (t *taskResolver) Status() string          { return t.task.Status }
+func (t *taskResolver) Priority() string        { return t.task.Priority }
+func (t *taskResolver) Overdue() bool           { return t.task.Overdue }
+func (t *taskResolver) Archived() bool          { return t.task.Archived }
+func (t *taskResolver) CreatedAt() graphql.Time { return graphql.Time{Time: t.task.CreatedAt} }
+func (t *taskResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: t.task.UpdatedAt} }
+
+func (t *taskResolver) DueDate() *graphql.Time {
+	if t.task.DueDate.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: t.task.DueDate}
+}
+
+func (t *taskResolver) Project(ctx context.Context) (*projectResolver, error) {
+	project, err := loadersFrom(ctx).projects.Load(ctx, t.task.ProjectID)()
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errProjectNotFound
+	}
+	return &projectResolver{*project}, nil
+}
+
+func (t *taskResolver) Assignee(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, t.task.AssignedTo)
+}
+
+func (t *taskResolver) Column(ctx context.Context) (*columnResolver, error) {
+	if t.task.ColumnID == nil {
+		return nil, nil
+	}
+	column, err := loadersFrom(ctx).columns.Load(ctx, *t.task.ColumnID)()
+	if err != nil || column == nil {
+		return nil, err
+	}
+	return &columnResolver{*column}, nil
+}
+
+func (t *taskResolver) Labels(ctx context.Context) ([]*labelResolver, error) {
+	labels, err := loadersFrom(ctx).labelsByTask.Load(ctx, t.task.ID)()
+	return labelResolvers(labels), err
+}
+
+func (t *taskResolver) Comments(ctx context.Context) ([]*commentResolver, error) {
+	comments, err := loadersFrom(ctx).commentsByTask.Load(ctx, t.task.ID)()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*commentResolver, len(comments))
+	for i, comment := range comments {
+		resolvers[i] = &commentResolver{comment}
+	}
+	return resolvers, nil
+}
+
+type commentResolver struct {
+	comment models.Comment
+}
+
+func (c *commentResolver) ID() graphql.ID          { return toID(c.comment.ID) }
+func (c *commentResolver) Body() string            { return c.comment.Body }
+func (c *commentResolver) CreatedAt() graphql.Time { return graphql.Time{Time: c.comment.CreatedAt} }
+
+func (c *commentResolver) Author(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, c.comment.UserID)
+}
+
+// eventResolver exposes the record carried by an event under the field
+// matching its type.
+type eventResolver struct {
+	event events.Event
+}
+
+func (e *eventResolver) ID() graphql.ID           { return graphql.ID(e.event.ID) }
+func (e *eventResolver) Type() string             { return e.event.Type }
+func (e *eventResolver) ProjectID() graphql.ID    { return toID(e.event.ProjectID) }
+func (e *eventResolver) OccurredAt() graphql.Time { return graphql.Time{Time: e.event.OccurredAt} }
+
+func (e *eventResolver) Actor(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, e.event.ActorID)
+}
+
+func (e *eventResolver) Project() *projectResolver {
+	if project, ok := e.event.Data.(models.Project); ok {
+		return &projectResolver{project}
+	}
+	return nil
+}
+
+func (e *eventResolver) Task() *taskResolver {
+	if task, ok := e.event.Data.(models.Task); ok {
+		return &taskResolver{task}
+	}
+	return nil
+}
+
+func (e *eventResolver) Comment() *commentResolver {
+	if comment, ok := e.event.Data.(models.Comment); ok {
+		return &commentResolver{comment}
+	}
+	return nil
+}
+
+func (e *eventResolver) Member() *userResolver {
+	if user, ok := e.event.Data.(models.User); ok {
+		return &userResolver{user}
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"kanban_server/logging"
+
+	"github.com/gorilla/websocket"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// subprotocol is the graphql-transport-ws protocol spoken by the graphql-ws
+// client library.
+const subprotocol = "graphql-transport-ws"
+
+const (
+	initTimeout  = 10 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+// Message types of the graphql-transport-ws protocol.
+const (
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+// Close codes defined by the protocol.
+const (
+	closeBadRequest      = 4400
+	closeForbidden       = 4403
+	closeInitTimeout     = 4408
+	closeDuplicateID     = 4409
+	closeTooManyInitReqs = 4429
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsConn is one websocket connection and its running operations.
+type wsConn struct {
+	handler *Handler
+	conn    *websocket.Conn
+	ctx     context.Context
+
+	writeMu sync.Mutex
+
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc
+}
+
+func (h *Handler) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if !h.track(conn) {
+		closeWith(conn, websocket.CloseGoingAway, "Server shutting down")
+		return
+	}
+	defer h.untrack(conn)
+	if conn.Subprotocol() != subprotocol {
+		closeWith(conn, websocket.CloseProtocolError, "Subprotocol "+subprotocol+" required")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	c := &wsConn{handler: h, conn: conn, ctx: ctx, operations: make(map[string]context.CancelFunc)}
+	c.serve(r.Header.Get("Authorization"))
+}
+
+// track registers a new connection, or reports false once Shutdown has
+// begun.
+func (h *Handler) track(conn *websocket.Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closing {
+		return false
+	}
+	h.conns[conn] = struct{}{}
+	h.draining.Add(1)
+	return true
+}
+
+func (h *Handler) untrack(conn *websocket.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, conn)
+	h.draining.Done()
+}
+
+// Connections returns the number of open websockets.
+func (h *Handler) Connections() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.conns)
+}
+
+// Shutdown refuses new websockets, asks the clients of open ones to go away
+// and waits until they have. Connections still open when ctx ends are
+// closed without waiting for the client. http.Server.Shutdown leaves
+// websockets alone, so it must be called as well.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	conns := make([]*websocket.Conn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+
+	// The client answers the close message with its own, which ends the
+	// read loop in serve.
+	for _, conn := range conns {
+		closeWith(conn, websocket.CloseGoingAway, "Server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.draining.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	h.mu.Lock()
+	for conn := range h.conns {
+		conn.Close()
+	}
+	h.mu.Unlock()
+	<-done
+	return ctx.Err()
+}
+
+func (c *wsConn) serve(headerToken string) {
+	c.conn.SetReadDeadline(time.Now().Add(initTimeout))
+	var init wsMessage
+	if err := c.conn.ReadJSON(&init); err != nil || init.Type != msgConnectionInit {
+		closeWith(c.conn, closeInitTimeout, "Connection initialisation timeout")
+		return
+	}
+	c.conn.SetReadDeadline(time.Time{})
+
+	// Browsers cannot set headers on websockets, so the token is usually
+	// sent in the connection_init payload.
+	var params struct {
+		Authorization string `json:"Authorization"`
+		Token         string `json:"authToken"`
+	}
+	json.Unmarshal(init.Payload, &params)
+	token := headerToken
+	if params.Authorization != "" {
+		token = params.Authorization
+	} else if params.Token != "" {
+		token = params.Token
+	}
+	userID, err := c.handler.authenticate(token)
+	if err != nil {
+		closeWith(c.conn, closeForbidden, "Forbidden")
+		return
+	}
+	logging.SetUserID(c.ctx, userID)
+	ctx := WithUserID(c.ctx, userID)
+	if err := c.write(wsMessage{Type: msgConnectionAck}); err != nil {
+		return
+	}
+
+	defer c.cancelAll()
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case msgPing:
+			c.write(wsMessage{Type: msgPong})
+		case msgPong:
+		case msgConnectionInit:
+			closeWith(c.conn, closeTooManyInitReqs, "Too many initialisation requests")
+			return
+		case msgSubscribe:
+			var req request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				closeWith(c.conn, closeBadRequest, "Invalid subscribe message")
+				return
+			}
+			if !c.start(ctx, msg.ID, req) {
+				closeWith(c.conn, closeDuplicateID, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+		case msgComplete:
+			c.stop(msg.ID)
+		default:
+			closeWith(c.conn, closeBadRequest, "Unknown message type "+msg.Type)
+			return
+		}
+	}
+}
+
+// start runs an operation in the background and reports false if the ID is
+// already in use.
+func (c *wsConn) start(ctx context.Context, id string, req request) bool {
+	c.mu.Lock()
+	if _, exists := c.operations[id]; exists {
+		c.mu.Unlock()
+		return false
+	}
+	opCtx, cancel := context.WithCancel(ctx)
+	c.operations[id] = cancel
+	c.mu.Unlock()
+
+	go func() {
+		defer c.stop(id)
+
+		opCtx := WithLoaders(opCtx, c.handler.db, false)
+		responses, err := c.handler.schema.Subscribe(opCtx, req.Query, req.OperationName, req.Variables)
+		if err != nil {
+			c.sendErrors(id, []map[string]string{{"message": err.Error()}})
+			return
+		}
+		first := true
+		for resp := range responses {
+			r := resp.(*graphql.Response)
+			// Errors before any result, such as validation errors, end the
+			// operation with an error message.
+			if first && r.Data == nil && len(r.Errors) > 0 {
+				c.sendErrors(id, r.Errors)
+				return
+			}
+			first = false
+			payload, _ := json.Marshal(r)
+			if c.write(wsMessage{ID: id, Type: msgNext, Payload: payload}) != nil {
+				return
+			}
+		}
+		if opCtx.Err() == nil {
+			c.write(wsMessage{ID: id, Type: msgComplete})
+		}
+	}()
+	return true
+}
+
+func (c *wsConn) sendErrors(id string, errs interface{}) {
+	payload, _ := json.Marshal(errs)
+	c.write(wsMessage{ID: id, Type: msgError, Payload: payload})
+}
+
+// stop cancels an operation when the client completes it or it ends.
+func (c *wsConn) stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cancel, ok := c.operations[id]; ok {
+		cancel()
+		delete(c.operations, id)
+	}
+}
+
+func (c *wsConn) cancelAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, cancel := range c.operations {
+		cancel()
+		delete(c.operations, id)
+	}
+}
+
+func (c *wsConn) write(msg wsMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.conn.WriteJSON(msg)
+}
+
+func closeWith(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"kanban_server/config"
+)
+
+// Build information, set by
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+//
+// Without -ldflags the commit is read from the VCS stamp go build embeds.
+var (
+	version   = "dev"
+	commit    = ""
+	buildTime = "unknown"
+)
+
+// readyTimeout bounds the checks of one readiness probe.
+const readyTimeout = 2 * time.Second
+
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type VersionResponse struct {
+	Version       string `json:"version"`
+	Commit        string `json:"commit"`
+	BuildTime     string `json:"build_time"`
+	GoVersion     string `json:"go_version"`
+	SchemaVersion int    `json:"schema_version"`
+}
+
+// checkHealth reports that the process is up and serving. It checks nothing
+// else, so that an orchestrator does not restart the server over a database
+// outage it could not fix.
+func (s *Server) checkHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
+}
+
+// checkReadiness reports whether the server should be sent requests: the
+// database answers and has been migrated to this build's schema, and the
+// background workers are running. It fails with 503 once shutdown begins.
+// Database errors are logged rather than shown, since they may name hosts.
+func (s *Server) checkReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	resp := HealthResponse{Status: "ok", Checks: map[string]string{}}
+	check := func(name string, err error) {
+		if err == nil {
+			resp.Checks[name] = "ok"
+			return
+		}
+		resp.Status = "unavailable"
+		resp.Checks[name] = err.Error()
+	}
+
+	if s.draining.Load() {
+		check("shutdown", errors.New("shutting down"))
+	}
+	if err := s.pingDB(ctx); err != nil {
+		slog.WarnContext(ctx, "Readiness check failed", "check", "database", "error", err)
+		check("database", errors.New("unreachable"))
+	} else {
+		check("database", nil)
+		migrated, err := config.MigratedVersion(ctx, s.DB)
+		if err != nil {
+			slog.WarnContext(ctx, "Readiness check failed", "check", "schema", "error", err)
+			err = errors.New("version unknown")
+		} else if migrated < config.SchemaVersion {
+			err = fmt.Errorf("migrated to version %d, this build needs %d", migrated, config.SchemaVersion)
+		}
+		check("schema", err)
+	}
+	var workersErr error
+	if exited := s.workers.Exited(); len(exited) > 0 {
+		workersErr = fmt.Errorf("stopped: %s", strings.Join(exited, ", "))
+	}
+	check("workers", workersErr)
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) pingDB(ctx context.Context) error {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// getVersion reports the build of the server and the schema version it
+// migrates to.
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildInfo())
+}
+
+func buildInfo() VersionResponse {
+	info := VersionResponse{
+		Version:       version,
+		Commit:        commit,
+		BuildTime:     buildTime,
+		GoVersion:     runtime.Version(),
+		SchemaVersion: config.SchemaVersion,
+	}
+	if info.Commit != "" {
+		return info
+	}
+	info.Commit = "unknown"
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		var revision string
+		var modified bool
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if revision != "" {
+			info.Commit = revision
+			if modified {
+				info.Commit += "-dirty"
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,124 @@
+// Package ical writes RFC 5545 iCalendar feeds.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest content line allowed before folding.
+const maxLineOctets = 75
+
+const dateTimeFormat = "20060102T150405Z"
+
+// Calendar is a VCALENDAR published to subscribers.
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event is a VEVENT. UID must stay the same across feed refreshes so clients
+// update the event instead of adding a copy.
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	URL          string
+	Start        time.Time
+	LastModified time.Time
+	Categories   []string
+}
+
+// Write encodes c. Times are written in UTC.
+func (c Calendar) Write(w io.Writer, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	l := lineWriter{w: bw}
+
+	l.line("BEGIN:VCALENDAR")
+	l.line("VERSION:2.0")
+	l.line("PRODID:" + c.ProdID)
+	l.line("CALSCALE:GREGORIAN")
+	l.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		l.line("X-WR-CALNAME:" + Escape(c.Name))
+	}
+	for _, e := range c.Events {
+		l.line("BEGIN:VEVENT")
+		l.line("UID:" + Escape(e.UID))
+		l.line("DTSTAMP:" + now.UTC().Format(dateTimeFormat))
+		l.line("DTSTART:" + e.Start.UTC().Format(dateTimeFormat))
+		l.line("SUMMARY:" + Escape(e.Summary))
+		if e.Description != "" {
+			l.line("DESCRIPTION:" + Escape(e.Description))
+		}
+		if e.URL != "" {
+			l.line("URL:" + e.URL)
+		}
+		if len(e.Categories) > 0 {
+			escaped := make([]string, len(e.Categories))
+			for i, category := range e.Categories {
+				escaped[i] = Escape(category)
+			}
+			l.line("CATEGORIES:" + strings.Join(escaped, ","))
+		}
+		if !e.LastModified.IsZero() {
+			l.line("LAST-MODIFIED:" + e.LastModified.UTC().Format(dateTimeFormat))
+		}
+		l.line("END:VEVENT")
+	}
+	l.line("END:VCALENDAR")
+
+	if l.err != nil {
+		return l.err
+	}
+	return bw.Flush()
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// Escape escapes a TEXT property value.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// lineWriter writes CRLF terminated content lines, folding those longer than
+// 75 octets without splitting UTF-8 sequences.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (l *lineWriter) line(s string) {
+	if l.err != nil {
+		return
+	}
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		l.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards the limit.
+		limit = maxLineOctets - 1
+	}
+	l.write(s + "\r\n")
+}
+
+func (l *lineWriter) write(s string) {
+	if l.err == nil {
+		_, l.err = l.w.WriteString(s)
+	}
+}
@@ -0,0 +1,73 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2024, 5, 3, 17, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	cal := Calendar{
+		ProdID: "-//Kanban//Tasks//EN",
+		Name:   "Ana's tasks",
+		Events: []Event{{
+			UID:          "task-7@example.com",
+			Summary:      "Review; merge, deploy",
+			Description:  "Line one\nLine two",
+			URL:          "https://example.com/projects/1/tasks/7",
+			Start:        due,
+			LastModified: now,
+			Categories:   []string{"high", "todo"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := cal.Write(&buf, now); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:Ana's tasks\r\n",
+		"UID:task-7@example.com\r\n",
+		"DTSTAMP:20240501T120000Z\r\n",
+		"DTSTART:20240503T153000Z\r\n",
+		`SUMMARY:Review\; merge\, deploy` + "\r\n",
+		`DESCRIPTION:Line one\nLine two` + "\r\n",
+		"CATEGORIES:high,todo\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFolding(t *testing.T) {
+	summary := strings.Repeat("é", 100)
+	cal := Calendar{ProdID: "p", Events: []Event{{UID: "u", Summary: summary, Start: time.Now()}}}
+
+	var buf bytes.Buffer
+	if err := cal.Write(&buf, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var unfolded strings.Builder
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line %d is %d octets long", i, len(line))
+		}
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+		} else {
+			unfolded.WriteString("\n" + line)
+		}
+	}
+	if !strings.Contains(unfolded.String(), "\nSUMMARY:"+summary+"\n") {
+		t.Errorf("unfolded output does not contain the summary:\n%s", unfolded.String())
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/problem"
+
+	"gorm.io/gorm/clause"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyMiddleware honours the Idempotency-Key header on POST and PATCH
+// requests. The first response for a user and key is stored for the
+// configured HTTP.IdempotencyTTL and replayed unchanged on retries. Reusing a
+// key with a different request is rejected with 422. It must run after
+// authMiddleware.
+func (s *Server) idempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(key) > maxIdempotencyKeyLength {
+			problem.Write(w, r, problem.Param("header", "Idempotency-Key", "Idempotency-Key is too long"))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			problem.Write(w, r, problem.BadRequest("Invalid request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := currentUserID(r)
+		now := time.Now()
+		s.db(r).Where("user_id = ? AND idempotency_key = ? AND expires_at < ?", userID, key, now).
+			Delete(&models.IdempotencyKey{})
+
+		record := models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash(r, body),
+			ExpiresAt:   now.Add(s.Config.HTTP.IdempotencyTTL),
+		}
+		result := s.db(r).Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			problem.Write(w, r, problem.Internal("Failed to store idempotency key"))
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			s.replayIdempotentResponse(w, r, userID, key, record.RequestHash)
+			return
+		}
+
+		rec := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// The outcome is stored even if the client has gone away meanwhile,
+		// or the key would stay in progress until it expires.
+		db := s.DB.WithContext(context.WithoutCancel(r.Context()))
+
+		// Server errors are not cached so that the client can retry them.
+		if rec.status >= http.StatusInternalServerError {
+			db.Delete(&record)
+			return
+		}
+
+		db.Model(&record).Updates(map[string]interface{}{
+			"completed":    true,
+			"status_code":  rec.status,
+			"content_type": rec.Header().Get("Content-Type"),
+			"body":         rec.body.Bytes(),
+		})
+	})
+}
+
+func (s *Server) replayIdempotentResponse(w http.ResponseWriter, r *http.Request, userID uint, key, hash string) {
+	var existing models.IdempotencyKey
+	if err := s.db(r).Where("user_id = ? AND idempotency_key = ?", userID, key).First(&existing).Error; err != nil {
+		problem.Write(w, r, problem.Conflict("Idempotency-Key is being processed, retry later"))
+		return
+	}
+
+	if existing.RequestHash != hash {
+		problem.Write(w, r, problem.New(http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused, "Idempotency-Key was already used with a different request"))
+		return
+	}
+
+	if !existing.Completed {
+		problem.Write(w, r, problem.Conflict("A request with this Idempotency-Key is still being processed"))
+		return
+	}
+
+	if existing.ContentType != "" {
+		w.Header().Set("Content-Type", existing.ContentType)
+	} else {
+		w.Header().Del("Content-Type")
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(existing.StatusCode)
+	w.Write(existing.Body)
+}
+
+// purgeIdempotencyKeys deletes expired keys every interval until ctx is
+// done, since keys that are never reused would otherwise be kept for ever.
+func (s *Server) purgeIdempotencyKeys(interval time.Duration) func(context.Context) {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.deleteExpiredIdempotencyKeys(ctx); err != nil && ctx.Err() == nil {
+				log.Println("Failed to purge idempotency keys:", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+func (s *Server) deleteExpiredIdempotencyKeys(ctx context.Context) error {
+	return s.DB.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{}).Error
+}
+
+// requestHash fingerprints the parts of a request that must match for a
+// retry to be considered the same request.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingResponseWriter passes the response through while keeping a copy of
+// the status code and body.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
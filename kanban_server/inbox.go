@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/notifications"
+	"kanban_server/problem"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultInboxLimit = 50
+	maxInboxLimit     = 200
+)
+
+type InboxResponse struct {
+	Notifications []models.Notification `json:"notifications"`
+	UnreadCount   int64                 `json:"unread_count"`
+}
+
+type NotificationSettings struct {
+	Muted []string `json:"muted" validate:"max=50,dive,notblank"`
+}
+
+// sendNotifications stores notifications for the current request and emails
+// them in the background. A failure is logged but does not fail the change
+// that caused it.
+func (s *Server) sendNotifications(r *http.Request, inbox ...models.Notification) {
+	s.deliverNotifications(r.Context(), currentUserID(r), inbox...)
+}
+
+func (s *Server) deliverNotifications(ctx context.Context, actorID uint, inbox ...models.Notification) {
+	for i := range inbox {
+		inbox[i].ActorID = actorID
+	}
+	delivered, err := notifications.Send(s.DB.WithContext(ctx), inbox...)
+	if err != nil {
+		log.Println("Failed to send notifications:", err)
+		return
+	}
+	if s.Emails != nil && len(delivered) > 0 {
+		s.background.Add(1)
+		go func() {
+			defer s.background.Done()
+			if err := s.Emails.Notify(context.Background(), delivered...); err != nil {
+				log.Println("Failed to email notifications:", err)
+			}
+		}()
+	}
+}
+
+// getNotifications lists the current user's inbox, newest first. ?unread=true
+// hides read notifications and ?before=<id> pages back through older ones.
+func (s *Server) getNotifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID := currentUserID(r)
+	query := r.URL.Query()
+
+	limit := defaultInboxLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxInboxLimit {
+			problem.Write(w, r, problem.Param("query", "limit", fmt.Sprintf("limit must be between 1 and %d", maxInboxLimit)))
+			return
+		}
+		limit = n
+	}
+
+	db := s.db(r).Where("user_id = ?", userID)
+	if query.Get("unread") == "true" {
+		db = db.Where("read_at IS NULL")
+	}
+	if v := query.Get("before"); v != "" {
+		before, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			problem.Write(w, r, problem.Param("query", "before", "before must be a notification ID"))
+			return
+		}
+		db = db.Where("id < ?", before)
+	}
+
+	resp := InboxResponse{Notifications: []models.Notification{}}
+	if err := db.Order("id DESC").Limit(limit).Find(&resp.Notifications).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch notifications"))
+		return
+	}
+	if err := s.unreadNotifications(r, userID).Count(&resp.UnreadCount).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch notifications"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: resp,
+	})
+}
+
+func (s *Server) getUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var count int64
+	if err := s.unreadNotifications(r, currentUserID(r)).Count(&count).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch notifications"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: map[string]int64{"unread_count": count},
+	})
+}
+
+func (s *Server) markNotificationRead(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	var notification models.Notification
+	if err := s.db(r).Where("user_id = ?", currentUserID(r)).First(&notification, vars["id"]).Error; err != nil {
+		writeLookupError(w, r, err, "Notification not found")
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := s.db(r).Model(&notification).Update("read_at", now).Error; err != nil {
+			problem.Write(w, r, problem.Internal("Failed to update notification"))
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Notification marked as read",
+		Data:    notification,
+	})
+}
+
+func (s *Server) markAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	result := s.unreadNotifications(r, currentUserID(r)).Update("read_at", time.Now())
+	if result.Error != nil {
+		problem.Write(w, r, problem.Internal("Failed to update notifications"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "All notifications marked as read",
+		Data:    map[string]int64{"updated": result.RowsAffected},
+	})
+}
+
+func (s *Server) unreadNotifications(r *http.Request, userID uint) *gorm.DB {
+	return s.db(r).Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+}
+
+func (s *Server) getNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	settings := NotificationSettings{Muted: []string{}}
+	err := s.db(r).Model(&models.NotificationMute{}).
+		Where("user_id = ?", currentUserID(r)).
+		Order("type").
+		Pluck("type", &settings.Muted).Error
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch notification settings"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: settings,
+	})
+}
+
+// updateNotificationSettings replaces the set of muted notification types.
+func (s *Server) updateNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID := currentUserID(r)
+
+	var req NotificationSettings
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	mutes := []models.NotificationMute{}
+	seen := make(map[string]bool)
+	for _, t := range req.Muted {
+		if !contains(notifications.Types, t) {
+			problem.Write(w, r, problem.Field("muted", fmt.Sprintf("muted types must be among %s", strings.Join(notifications.Types, ", "))))
+			return
+		}
+		if !seen[t] {
+			seen[t] = true
+			mutes = append(mutes, models.NotificationMute{UserID: userID, Type: t})
+		}
+	}
+
+	err := s.db(r).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NotificationMute{}).Error; err != nil {
+			return err
+		}
+		if len(mutes) == 0 {
+			return nil
+		}
+		return tx.Create(&mutes).Error
+	})
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to update notification settings"))
+		return
+	}
+
+	muted := make([]string, len(mutes))
+	for i, m := range mutes {
+		muted[i] = m.Type
+	}
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Notification settings updated",
+		Data:    NotificationSettings{Muted: muted},
+	})
+}
@@ -0,0 +1,83 @@
+// Package logging writes the server log as structured records with log/slog.
+// Records logged with the context of a request carry its request ID and, once
+// authenticated, the user's ID, and secrets such as the Authorization header
+// never reach the log.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"kanban_server/config"
+	"kanban_server/requestid"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Redacted replaces the values of sensitive attributes.
+const Redacted = "[REDACTED]"
+
+// sensitive are the attribute keys, compared in lower case, whose values are
+// redacted. Request headers are logged under their own names, so these
+// include the headers carrying credentials.
+var sensitive = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"password":            true,
+	"token":               true,
+	"secret":              true,
+	"jwt_secret":          true,
+}
+
+// New returns a logger writing records at cfg.Level and above to w, as JSON
+// or, with the "text" format, as key=value pairs.
+func New(cfg config.Log, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level, ReplaceAttr: redact}
+	var handler slog.Handler
+	if cfg.Format == "text" {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitive[strings.ToLower(a.Key)] {
+		a.Value = slog.StringValue(Redacted)
+	}
+	return a
+}
+
+// contextHandler adds what the context knows about the request being served
+// to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if e, ok := ctx.Value(entryKey{}).(*entry); ok && e.userID != 0 {
+		r.AddAttrs(slog.Uint64("user_id", uint64(e.userID)))
+	}
+	// Traces that are not recorded cannot be looked up, so only the IDs of
+	// sampled ones are worth logging.
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+)
+
+type entryKey struct{}
+
+// entry collects what later middleware learns about a request, which the
+// access log cannot read from a context it did not create.
+type entry struct {
+	userID uint
+}
+
+// SetUserID notes the user authenticated for the request of ctx, which is
+// then logged with its records.
+func SetUserID(ctx context.Context, userID uint) {
+	if e, ok := ctx.Value(entryKey{}).(*entry); ok {
+		e.userID = userID
+	}
+}
+
+// Middleware logs each request once it is served, with its route template,
+// status, latency, response size and user. Server errors are logged at
+// error level and client errors at warn level. At debug level the request
+// headers are logged too, with credentials redacted.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx := context.WithValue(r.Context(), entryKey{}, &entry{})
+			rec := &recorder{status: http.StatusOK}
+			next.ServeHTTP(rec.wrap(w), r.WithContext(ctx))
+
+			level := slog.LevelInfo
+			switch {
+			case rec.status >= 500:
+				level = slog.LevelError
+			case rec.status >= 400:
+				level = slog.LevelWarn
+			}
+			if !logger.Enabled(ctx, level) {
+				return
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+			}
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					attrs = append(attrs, slog.String("route", template))
+				}
+			}
+			attrs = append(attrs,
+				slog.Int("status", rec.status),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.Int64("bytes", rec.bytes),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+			if logger.Enabled(ctx, slog.LevelDebug) {
+				headers := make([]any, 0, len(r.Header))
+				for name, values := range r.Header {
+					headers = append(headers, slog.Any(name, values))
+				}
+				attrs = append(attrs, slog.Group("headers", headers...))
+			}
+			logger.LogAttrs(ctx, level, "request", attrs...)
+		})
+	}
+}
+
+// recorder notes the status and size of a response. A hijacked connection,
+// such as a websocket, is logged as 101 Switching Protocols.
+type recorder struct {
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// wrap returns w reporting to rec. It keeps the optional interfaces of w,
+// such as http.Flusher and http.Hijacker.
+func (rec *recorder) wrap(w http.ResponseWriter) http.ResponseWriter {
+	return httpsnoop.Wrap(w, httpsnoop.Hooks{
+		WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+			return func(status int) {
+				if !rec.wroteHeader {
+					rec.status, rec.wroteHeader = status, true
+				}
+				next(status)
+			}
+		},
+		Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+			return func(b []byte) (int, error) {
+				rec.wroteHeader = true
+				n, err := next(b)
+				rec.bytes += int64(n)
+				return n, err
+			}
+		},
+		ReadFrom: func(next httpsnoop.ReadFromFunc) httpsnoop.ReadFromFunc {
+			return func(src io.Reader) (int64, error) {
+				rec.wroteHeader = true
+				n, err := next(src)
+				rec.bytes += n
+				return n, err
+			}
+		},
+		Hijack: func(next httpsnoop.HijackFunc) httpsnoop.HijackFunc {
+			return func() (net.Conn, *bufio.ReadWriter, error) {
+				conn, rw, err := next()
+				if err == nil {
+					rec.status = http.StatusSwitchingProtocols
+				}
+				return conn, rw, err
+			}
+		},
+	})
+}
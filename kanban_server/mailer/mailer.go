@@ -0,0 +1,196 @@
+// Package mailer sends notification emails. Transports implement Mailer;
+// Service renders the emails and applies the recipients' opt-outs.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a multipart/alternative email with a plain-text and an HTML
+// body.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Headers are extra headers such as List-Unsubscribe.
+	Headers map[string]string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Bytes encodes msg in RFC 5322 format.
+func (msg Message) Bytes(now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	headers := map[string]string{
+		"From":         msg.From,
+		"To":           msg.To,
+		"Subject":      mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date":         now.Format(time.RFC1123Z),
+		"Message-ID":   "<" + randomID() + "@" + domain(msg.From) + ">",
+		"MIME-Version": "1.0",
+		"Content-Type": "multipart/alternative; boundary=" + body.Boundary(),
+	}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out bytes.Buffer
+	for _, key := range keys {
+		fmt.Fprintf(&out, "%s: %s\r\n", key, headerReplacer.Replace(headers[key]))
+	}
+	out.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	out.Write(buf.Bytes())
+	return out.Bytes(), nil
+}
+
+// headerReplacer keeps user-controlled values from starting new headers.
+var headerReplacer = strings.NewReplacer("\r", " ", "\n", " ")
+
+// address returns the bare address of a "Name <address>" header value.
+func address(s string) string {
+	if i := strings.LastIndex(s, "<"); i >= 0 {
+		return strings.TrimSuffix(s[i+1:], ">")
+	}
+	return strings.TrimSpace(s)
+}
+
+func domain(from string) string {
+	if _, d, ok := strings.Cut(address(from), "@"); ok && d != "" {
+		return d
+	}
+	return "localhost"
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SMTPMailer sends mail through an SMTP server, using STARTTLS when the
+// server offers it and PLAIN authentication when a username is set.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+func (m SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := msg.Bytes(time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(m.Host+":"+m.Port, auth, address(msg.From), []string{address(msg.To)}, data)
+}
+
+// FileMailer stores every message as a file in a maildir, for development.
+// Mail clients such as mutt can open the directory directly.
+type FileMailer struct {
+	Dir string
+
+	mu    sync.Mutex
+	count int
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := msg.Bytes(now)
+	if err != nil {
+		return err
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(m.Dir, sub), 0o755); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.count++
+	count := m.count
+	m.mu.Unlock()
+	host, _ := os.Hostname()
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(), count, host)
+
+	// Maildir delivery: write to tmp, then move into new atomically.
+	tmp := filepath.Join(m.Dir, "tmp", name)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(m.Dir, "new", name))
+}
+
+// MemoryMailer keeps messages in memory, for tests.
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of the messages sent so far.
+func (m *MemoryMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
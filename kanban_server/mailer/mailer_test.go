@@ -0,0 +1,133 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	data := EmailData{
+		Name:           "Ana",
+		Actor:          "Bob",
+		TaskTitle:      "Fix <script> tags",
+		ProjectTitle:   "Website",
+		Comment:        "@ana can you look?",
+		Link:           "http://localhost:5000/projects/1/tasks/2",
+		UnsubscribeURL: "http://localhost:5000/unsubscribe?token=t&type=mentions",
+	}
+
+	subject, text, html, err := Render(KindMention, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != `Bob mentioned you on "Fix <script> tags"` {
+		t.Errorf("subject = %q", subject)
+	}
+	for _, want := range []string{"Hi Ana,", "> @ana can you look?", data.Link, data.UnsubscribeURL} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text body is missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "Fix &lt;script&gt; tags") {
+		t.Errorf("HTML body does not escape the task title:\n%s", html)
+	}
+	if !strings.Contains(html, `href="http://localhost:5000/unsubscribe?token=t&amp;type=mentions"`) {
+		t.Errorf("HTML body is missing the unsubscribe link:\n%s", html)
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	data := EmailData{
+		Name: "Ana",
+		Items: []DigestItem{
+			{Message: `You were assigned "Deploy"`, Link: "http://x/projects/1/tasks/3", At: at},
+			{Message: `You were added to "Website"`, At: at},
+		},
+	}
+	subject, text, _, err := Render(KindDigest, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "You have 2 unread notifications" {
+		t.Errorf("subject = %q", subject)
+	}
+	if !strings.Contains(text, `- You were assigned "Deploy" (Mar 1 09:30)`) {
+		t.Errorf("text body is missing the digest item:\n%s", text)
+	}
+}
+
+func TestMessageBytes(t *testing.T) {
+	msg := Message{
+		From:    "Kanban <no-reply@example.com>",
+		To:      "Ana <ana@example.com>",
+		Subject: "Grüße\r\nBcc: evil@example.com",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<http://x/unsubscribe>"},
+	}
+	data, err := msg.Bytes(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Header.Get("Bcc"); got != "" {
+		t.Errorf("subject injected a Bcc header: %q", got)
+	}
+	if got := parsed.Header.Get("List-Unsubscribe"); got != "<http://x/unsubscribe>" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+	if !strings.HasSuffix(parsed.Header.Get("Message-Id"), "@example.com>") {
+		t.Errorf("Message-ID = %q", parsed.Header.Get("Message-Id"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q", parsed.Header.Get("Content-Type"))
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		bodies = append(bodies, string(body))
+	}
+	if len(bodies) != 2 || bodies[0] != "plain body" || bodies[1] != "<p>html body</p>" {
+		t.Errorf("bodies = %q", bodies)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: dir}
+	for i := 0; i < 2; i++ {
+		if err := m.Send(context.Background(), Message{From: "a@example.com", To: "b@example.com", Subject: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	delivered, _ := os.ReadDir(filepath.Join(dir, "new"))
+	pending, _ := os.ReadDir(filepath.Join(dir, "tmp"))
+	if len(delivered) != 2 || len(pending) != 0 {
+		t.Errorf("maildir has %d new and %d tmp messages, want 2 and 0", len(delivered), len(pending))
+	}
+}
@@ -0,0 +1,320 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/notifications"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Unsubscribe targets accepted by Unsubscribe, besides UnsubscribeAll.
+const (
+	UnsubscribeAssignments = "assignments"
+	UnsubscribeMentions    = "mentions"
+	UnsubscribeDigest      = "digest"
+	UnsubscribeAll         = "all"
+)
+
+var ErrInvalidUnsubscribe = errors.New("invalid unsubscribe token or type")
+
+// Service emails notifications to users who have not opted out.
+type Service struct {
+	db      *gorm.DB
+	mailer  Mailer
+	from    string
+	baseURL string
+	now     func() time.Time
+}
+
+func NewService(db *gorm.DB, mailer Mailer, from, baseURL string) *Service {
+	return &Service{db: db, mailer: mailer, from: from, baseURL: baseURL, now: time.Now}
+}
+
+// Preference returns a user's email settings, creating the defaults (all
+// emails enabled) on first use.
+func Preference(db *gorm.DB, userID uint) (models.EmailPreference, error) {
+	pref := models.EmailPreference{
+		UserID:           userID,
+		Assignments:      true,
+		Mentions:         true,
+		Digest:           true,
+		UnsubscribeToken: newToken(),
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&pref).Error; err != nil {
+		return pref, err
+	}
+	err := db.Where("user_id = ?", userID).First(&pref).Error
+	return pref, err
+}
+
+// unsubscribeUpdates returns the preference changes that unsubscribe from
+// kind.
+func unsubscribeUpdates(kind string) (map[string]interface{}, error) {
+	switch kind {
+	case UnsubscribeAssignments, UnsubscribeMentions, UnsubscribeDigest:
+		return map[string]interface{}{kind: false}, nil
+	case UnsubscribeAll, "":
+		return map[string]interface{}{
+			UnsubscribeAssignments: false,
+			UnsubscribeMentions:    false,
+			UnsubscribeDigest:      false,
+		}, nil
+	}
+	return nil, ErrInvalidUnsubscribe
+}
+
+// CheckUnsubscribe reports whether Unsubscribe would accept token and kind,
+// without changing anything.
+func CheckUnsubscribe(db *gorm.DB, token, kind string) error {
+	if _, err := unsubscribeUpdates(kind); err != nil || token == "" {
+		return ErrInvalidUnsubscribe
+	}
+	var count int64
+	if err := db.Model(&models.EmailPreference{}).Where("unsubscribe_token = ?", token).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrInvalidUnsubscribe
+	}
+	return nil
+}
+
+// Unsubscribe turns off one kind of email, or all of them, for the user
+// owning token.
+func Unsubscribe(db *gorm.DB, token, kind string) error {
+	updates, err := unsubscribeUpdates(kind)
+	if err != nil || token == "" {
+		return ErrInvalidUnsubscribe
+	}
+
+	result := db.Model(&models.EmailPreference{}).Where("unsubscribe_token = ?", token).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidUnsubscribe
+	}
+	return nil
+}
+
+func newToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Notify emails the assignment and mention notifications among ns. Other
+// types only appear in the digest.
+func (s *Service) Notify(ctx context.Context, ns ...models.Notification) error {
+	db := s.db.WithContext(ctx)
+	var first error
+	for _, n := range ns {
+		var kind, target string
+		switch n.Type {
+		case notifications.TypeAssigned:
+			kind, target = KindAssignment, UnsubscribeAssignments
+		case notifications.TypeMention:
+			kind, target = KindMention, UnsubscribeMentions
+		default:
+			continue
+		}
+
+		if err := s.notify(ctx, db, n, kind, target); err != nil && first == nil {
+			first = fmt.Errorf("emailing notification %d: %w", n.ID, err)
+		}
+	}
+	return first
+}
+
+func (s *Service) notify(ctx context.Context, db *gorm.DB, n models.Notification, kind, target string) error {
+	pref, err := Preference(db, n.UserID)
+	if err != nil {
+		return err
+	}
+	if (target == UnsubscribeAssignments && !pref.Assignments) || (target == UnsubscribeMentions && !pref.Mentions) {
+		return nil
+	}
+
+	var recipient, actor models.User
+	var task models.Task
+	var project models.Project
+	if err := db.First(&recipient, n.UserID).Error; err != nil {
+		return err
+	}
+	if err := db.First(&task, n.TaskID).Error; err != nil {
+		return err
+	}
+	if err := db.First(&project, task.ProjectID).Error; err != nil {
+		return err
+	}
+	actor.Name = "Someone"
+	if n.ActorID != 0 {
+		db.First(&actor, n.ActorID)
+	}
+
+	data := EmailData{
+		Name:         recipient.Name,
+		Actor:        actor.Name,
+		TaskTitle:    task.Title,
+		ProjectTitle: project.Title,
+		Link:         s.link(n),
+	}
+	if n.CommentID != 0 {
+		var comment models.Comment
+		if err := db.First(&comment, n.CommentID).Error; err == nil {
+			data.Comment = comment.Body
+		}
+	}
+	return s.send(ctx, recipient, pref, kind, target, data)
+}
+
+func (s *Service) send(ctx context.Context, recipient models.User, pref models.EmailPreference, kind, target string, data EmailData) error {
+	unsubscribe := s.unsubscribeURL(pref.UnsubscribeToken, target)
+	data.UnsubscribeURL = unsubscribe
+
+	subject, text, html, err := Render(kind, data)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, Message{
+		From:    s.from,
+		To:      (&mail.Address{Name: recipient.Name, Address: recipient.Email}).String(),
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribe + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
+
+func (s *Service) link(n models.Notification) string {
+	switch {
+	case n.TaskID != 0:
+		return fmt.Sprintf("%s/projects/%d/tasks/%d", s.baseURL, n.ProjectID, n.TaskID)
+	case n.ProjectID != 0:
+		return fmt.Sprintf("%s/projects/%d", s.baseURL, n.ProjectID)
+	}
+	return ""
+}
+
+func (s *Service) unsubscribeURL(token, target string) string {
+	return s.baseURL + "/unsubscribe?" + url.Values{"token": {token}, "type": {target}}.Encode()
+}
+
+// DigestWorker periodically emails every user a summary of the unread
+// notifications they received since their previous digest.
+type DigestWorker struct {
+	service  *Service
+	interval time.Duration
+}
+
+func NewDigestWorker(service *Service, interval time.Duration) *DigestWorker {
+	return &DigestWorker{service: service, interval: interval}
+}
+
+// Run sends digests every interval until ctx is cancelled.
+func (w *DigestWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if sent, err := w.RunOnce(ctx); err != nil {
+			log.Println("Digest worker:", err)
+		} else if sent > 0 {
+			log.Printf("Digest worker sent %d digests\n", sent)
+		}
+	}
+}
+
+// RunOnce sends the digests that are due and returns how many were sent.
+// A digest covers at most one interval, so users are not flooded with old
+// notifications when digests are first enabled.
+func (w *DigestWorker) RunOnce(ctx context.Context) (int, error) {
+	s := w.service
+	db := s.db.WithContext(ctx)
+	now := s.now()
+	windowStart := now.Add(-w.interval)
+
+	var userIDs []uint
+	err := db.Model(&models.Notification{}).
+		Where("read_at IS NULL AND created_at > ?", windowStart).
+		Distinct().Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		ok, err := w.digest(ctx, db, userID, windowStart, now)
+		if err != nil {
+			log.Printf("Digest worker: user %d: %v\n", userID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (w *DigestWorker) digest(ctx context.Context, db *gorm.DB, userID uint, windowStart, now time.Time) (bool, error) {
+	s := w.service
+	pref, err := Preference(db, userID)
+	if err != nil || !pref.Digest {
+		return false, err
+	}
+	since := windowStart
+	if pref.LastDigestAt.After(since) {
+		since = pref.LastDigestAt
+	}
+
+	var unread []models.Notification
+	err = db.Where("user_id = ? AND read_at IS NULL AND created_at > ? AND created_at <= ?", userID, since, now).
+		Order("created_at").Find(&unread).Error
+	if err != nil || len(unread) == 0 {
+		return false, err
+	}
+
+	// Claim the digest so concurrent workers do not send it twice.
+	claim := db.Model(&models.EmailPreference{}).
+		Where("user_id = ? AND last_digest_at = ?", userID, pref.LastDigestAt).
+		Update("last_digest_at", now)
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return false, claim.Error
+	}
+
+	var recipient models.User
+	if err := db.First(&recipient, userID).Error; err != nil {
+		return false, err
+	}
+	data := EmailData{Name: recipient.Name}
+	for _, n := range unread {
+		data.Items = append(data.Items, DigestItem{Message: n.Message, Link: s.link(n), At: n.CreatedAt})
+	}
+	if err := s.send(ctx, recipient, pref, KindDigest, UnsubscribeDigest, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,96 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Email kinds, each with a <kind>.txt and <kind>.html template.
+const (
+	KindAssignment = "assignment"
+	KindMention    = "mention"
+	KindDigest     = "digest"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	textTemplates = make(map[string]*texttemplate.Template)
+	htmlTemplates = make(map[string]*htmltemplate.Template)
+
+	unsubscribeTemplate = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/unsubscribe.html"))
+)
+
+func init() {
+	for _, kind := range []string{KindAssignment, KindMention, KindDigest} {
+		textTemplates[kind] = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/layout.txt", "templates/"+kind+".txt"))
+		htmlTemplates[kind] = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+kind+".html"))
+	}
+}
+
+// EmailData is the input of every template. Fields a kind does not use are
+// left empty.
+type EmailData struct {
+	Name           string
+	Actor          string
+	TaskTitle      string
+	ProjectTitle   string
+	Comment        string
+	Link           string
+	Items          []DigestItem
+	UnsubscribeURL string
+}
+
+// DigestItem is one unread notification in a digest.
+type DigestItem struct {
+	Message string
+	Link    string
+	At      time.Time
+}
+
+// Render returns the subject and bodies of an email of the given kind.
+func Render(kind string, data EmailData) (subject, text, html string, err error) {
+	var buf bytes.Buffer
+	if err := textTemplates[kind].ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := textTemplates[kind].ExecuteTemplate(&buf, "layout", data); err != nil {
+		return "", "", "", err
+	}
+	text = buf.String()
+
+	buf.Reset()
+	if err := htmlTemplates[kind].ExecuteTemplate(&buf, "layout", data); err != nil {
+		return "", "", "", err
+	}
+	return subject, text, buf.String(), nil
+}
+
+// UnsubscribePage is the input of the page an unsubscribe link opens.
+type UnsubscribePage struct {
+	// Kind is the type of email, or UnsubscribeAll.
+	Kind string
+	// Done is set once the user confirmed; until then the page asks them to.
+	Done bool
+}
+
+// RenderUnsubscribePage returns the HTML of the unsubscribe page. The
+// confirmation form posts back to the URL the page was served from.
+func RenderUnsubscribePage(page UnsubscribePage) (string, error) {
+	if page.Kind == "" {
+		page.Kind = UnsubscribeAll
+	}
+	var buf bytes.Buffer
+	if err := unsubscribeTemplate.Execute(&buf, page); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -1,99 +1,523 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"kanban_server/config"
+	"kanban_server/events"
+	"kanban_server/graph"
+	"kanban_server/logging"
+	"kanban_server/mailer"
+	"kanban_server/metrics"
 	"kanban_server/models"
+	"kanban_server/notifications"
+	"kanban_server/openapi"
+	"kanban_server/problem"
+	"kanban_server/recurrence"
+	"kanban_server/reminders"
+	"kanban_server/requestid"
+	"kanban_server/rpc"
+	"kanban_server/service"
+	"kanban_server/store"
+	"kanban_server/store/gormstore"
+	"kanban_server/telemetry"
+	"kanban_server/validation"
+	"kanban_server/webhooks"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
 )
 
 type RouteResponse struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
+// RegisterRequest caps passwords at 72 bytes, the most bcrypt hashes.
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Name     string `json:"name"`
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Name     string `json:"name" validate:"required,notblank,max=100"`
 }
 
 type ProjectRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title       string `json:"title" validate:"required,notblank,max=200"`
+	Description string `json:"description" validate:"max=10000"`
 }
 
-var jwtKey = []byte("your-secret-key") // In production, use environment variable
+type contextKey string
+
+const userIDKey contextKey = "user_id"
+
+// Server holds what the HTTP handlers depend on.
+type Server struct {
+	Config *config.Config
+	// DB serves the handlers whose records have no repository yet.
+	DB       *gorm.DB
+	Users    store.Users
+	Projects store.Projects
+	Tasks    store.Tasks
+	// Kanban performs the project and task changes, shared with the GraphQL
+	// and gRPC APIs.
+	Kanban *service.Service
+	// Bus carries board events from the handlers to webhooks and other
+	// subscribers.
+	Bus *events.Bus
+	// Emails sends notification emails. It is nil when email is disabled.
+	Emails *mailer.Service
+	// Metrics is served on /metrics. It is nil when metrics are not
+	// collected.
+	Metrics *metrics.Metrics
+	// Tracing records a span for each request and the queries made for it.
+	Tracing trace.TracerProvider
+	// Logger logs each request.
+	Logger *slog.Logger
+
+	// workers run in the background until shutdown.
+	workers *workerGroup
+	// draining is set once shutdown begins, failing readiness.
+	draining atomic.Bool
+	// background tracks work that outlives its request, such as sending
+	// emails, so that shutdown can wait for it.
+	background sync.WaitGroup
+}
+
+// newServer returns a server configured by cfg, storing users, projects and
+// tasks in repos and everything else in db.
+func newServer(cfg *config.Config, db *gorm.DB, repos store.Store) *Server {
+	s := &Server{Config: cfg, DB: db, Users: repos, Projects: repos, Tasks: repos, Bus: events.NewBus(), Tracing: noop.NewTracerProvider(), Logger: slog.Default(), workers: newWorkerGroup()}
+	s.Kanban = &service.Service{Users: repos, Projects: repos, Tasks: repos, Bus: s.Bus, Notify: s.deliverNotifications}
+	return s
+}
+
+// db returns the database for queries made on behalf of r, so that they
+// are cancelled along with the request and traced as part of it.
+func (s *Server) db(r *http.Request) *gorm.DB {
+	return s.DB.WithContext(r.Context())
+}
+
+// publishEvent publishes a change made by the current request.
+func (s *Server) publishEvent(r *http.Request, eventType string, projectID uint, data interface{}) {
+	s.Bus.Publish(events.New(eventType, projectID, currentUserID(r), data))
+}
+
+// restFields names the request members behind the fields of
+// service.ValidationError where they differ.
+var restFields = map[string]string{
+	"assignee": "assigned_to",
+	"column":   "column_id",
+	"due date": "due_date",
+}
+
+// writeError reports err as a problem. Problems are sent as they are,
+// service errors get their status and anything else is logged and replaced
+// by a 500 with the fallback detail.
+func writeError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	var p *problem.Problem
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &p):
+	case errors.As(err, &invalid):
+		field := invalid.Field
+		if name, ok := restFields[field]; ok {
+			field = name
+		}
+		p = problem.Field(field, invalid.Error())
+	case errors.Is(err, service.ErrProjectNotFound):
+		p = problem.NotFound("Project not found")
+	case errors.Is(err, service.ErrTaskNotFound):
+		p = problem.NotFound("Task not found")
+	case errors.Is(err, service.ErrMemberNotFound):
+		p = problem.NotFound("Member not found")
+	case errors.Is(err, service.ErrAlreadyMember):
+		p = problem.Conflict("User is already a member of this project")
+	default:
+		slog.ErrorContext(r.Context(), "Request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		p = problem.Internal(fallback)
+	}
+	problem.Write(w, r, p)
+}
+
+// writeLookupError reports err from loading a single record: a 404 with the
+// notFound detail if there is no such record, and a 500 otherwise, so that a
+// failing database is not mistaken for a missing record.
+func writeLookupError(w http.ResponseWriter, r *http.Request, err error, notFound string) {
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Write(w, r, problem.NotFound(notFound))
+		return
+	}
+	writeError(w, r, err, "Failed to load record")
+}
+
+// readJSON decodes the request body into v and validates it, reporting any
+// problem to the client.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		problem.Write(w, r, problem.BadRequest("Invalid request body"))
+		return false
+	}
+	if err := validation.Struct(v); err != nil {
+		writeError(w, r, err, "Invalid request body")
+		return false
+	}
+	return true
+}
+
+// currentUserID returns the ID of the user authenticated by authMiddleware.
+func currentUserID(r *http.Request) uint {
+	userID, _ := r.Context().Value(userIDKey).(uint)
+	return userID
+}
+
+// pathID parses the named route variable as a record ID.
+func pathID(r *http.Request, name string) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)[name], 10, 64)
+	return uint(id), err == nil
+}
 
 func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	// The standard log package writes through the default logger too.
+	slog.SetDefault(logging.New(cfg.Log, os.Stderr))
 	log.Println("Starting server")
 
-	// Initialize database
-	config.InitDB()
+	exporter, err := telemetry.NewExporter(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tracing := telemetry.NewTracerProvider(cfg.Tracing, exporter)
+	otel.SetTracerProvider(tracing)
+	otel.SetTextMapPropagator(telemetry.Propagator)
+
+	db, err := config.OpenDB(cfg.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.Use(telemetry.NewGORMPlugin(tracing)); err != nil {
+		log.Fatal("Failed to trace database queries:", err)
+	}
+	log.Println("Database connection established")
+	s := newServer(cfg, db, gormstore.New(db))
+	s.Tracing = tracing
+
+	s.workers.Go("recurrence", recurrence.NewScheduler(db, cfg.Jobs.RecurrenceInterval).Run)
+	s.workers.Go("reminders", reminders.NewWorker(db, reminders.MultiNotifier{reminders.LogNotifier{}, notifications.InboxNotifier{DB: db}}, cfg.Jobs.ReminderInterval, cfg.Jobs.ReminderEscalateAfter).Run)
+
+	s.workers.Go("idempotency", s.purgeIdempotencyKeys(cfg.Jobs.IdempotencyPurgeInterval))
+
+	s.Bus.Subscribe(func(e events.Event) {
+		if err := webhooks.Enqueue(db, e); err != nil {
+			log.Printf("Failed to queue webhooks for %s: %v\n", e.Type, err)
+		}
+	})
+	webhookClient := webhooks.NewClient(cfg.Jobs.WebhookTimeout)
+	webhookClient.Transport = telemetry.Transport(tracing, webhookClient.Transport)
+	s.workers.Go("webhooks", webhooks.NewDeliverer(db, webhookClient, cfg.Jobs.WebhookInterval).Run)
+
+	mail, err := newMailer(cfg.Mail)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if mail != nil {
+		s.Emails = mailer.NewService(db, mail, cfg.Mail.From, cfg.HTTP.PublicURL)
+		s.workers.Go("digest", mailer.NewDigestWorker(s.Emails, cfg.Jobs.DigestInterval).Run)
+	}
+
+	schema, err := graph.NewSchema(&graph.Resolver{Service: s.Kanban, DB: db})
+	if err != nil {
+		log.Fatal("Failed to load GraphQL schema:", err)
+	}
+
+	apiDoc, err := openapi.Load()
+	if err != nil {
+		log.Fatal("Failed to load OpenAPI document:", err)
+	}
+	validator, err := openapi.NewValidator(apiDoc)
+	if err != nil {
+		log.Fatal("Failed to build OpenAPI validator:", err)
+	}
+	validator.ValidateResponses = cfg.Env == "test"
+
+	graphQL := graph.NewHandler(schema, db, s.parseToken)
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.Metrics = metrics.New()
+	s.Metrics.Register(
+		collectors.NewDBStatsCollector(sqlDB, cfg.DB.Driver),
+		metrics.NewBoardCollector(db),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Name:      "websocket_connections",
+			Help:      "Open GraphQL websocket connections.",
+		}, func() float64 { return float64(graphQL.Connections()) }),
+	)
+
+	router := s.newRouter(graphQL, validator)
+	httpServer := &http.Server{
+		Addr:              cfg.HTTP.Addr,
+		Handler:           requestid.Middleware(s.Metrics.Middleware(router)(corsMiddleware(cfg.CORS)(router))),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+	}
+
+	grpcListener, err := net.Listen("tcp", cfg.GRPC.Addr)
+	if err != nil {
+		log.Fatal("Failed to listen for gRPC:", err)
+	}
+	// Watch streams last until the client leaves, so they are ended when
+	// shutting down for GracefulStop to return.
+	endStreams, stopStreams := context.WithCancel(context.Background())
+	grpcServer := rpc.NewServer(s.Kanban, s.parseToken, rpc.EndStreams(endStreams))
+
+	failed := make(chan error, 2)
+	go func() {
+		log.Printf("gRPC listening on %s...\n", cfg.GRPC.Addr)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			failed <- fmt.Errorf("gRPC server failed: %w", err)
+		}
+	}()
+	go func() {
+		log.Printf("Listening on %s...\n", cfg.HTTP.Addr)
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			failed <- fmt.Errorf("HTTP server failed: %w", err)
+		}
+	}()
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	var serveErr error
+	select {
+	case <-signals.Done():
+		log.Println("Shutting down")
+	case serveErr = <-failed:
+		log.Println(serveErr)
+	}
+	stopSignals()
 
+	// Readiness fails from here on. Serving on for a while lets load
+	// balancers notice before connections are refused.
+	s.draining.Store(true)
+	if serveErr == nil {
+		time.Sleep(cfg.HTTP.ShutdownDelay)
+	}
+	stopStreams()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	err = s.shutdown(ctx, httpServer, grpcServer, graphQL)
+	// Spans still queued are sent last. Losing them, say with the collector
+	// down, does not make the shutdown unclean.
+	if err := tracing.Shutdown(ctx); err != nil {
+		log.Println("Failed to send the remaining spans:", err)
+	}
+	cancel()
+	if err != nil {
+		log.Fatal("Shutdown was not clean: ", err)
+	}
+	if serveErr != nil {
+		os.Exit(1)
+	}
+	log.Println("Server stopped")
+}
+
+// newRouter registers every route. The GraphQL handler is passed in since it
+// needs the schema built at startup.
+func (s *Server) newRouter(graphQL http.Handler, validator *openapi.Validator) *mux.Router {
 	router := mux.NewRouter()
+	// Middleware set with Use only runs for requests a route matched, so the
+	// handlers of the others are traced and logged too.
+	observed := alice.New(telemetry.Middleware(s.Tracing), logging.Middleware(s.Logger))
+	router.Use(observed.Append(s.limitBody, validator.Middleware).Then)
+	router.NotFoundHandler = observed.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.NotFound("No route matches "+r.URL.Path))
+	})
+	router.MethodNotAllowedHandler = observed.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.New(http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path))
+	})
 
 	log.Println("Setting up routes")
 
-	router.Handle("/register", alice.New(loggingMiddleware).ThenFunc(register)).Methods("POST")
-	router.Handle("/login", alice.New(loggingMiddleware).ThenFunc(login)).Methods("POST")
-	router.Handle("/projects", alice.New(loggingMiddleware, authMiddleware).ThenFunc(createProject)).Methods("POST")
-	router.Handle("/projects/{id}", alice.New(loggingMiddleware, authMiddleware).ThenFunc(updateProject)).Methods("PUT")
-	router.Handle("/projects/{id}", alice.New(loggingMiddleware, authMiddleware).ThenFunc(deleteProject)).Methods("DELETE")
-	router.Handle("/projects/{id}", alice.New(loggingMiddleware, authMiddleware).ThenFunc(getProject)).Methods("GET")
-	router.Handle("/projects", alice.New(loggingMiddleware, authMiddleware).ThenFunc(getProjects)).Methods("GET")
+	router.HandleFunc("/healthz", s.checkHealth).Methods("GET")
+	router.HandleFunc("/readyz", s.checkReadiness).Methods("GET")
+	router.HandleFunc("/version", s.getVersion).Methods("GET")
+	if s.Metrics != nil {
+		router.Handle("/metrics", s.Metrics.Handler()).Methods("GET")
+	}
+	router.Handle("/openapi.json", openapi.Handler()).Methods("GET")
+
+	router.HandleFunc("/register", s.register).Methods("POST")
+	router.HandleFunc("/login", s.login).Methods("POST")
+	router.HandleFunc("/unsubscribe", s.unsubscribe).Methods("GET", "POST")
+	router.HandleFunc("/calendar/{token}.ics", s.serveCalendarFeed).Methods("GET")
+	// The GraphQL handler authenticates itself, since websocket clients send
+	// their token after connecting.
+	router.Handle("/graphql", graphQL).Methods("GET", "POST")
+	router.Handle("/projects", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.createProject)).Methods("POST")
+	router.Handle("/projects/import", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.importProject)).Methods("POST")
+	router.Handle("/projects/import/trello", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.importTrelloBoard)).Methods("POST")
+	router.Handle("/projects/{id}", alice.New(s.authMiddleware).ThenFunc(s.updateProject)).Methods("PUT")
+	router.Handle("/projects/{id}", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.patchProject)).Methods("PATCH")
+	router.Handle("/projects/{id}", alice.New(s.authMiddleware).ThenFunc(s.deleteProject)).Methods("DELETE")
+	router.Handle("/projects/{id}", alice.New(s.authMiddleware).ThenFunc(s.getProject)).Methods("GET")
+	router.Handle("/projects", alice.New(s.authMiddleware).ThenFunc(s.getProjects)).Methods("GET")
+	router.Handle("/projects/{id}/export", alice.New(s.authMiddleware).ThenFunc(s.exportProject)).Methods("GET")
+	router.Handle("/projects/{id}/clone", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.cloneProject)).Methods("POST")
+	router.Handle("/projects/{id}/template", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.createTemplate)).Methods("POST")
+	router.Handle("/me/reminders", alice.New(s.authMiddleware).ThenFunc(s.getReminderPreferences)).Methods("GET")
+	router.Handle("/me/reminders", alice.New(s.authMiddleware).ThenFunc(s.updateReminderPreferences)).Methods("PUT")
+	router.Handle("/me/calendar", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.createCalendarFeed)).Methods("POST")
+	router.Handle("/me/calendar", alice.New(s.authMiddleware).ThenFunc(s.getCalendarFeed)).Methods("GET")
+	router.Handle("/me/calendar", alice.New(s.authMiddleware).ThenFunc(s.revokeCalendarFeed)).Methods("DELETE")
+	router.Handle("/me/email", alice.New(s.authMiddleware).ThenFunc(s.getEmailPreferences)).Methods("GET")
+	router.Handle("/me/email", alice.New(s.authMiddleware).ThenFunc(s.updateEmailPreferences)).Methods("PUT")
+	router.Handle("/me/notifications", alice.New(s.authMiddleware).ThenFunc(s.getNotificationSettings)).Methods("GET")
+	router.Handle("/me/notifications", alice.New(s.authMiddleware).ThenFunc(s.updateNotificationSettings)).Methods("PUT")
+	router.Handle("/notifications", alice.New(s.authMiddleware).ThenFunc(s.getNotifications)).Methods("GET")
+	router.Handle("/notifications/unread-count", alice.New(s.authMiddleware).ThenFunc(s.getUnreadNotificationCount)).Methods("GET")
+	router.Handle("/notifications/read-all", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.markAllNotificationsRead)).Methods("POST")
+	router.Handle("/notifications/{id}/read", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.markNotificationRead)).Methods("POST")
+	router.Handle("/templates", alice.New(s.authMiddleware).ThenFunc(s.getTemplates)).Methods("GET")
+	router.Handle("/templates/{id}", alice.New(s.authMiddleware).ThenFunc(s.getTemplate)).Methods("GET")
+	router.Handle("/templates/{id}", alice.New(s.authMiddleware).ThenFunc(s.deleteTemplate)).Methods("DELETE")
+	router.Handle("/templates/{id}/instantiate", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.instantiateTemplate)).Methods("POST")
+	router.Handle("/projects/{id}/tasks/bulk", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.bulkTasks)).Methods("POST")
+	router.Handle("/projects/{id}/tasks/{taskId}", alice.New(s.authMiddleware).ThenFunc(s.getTask)).Methods("GET")
+	router.Handle("/projects/{id}/tasks/{taskId}", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.patchTask)).Methods("PATCH")
+	router.Handle("/projects/{id}/calendar", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.createCalendarFeed)).Methods("POST")
+	router.Handle("/projects/{id}/calendar", alice.New(s.authMiddleware).ThenFunc(s.getCalendarFeed)).Methods("GET")
+	router.Handle("/projects/{id}/calendar", alice.New(s.authMiddleware).ThenFunc(s.revokeCalendarFeed)).Methods("DELETE")
+	router.Handle("/projects/{id}/webhooks", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.createWebhook)).Methods("POST")
+	router.Handle("/projects/{id}/webhooks", alice.New(s.authMiddleware).ThenFunc(s.getWebhooks)).Methods("GET")
+	router.Handle("/projects/{id}/webhooks/{webhookId}", alice.New(s.authMiddleware).ThenFunc(s.getWebhook)).Methods("GET")
+	router.Handle("/projects/{id}/webhooks/{webhookId}", alice.New(s.authMiddleware).ThenFunc(s.updateWebhook)).Methods("PUT")
+	router.Handle("/projects/{id}/webhooks/{webhookId}", alice.New(s.authMiddleware).ThenFunc(s.deleteWebhook)).Methods("DELETE")
+	router.Handle("/projects/{id}/webhooks/{webhookId}/deliveries", alice.New(s.authMiddleware).ThenFunc(s.getWebhookDeliveries)).Methods("GET")
+	router.Handle("/projects/{id}/webhooks/{webhookId}/deliveries/{deliveryId}", alice.New(s.authMiddleware).ThenFunc(s.getWebhookDelivery)).Methods("GET")
+	router.Handle("/projects/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.redeliverWebhook)).Methods("POST")
+	router.Handle("/projects/{id}/members", alice.New(s.authMiddleware).ThenFunc(s.getProjectMembers)).Methods("GET")
+	router.Handle("/projects/{id}/members", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.addProjectMember)).Methods("POST")
+	router.Handle("/projects/{id}/members/{userId}", alice.New(s.authMiddleware).ThenFunc(s.removeProjectMember)).Methods("DELETE")
+	router.Handle("/projects/{id}/tasks/{taskId}/comments", alice.New(s.authMiddleware).ThenFunc(s.getComments)).Methods("GET")
+	router.Handle("/projects/{id}/tasks/{taskId}/comments", alice.New(s.authMiddleware, s.idempotencyMiddleware).ThenFunc(s.createComment)).Methods("POST")
+	router.Handle("/projects/{id}/tasks/{taskId}/recurrence", alice.New(s.authMiddleware).ThenFunc(s.setTaskRecurrence)).Methods("PUT")
+	router.Handle("/projects/{id}/tasks/{taskId}/recurrence", alice.New(s.authMiddleware).ThenFunc(s.getTaskRecurrence)).Methods("GET")
+	router.Handle("/projects/{id}/tasks/{taskId}/recurrence", alice.New(s.authMiddleware).ThenFunc(s.stopTaskRecurrence)).Methods("DELETE")
+
+	return router
+}
 
-	log.Println("Listening on port 5000...")
-	log.Fatal(http.ListenAndServe(":5000", router))
+// bodyLimits are the routes accepting bodies larger than HTTP.MaxBodyBytes,
+// by path template.
+var bodyLimits = map[string]int64{
+	"/projects/import/trello": maxTrelloExportSize,
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
+// limitBody caps the request body at HTTP.MaxBodyBytes or the route's own
+// limit. A body declaring a larger length is rejected at once; one that
+// turns out larger fails to read.
+func (s *Server) limitBody(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s %s\n", r.RemoteAddr, r.Method, r.URL)
+		limit := int64(s.Config.HTTP.MaxBodyBytes)
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				if n, ok := bodyLimits[template]; ok {
+					limit = n
+				}
+			}
+		}
+		if r.ContentLength > limit {
+			problem.Write(w, r, problem.New(http.StatusRequestEntityTooLarge, problem.CodeTooLarge, fmt.Sprintf("Request body exceeds %d bytes", limit)))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
 		next.ServeHTTP(w, r)
 	})
 }
 
-func authMiddleware(next http.Handler) http.Handler {
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tokenString := r.Header.Get("Authorization")
 		if tokenString == "" {
-			json.NewEncoder(w).Encode(RouteResponse{Error: "Authorization header required"})
+			problem.Write(w, r, problem.Unauthorized("Authorization header required"))
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			json.NewEncoder(w).Encode(RouteResponse{Error: "Invalid token"})
+		userID, err := s.parseToken(tokenString)
+		if err != nil {
+			problem.Write(w, r, problem.Unauthorized("Invalid token"))
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		logging.SetUserID(r.Context(), userID)
+		ctx := context.WithValue(r.Context(), userIDKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func register(w http.ResponseWriter, r *http.Request) {
+// parseToken validates a JWT issued by login and returns its user ID.
+func (s *Server) parseToken(tokenString string) (uint, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(s.Config.Auth.JWTSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, errInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	userID, hasUserID := claims["user_id"].(float64)
+	if !ok || !hasUserID {
+		return 0, errInvalidToken
+	}
+	return uint(userID), nil
+}
+
+var errInvalidToken = errors.New("invalid token")
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var req RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Invalid request body"})
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if _, err := s.Users.FindUserByEmail(r.Context(), req.Email); err == nil {
+		problem.Write(w, r, problem.Conflict("Email is already registered"))
 		return
 	}
 
@@ -103,8 +527,8 @@ func register(w http.ResponseWriter, r *http.Request) {
 		Name:     req.Name,
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Failed to create user"})
+	if err := s.Users.CreateUser(r.Context(), &user); err != nil {
+		problem.Write(w, r, problem.Internal("Failed to create user"))
 		return
 	}
 
@@ -114,34 +538,33 @@ func register(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func login(w http.ResponseWriter, r *http.Request) {
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Invalid request body"})
+	if !readJSON(w, r, &req) {
 		return
 	}
 
-	var user models.User
-	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Invalid credentials"})
+	user, err := s.Users.FindUserByEmail(r.Context(), req.Email)
+	if err != nil {
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials, "Invalid credentials"))
 		return
 	}
 
-	if err := user.CheckPassword(req.Password); err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Invalid credentials"})
+	if err = user.CheckPassword(req.Password); err != nil {
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials, "Invalid credentials"))
 		return
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"exp":     time.Now().Add(s.Config.Auth.TokenTTL).Unix(),
 	})
 
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := token.SignedString([]byte(s.Config.Auth.JWTSecret))
 	if err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Failed to generate token"})
+		problem.Write(w, r, problem.Internal("Failed to generate token"))
 		return
 	}
 
@@ -151,23 +574,20 @@ func login(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func createProject(w http.ResponseWriter, r *http.Request) {
+func (s *Server) createProject(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var req ProjectRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Invalid request body"})
+	if !readJSON(w, r, &req) {
 		return
 	}
 
-	project := models.Project{
+	project, err := s.Kanban.CreateProject(r.Context(), currentUserID(r), service.NewProject{
 		Title:       req.Title,
 		Description: req.Description,
-		Status:      "active",
-	}
-
-	if err := config.DB.Create(&project).Error; err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Failed to create project"})
+	})
+	if err != nil {
+		writeError(w, r, err, "Failed to create project")
 		return
 	}
 
@@ -177,28 +597,25 @@ func createProject(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func updateProject(w http.ResponseWriter, r *http.Request) {
+func (s *Server) updateProject(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	id := vars["id"]
 
 	var req ProjectRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Invalid request body"})
+	if !readJSON(w, r, &req) {
 		return
 	}
 
-	var project models.Project
-	if err := config.DB.First(&project, id).Error; err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Project not found"})
+	project, ok := s.findProject(w, r)
+	if !ok {
 		return
 	}
 
-	project.Title = req.Title
-	project.Description = req.Description
-
-	if err := config.DB.Save(&project).Error; err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Failed to update project"})
+	updates, err := service.ProjectChanges{Title: &req.Title, Description: &req.Description}.Updates()
+	if err == nil {
+		project, err = s.Kanban.UpdateProject(r.Context(), currentUserID(r), project, updates)
+	}
+	if err != nil {
+		writeError(w, r, err, "Failed to update project")
 		return
 	}
 
@@ -208,13 +625,16 @@ func updateProject(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func deleteProject(w http.ResponseWriter, r *http.Request) {
+func (s *Server) deleteProject(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	id := vars["id"]
 
-	if err := config.DB.Delete(&models.Project{}, id).Error; err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Failed to delete project"})
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.Kanban.DeleteProject(r.Context(), currentUserID(r), project); err != nil {
+		problem.Write(w, r, problem.Internal("Failed to delete project"))
 		return
 	}
 
@@ -223,28 +643,84 @@ func deleteProject(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func getProject(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getProject(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	id := vars["id"]
 
-	var project models.Project
-	if err := config.DB.Preload("Tasks").First(&project, id).Error; err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Project not found"})
+	project, ok := s.findProject(w, r)
+	if !ok {
 		return
 	}
+	tasks, err := s.Tasks.ListTasks(r.Context(), project.ID)
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch tasks"))
+		return
+	}
+	project.Tasks = tasks
 
 	json.NewEncoder(w).Encode(RouteResponse{
 		Data: project,
 	})
 }
 
-func getProjects(w http.ResponseWriter, r *http.Request) {
+// getTask returns a task of the project. Calendar feeds and emails link
+// here.
+func (s *Server) getTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: task,
+	})
+}
+
+// findProject loads the project named by the id route variable, reporting
+// an error to the client if there is none or the current user is not one of
+// its members.
+func (s *Server) findProject(w http.ResponseWriter, r *http.Request) (models.Project, bool) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		problem.Write(w, r, problem.NotFound("Project not found"))
+		return models.Project{}, false
+	}
+	project, err := s.Kanban.FindMemberProject(r.Context(), currentUserID(r), id)
+	if err != nil {
+		writeError(w, r, err, "Failed to load project")
+		return models.Project{}, false
+	}
+	return project, true
+}
+
+// findTask loads the task named by the taskId route variable, which must
+// belong to the project named by id, reporting an error to the client if
+// there is none or the current user is not a member of the project.
+func (s *Server) findTask(w http.ResponseWriter, r *http.Request) (models.Task, bool) {
+	projectID, _ := pathID(r, "id")
+	id, ok := pathID(r, "taskId")
+	if !ok {
+		problem.Write(w, r, problem.NotFound("Task not found"))
+		return models.Task{}, false
+	}
+	task, err := s.Kanban.FindMemberTask(r.Context(), currentUserID(r), id)
+	if err == nil && task.ProjectID != projectID {
+		err = service.ErrTaskNotFound
+	}
+	if err != nil {
+		writeError(w, r, err, "Failed to load task")
+		return models.Task{}, false
+	}
+	return task, true
+}
+
+func (s *Server) getProjects(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var projects []models.Project
-	if err := config.DB.Preload("Tasks").Find(&projects).Error; err != nil {
-		json.NewEncoder(w).Encode(RouteResponse{Error: "Failed to fetch projects"})
+	projects, err := s.Projects.ListProjects(r.Context(), store.ProjectFilter{MemberID: currentUserID(r), WithTasks: true})
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch projects"))
 		return
 	}
 
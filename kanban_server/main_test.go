@@ -2,21 +2,94 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"kanban_server/config"
+	"kanban_server/mailer"
 	"kanban_server/models"
+	"kanban_server/openapi"
+	"kanban_server/problem"
+	"kanban_server/requestid"
+	"kanban_server/store"
+	"kanban_server/store/gormstore"
+	"kanban_server/store/memstore"
+	"kanban_server/telemetry"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm/logger"
 )
 
+const testJWTSecret = "test-secret-that-is-long-enough-for-hs256"
+
+// newTestServer returns a server on a fresh SQLite database.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	db, err := config.Open(config.DriverSQLite, filepath.Join(t.TempDir(), "kanban.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.Logger = logger.Discard
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = testJWTSecret
+	return newServer(cfg, db, gormstore.New(db))
+}
+
+// newTestRouter returns the API routes of srv, validating requests and
+// responses against the OpenAPI document.
+func newTestRouter(t *testing.T, srv *Server) http.Handler {
+	t.Helper()
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+	return srv.newRouter(http.NotFoundHandler(), validator)
+}
+
+// testToken returns a bearer token for userID.
+func testToken(userID uint) string {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testJWTSecret))
+	return token
+}
+
+// serve sends a request with an optional JSON body to handler as the user
+// token belongs to.
+func serve(handler http.Handler, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
 func TestRegister(t *testing.T) {
-	// Initialize test database
-	config.InitDB()
+	srv := newTestServer(t)
 
 	// Create test request
 	reqBody := RegisterRequest{
@@ -33,7 +106,7 @@ func TestRegister(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Call handler
-	register(rr, req)
+	srv.register(rr, req)
 
 	// Check status code
 	if status := rr.Code; status != http.StatusOK {
@@ -47,20 +120,15 @@ func TestRegister(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if response.Error != "" {
-		t.Errorf("handler returned error: %v", response.Error)
-	}
-
 	// Verify user was created in database
 	var user models.User
-	if err := config.DB.Where("email = ?", reqBody.Email).First(&user).Error; err != nil {
+	if err := srv.DB.Where("email = ?", reqBody.Email).First(&user).Error; err != nil {
 		t.Errorf("user was not created in database: %v", err)
 	}
 }
 
 func TestLogin(t *testing.T) {
-	// Initialize test database
-	config.InitDB()
+	srv := newTestServer(t)
 
 	// Create test user
 	user := models.User{
@@ -68,7 +136,7 @@ func TestLogin(t *testing.T) {
 		Password: "password123",
 		Name:     "Test User",
 	}
-	config.DB.Create(&user)
+	srv.DB.Create(&user)
 
 	// Create test request
 	reqBody := LoginRequest{
@@ -84,7 +152,7 @@ func TestLogin(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Call handler
-	login(rr, req)
+	srv.login(rr, req)
 
 	// Check status code
 	if status := rr.Code; status != http.StatusOK {
@@ -98,10 +166,6 @@ func TestLogin(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if response.Error != "" {
-		t.Errorf("handler returned error: %v", response.Error)
-	}
-
 	// Verify token was returned
 	data, ok := response.Data.(map[string]interface{})
 	if !ok {
@@ -113,8 +177,7 @@ func TestLogin(t *testing.T) {
 }
 
 func TestCreateProject(t *testing.T) {
-	// Initialize test database
-	config.InitDB()
+	srv := newTestServer(t)
 
 	// Create test user and get token
 	user := models.User{
@@ -122,13 +185,13 @@ func TestCreateProject(t *testing.T) {
 		Password: "password123",
 		Name:     "Test User",
 	}
-	config.DB.Create(&user)
+	srv.DB.Create(&user)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
 		"exp":     time.Now().Add(time.Hour * 24).Unix(),
 	})
-	tokenString, _ := token.SignedString(jwtKey)
+	tokenString, _ := token.SignedString([]byte(testJWTSecret))
 
 	// Create test request
 	reqBody := ProjectRequest{
@@ -145,7 +208,7 @@ func TestCreateProject(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Call handler
-	createProject(rr, req)
+	srv.authMiddleware(http.HandlerFunc(srv.createProject)).ServeHTTP(rr, req)
 
 	// Check status code
 	if status := rr.Code; status != http.StatusOK {
@@ -159,20 +222,15 @@ func TestCreateProject(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if response.Error != "" {
-		t.Errorf("handler returned error: %v", response.Error)
-	}
-
 	// Verify project was created in database
 	var project models.Project
-	if err := config.DB.Where("title = ?", reqBody.Title).First(&project).Error; err != nil {
+	if err := srv.DB.Where("title = ?", reqBody.Title).First(&project).Error; err != nil {
 		t.Errorf("project was not created in database: %v", err)
 	}
 }
 
 func TestGetProjects(t *testing.T) {
-	// Initialize test database
-	config.InitDB()
+	srv := newTestServer(t)
 
 	// Create test user and get token
 	user := models.User{
@@ -180,32 +238,40 @@ func TestGetProjects(t *testing.T) {
 		Password: "password123",
 		Name:     "Test User",
 	}
-	config.DB.Create(&user)
+	srv.DB.Create(&user)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
 		"exp":     time.Now().Add(time.Hour * 24).Unix(),
 	})
-	tokenString, _ := token.SignedString(jwtKey)
+	tokenString, _ := token.SignedString([]byte(testJWTSecret))
 
-	// Create test projects
+	// Create test projects, and one the user is not a member of
+	other := models.User{Email: "other@example.com", Password: "password123", Name: "Other User"}
+	srv.DB.Create(&other)
 	projects := []models.Project{
 		{Title: "Project 1", Description: "Description 1", Status: "active"},
 		{Title: "Project 2", Description: "Description 2", Status: "active"},
+		{Title: "Project 3", Description: "Description 3", Status: "active"},
 	}
-	for _, p := range projects {
-		config.DB.Create(&p)
+	for i, p := range projects {
+		memberID := user.ID
+		if i == 2 {
+			memberID = other.ID
+		}
+		srv.Projects.CreateProject(context.Background(), &p, memberID)
 	}
 
 	// Create test request
 	req := httptest.NewRequest("GET", "/projects", nil)
 	req.Header.Set("Authorization", tokenString)
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, user.ID))
 
 	// Create response recorder
 	rr := httptest.NewRecorder()
 
 	// Call handler
-	getProjects(rr, req)
+	srv.getProjects(rr, req)
 
 	// Check status code
 	if status := rr.Code; status != http.StatusOK {
@@ -219,10 +285,6 @@ func TestGetProjects(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if response.Error != "" {
-		t.Errorf("handler returned error: %v", response.Error)
-	}
-
 	// Verify projects were returned
 	projectsData, ok := response.Data.([]interface{})
 	if !ok {
@@ -232,3 +294,1348 @@ func TestGetProjects(t *testing.T) {
 		t.Errorf("expected 2 projects, got %d", len(projectsData))
 	}
 }
+
+func TestReadPatch(t *testing.T) {
+	project := models.Project{ID: 1, Title: "Title", Description: "Description", Status: models.ProjectStatusActive}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        map[string]interface{}
+		wantErr     bool
+	}{
+		{
+			name:        "merge patch updates only sent fields",
+			contentType: mergePatchContentType,
+			body:        `{"status":"archived"}`,
+			want:        map[string]interface{}{"status": "archived"},
+		},
+		{
+			name:        "merge patch null clears nullable field",
+			contentType: mergePatchContentType,
+			body:        `{"description":null}`,
+			want:        map[string]interface{}{"description": ""},
+		},
+		{
+			name:        "json patch replace",
+			contentType: jsonPatchContentType,
+			body:        `[{"op":"test","path":"/title","value":"Title"},{"op":"replace","path":"/title","value":"Renamed"}]`,
+			want:        map[string]interface{}{"title": "Renamed"},
+		},
+		{
+			name:        "json patch failed test",
+			contentType: jsonPatchContentType,
+			body:        `[{"op":"test","path":"/title","value":"Other"}]`,
+			wantErr:     true,
+		},
+		{
+			name:        "read-only field",
+			contentType: mergePatchContentType,
+			body:        `{"id":2}`,
+			wantErr:     true,
+		},
+		{
+			name:        "required field removed",
+			contentType: mergePatchContentType,
+			body:        `{"title":null}`,
+			wantErr:     true,
+		},
+		{
+			name:        "invalid enum",
+			contentType: mergePatchContentType,
+			body:        `{"status":"deleted"}`,
+			wantErr:     true,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			body:        `{}`,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", "/projects/1", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			updates, err := readPatch(req, project, projectPatchFields, &projectPatch{})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got updates %v", updates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(updates) != len(tt.want) {
+				t.Fatalf("got updates %v, want %v", updates, tt.want)
+			}
+			for column, value := range tt.want {
+				if updates[column] != value {
+					t.Errorf("column %s: got %v, want %v", column, updates[column], value)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPatchReportsEveryViolation(t *testing.T) {
+	task := models.Task{ID: 1, ProjectID: 1, Title: "Ship", Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium}
+	body := `{"id":7,"title":"  ","priority":"urgent","due_date":"0025-01-01T00:00:00Z","assigned_to":"ada"}`
+	req := httptest.NewRequest("PATCH", "/projects/1/tasks/1", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", mergePatchContentType)
+
+	_, err := readPatch(req, task, taskPatchFields, &taskPatch{})
+	p, ok := err.(*problem.Problem)
+	if !ok {
+		t.Fatalf("got %v, want a problem", err)
+	}
+	var fields []string
+	for _, e := range p.Errors {
+		fields = append(fields, e.Field)
+	}
+	want := []string{"assigned_to", "id", "title", "priority", "due_date"}
+	if p.Status != http.StatusUnprocessableEntity || !reflect.DeepEqual(fields, want) {
+		t.Errorf("got status %d and fields %v, want 422 and %v", p.Status, fields, want)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	srv := newTestServer(t)
+	req := httptest.NewRequest("POST", "/register", bytes.NewBufferString(`{"email":"ada","password":"short","name":" "}`))
+	rr := httptest.NewRecorder()
+	srv.register(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d, want 422: %s", rr.Code, rr.Body)
+	}
+	var p problem.Problem
+	if err := json.NewDecoder(rr.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	want := []problem.FieldError{
+		{Field: "email", In: "body", Message: "must be a valid email address"},
+		{Field: "password", In: "body", Message: "must be at least 8 characters"},
+		{Field: "name", In: "body", Message: "must not be blank"},
+	}
+	if !reflect.DeepEqual(p.Errors, want) {
+		t.Errorf("got errors %+v, want %+v", p.Errors, want)
+	}
+	var count int64
+	srv.DB.Model(&models.User{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d users were created from an invalid request", count)
+	}
+}
+
+func TestParseTaskFilter(t *testing.T) {
+	valid := []string{
+		"status:todo",
+		"status:todo,in_progress priority:high",
+		"assigned_to:3 label:bug,ui archived:false",
+		"due_before:2025-01-31 due_after:2025-01-01 column_id:4",
+	}
+	for _, expr := range valid {
+		if _, err := parseTaskFilter(1, expr); err != nil {
+			t.Errorf("parseTaskFilter(%q) returned error: %v", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"status",
+		"status:blocked",
+		"priority:urgent",
+		"assigned_to:me",
+		"due_before:tomorrow",
+		"owner:3",
+	}
+	for _, expr := range invalid {
+		if _, err := parseTaskFilter(1, expr); err == nil {
+			t.Errorf("parseTaskFilter(%q) expected error", expr)
+		}
+	}
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Failed to load OpenAPI document: %v", err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatalf("Failed to build validator: %v", err)
+	}
+
+	undocumented := map[string]bool{"/graphql": true, "/openapi.json": true}
+	router := newTestServer(t).newRouter(http.NotFoundHandler(), validator)
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || undocumented[path] {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		item := doc.Paths.Value(path)
+		for _, method := range methods {
+			if item == nil || item.GetOperation(method) == nil {
+				t.Errorf("%s %s is missing from the OpenAPI document", method, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetProjectFromMemoryStore(t *testing.T) {
+	repos := memstore.New()
+	srv := newServer(config.Default(), nil, repos)
+	ctx := context.Background()
+
+	user := models.User{Email: "test@example.com", Password: "password123", Name: "Test User"}
+	if err := repos.CreateUser(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	project := models.Project{Title: "Project 1"}
+	if err := repos.CreateProject(ctx, &project, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	other := models.Project{Title: "Project 2"}
+	if err := repos.CreateProject(ctx, &other, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range []models.Task{{ProjectID: project.ID, Title: "Mine"}, {ProjectID: other.ID, Title: "Other"}} {
+		if err := repos.CreateTask(ctx, &task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/projects/"+id, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, user.ID))
+		rr := httptest.NewRecorder()
+		srv.getProject(rr, req)
+		return rr
+	}
+
+	rr := get(strconv.Itoa(int(project.ID)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, body %s", rr.Code, rr.Body)
+	}
+	var response RouteResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := response.Data.(map[string]interface{})
+	tasks, _ := data["tasks"].([]interface{})
+	if data["title"] != "Project 1" || len(tasks) != 1 {
+		t.Errorf("got %v, want Project 1 with one task", response.Data)
+	}
+
+	for _, id := range []string{"999", "abc"} {
+		if rr := get(id); rr.Code != http.StatusNotFound {
+			t.Errorf("GET /projects/%s: got status %d, want 404", id, rr.Code)
+		}
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	srv := newTestServer(t)
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+	handler := requestid.Middleware(srv.newRouter(http.NotFoundHandler(), validator))
+
+	user := models.User{Email: "test@example.com", Password: "password123", Name: "Test User"}
+	if err := srv.Users.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testJWTSecret))
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		token     string
+		body      string
+		status    int
+		code      string
+		wantField string
+	}{
+		{name: "missing token", method: "GET", path: "/projects", status: http.StatusUnauthorized, code: problem.CodeUnauthorized},
+		{name: "invalid token", method: "GET", path: "/projects", token: "nope", status: http.StatusUnauthorized, code: problem.CodeUnauthorized},
+		{name: "wrong password", method: "POST", path: "/login", body: `{"email":"test@example.com","password":"wrong"}`, status: http.StatusUnauthorized, code: problem.CodeInvalidCredentials},
+		{name: "duplicate email", method: "POST", path: "/register", body: `{"email":"test@example.com","password":"password123","name":"Again"}`, status: http.StatusConflict, code: problem.CodeConflict},
+		{name: "missing project", method: "GET", path: "/projects/999", token: token, status: http.StatusNotFound, code: problem.CodeNotFound},
+		{name: "malformed body", method: "POST", path: "/projects", token: token, body: `{"title":`, status: http.StatusBadRequest, code: problem.CodeBadRequest},
+		{name: "invalid body", method: "POST", path: "/projects", token: token, body: `{"description":"x"}`, status: http.StatusUnprocessableEntity, code: problem.CodeValidation, wantField: "title"},
+		{name: "invalid reminder offset", method: "PUT", path: "/me/reminders", token: token, body: `{"offsets_minutes":[0]}`, status: http.StatusUnprocessableEntity, code: problem.CodeValidation, wantField: "offsets_minutes.0"},
+		{name: "blank muted type", method: "PUT", path: "/me/notifications", token: token, body: `{"muted":[" "]}`, status: http.StatusUnprocessableEntity, code: problem.CodeValidation, wantField: "muted.0"},
+		{name: "unknown route", method: "GET", path: "/nowhere", status: http.StatusNotFound, code: problem.CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", rr.Code, tt.status, rr.Body)
+			}
+			if got := rr.Header().Get("Content-Type"); got != problem.ContentType {
+				t.Errorf("got Content-Type %q, want %q", got, problem.ContentType)
+			}
+			var p problem.Problem
+			if err := json.NewDecoder(rr.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Status != tt.status || p.Code != tt.code || p.Instance != tt.path {
+				t.Errorf("got problem %+v, want status %d and code %s for %s", p, tt.status, tt.code, tt.path)
+			}
+			if p.RequestID == "" || p.RequestID != rr.Header().Get(requestid.Header) {
+				t.Errorf("problem request ID %q does not match header %q", p.RequestID, rr.Header().Get(requestid.Header))
+			}
+			if tt.wantField != "" && (len(p.Errors) == 0 || p.Errors[0].Field != tt.wantField) {
+				t.Errorf("got field errors %+v, want one for %s", p.Errors, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	handler := corsMiddleware(config.CORS{AllowedOrigins: []string{"https://board.example.com"}, MaxAge: time.Minute})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}))
+
+	preflight := httptest.NewRequest("OPTIONS", "/projects", nil)
+	preflight.Header.Set("Origin", "https://board.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, preflight)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("preflight got status %d, want 204", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://board.example.com" {
+		t.Errorf("preflight got Access-Control-Allow-Origin %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Max-Age"); got != "60" {
+		t.Errorf("preflight got Access-Control-Max-Age %q, want 60", got)
+	}
+
+	other := httptest.NewRequest("GET", "/projects", nil)
+	other.Header.Set("Origin", "https://evil.example.com")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, other)
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("request from another origin got status %d, want it passed on", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("request from another origin got Access-Control-Allow-Origin %q", got)
+	}
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	srv := newTestServer(t)
+	srv.Config.HTTP.MaxBodyBytes = 64
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := srv.newRouter(http.NotFoundHandler(), validator)
+
+	user := models.User{Email: "test@example.com", Password: "password123", Name: "Test User"}
+	if err := srv.Users.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testJWTSecret))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	description := string(bytes.Repeat([]byte("x"), 100))
+	rr := post("/projects", `{"title":"Big","description":"`+description+`"}`)
+	var p problem.Problem
+	json.NewDecoder(rr.Body).Decode(&p)
+	if rr.Code != http.StatusRequestEntityTooLarge || p.Code != problem.CodeTooLarge {
+		t.Errorf("oversized body got %d %q, want 413 %s", rr.Code, p.Code, problem.CodeTooLarge)
+	}
+
+	if rr := post("/projects", `{"title":"Small"}`); rr.Code != http.StatusOK {
+		t.Errorf("small body got %d: %s", rr.Code, rr.Body)
+	}
+
+	// The Trello import has a limit of its own, so the board is read rather
+	// than refused for its size.
+	if rr := post("/projects/import/trello", `{"name":"Board","lists":[],"cards":[],"desc":"`+description+`"}`); rr.Code == http.StatusRequestEntityTooLarge {
+		t.Errorf("Trello import got 413 under its own limit")
+	}
+}
+
+func TestRequestTracing(t *testing.T) {
+	srv := newTestServer(t)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := telemetry.NewTracerProvider(srv.Config.Tracing, exporter)
+	defer tp.Shutdown(context.Background())
+	srv.Tracing = tp
+	if err := srv.DB.Use(telemetry.NewGORMPlugin(tp)); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := srv.newRouter(http.NotFoundHandler(), validator)
+
+	user := models.User{Email: "test@example.com", Password: "password123", Name: "Test User"}
+	if err := srv.Users.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testJWTSecret))
+
+	for _, path := range []string{"/me/reminders", "/nowhere"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	request, ok := spans["GET /me/reminders"]
+	if !ok {
+		t.Fatalf("no span for the route among %v", spans)
+	}
+	if query, ok := spans["SELECT reminder_preferences"]; !ok || query.Parent.SpanID() != request.SpanContext.SpanID() {
+		t.Errorf("the handler's query is not traced under the request span")
+	}
+	if _, ok := spans["GET"]; !ok {
+		t.Errorf("the unmatched request was not traced")
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	srv := newTestServer(t)
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+	handler := srv.newRouter(http.NotFoundHandler(), validator)
+
+	get := func(path string, v interface{}) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if err := json.NewDecoder(rr.Body).Decode(v); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return rr.Code
+	}
+
+	var health HealthResponse
+	if code := get("/healthz", &health); code != http.StatusOK || health.Status != "ok" {
+		t.Errorf("healthz got %d %q", code, health.Status)
+	}
+	var ready HealthResponse
+	if code := get("/readyz", &ready); code != http.StatusOK {
+		t.Errorf("readyz got %d: %v", code, ready.Checks)
+	}
+	for _, check := range []string{"database", "schema", "workers"} {
+		if ready.Checks[check] != "ok" {
+			t.Errorf("readiness check %s = %q", check, ready.Checks[check])
+		}
+	}
+
+	var version VersionResponse
+	if code := get("/version", &version); code != http.StatusOK || version.SchemaVersion != config.SchemaVersion || version.Commit == "" {
+		t.Errorf("version got %d %+v", code, version)
+	}
+
+	srv.workers.Go("webhooks", func(context.Context) {})
+	srv.workers.Go("digest", func(ctx context.Context) { <-ctx.Done() })
+	defer srv.workers.Stop(context.Background())
+	for deadline := time.Now().Add(time.Second); len(srv.workers.Exited()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	srv.draining.Store(true)
+	ready = HealthResponse{}
+	if code := get("/readyz", &ready); code != http.StatusServiceUnavailable || ready.Status != "unavailable" {
+		t.Errorf("readyz while draining got %d %q", code, ready.Status)
+	}
+	if ready.Checks["shutdown"] != "shutting down" || ready.Checks["workers"] != "stopped: webhooks" {
+		t.Errorf("got checks %v, want the shutdown and the stopped worker reported", ready.Checks)
+	}
+}
+
+// TestProjectRoutesFromMemoryStore runs the project and task routes on a
+// server without a database, so that any lookup not made through the store
+// would fail.
+func TestProjectRoutesFromMemoryStore(t *testing.T) {
+	repos := memstore.New()
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = testJWTSecret
+	srv := newServer(cfg, nil, repos)
+	handler := newTestRouter(t, srv)
+	ctx := context.Background()
+
+	ada := models.User{Email: "ada@example.com", Password: "password123", Name: "Ada"}
+	bob := models.User{Email: "bob@example.com", Password: "password123", Name: "Bob"}
+	for _, user := range []*models.User{&ada, &bob} {
+		if err := repos.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := models.Project{Title: "Launch"}
+	other := models.Project{Title: "Hiring"}
+	for _, p := range []*models.Project{&project, &other} {
+		if err := repos.CreateProject(ctx, p, ada.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	task := models.Task{ProjectID: project.ID, Title: "Ship", Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium}
+	foreign := models.Task{ProjectID: other.ID, Title: "Interview", Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium}
+	for _, task := range []*models.Task{&task, &foreign} {
+		if err := repos.CreateTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token := testToken(ada.ID)
+	projectPath := fmt.Sprintf("/projects/%d", project.ID)
+	taskPath := fmt.Sprintf("%s/tasks/%d", projectPath, task.ID)
+
+	rr := serve(handler, token, "GET", projectPath+"/members", "")
+	var members struct{ Data []models.User }
+	if err := json.NewDecoder(rr.Body).Decode(&members); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("GET members: got status %d, error %v", rr.Code, err)
+	}
+	if len(members.Data) != 1 || members.Data[0].ID != ada.ID {
+		t.Errorf("GET members = %+v, want Ada", members.Data)
+	}
+
+	if rr := serve(handler, token, "PATCH", taskPath, `{"title":"Ship it"}`); rr.Code != http.StatusOK {
+		t.Errorf("PATCH task: got status %d: %s", rr.Code, rr.Body)
+	}
+	if stored, _ := repos.FindTask(ctx, task.ID); stored.Title != "Ship it" {
+		t.Errorf("PATCH task stored title %q", stored.Title)
+	}
+	rr = serve(handler, token, "GET", taskPath, "")
+	var got struct{ Data models.Task }
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil || rr.Code != http.StatusOK || got.Data.ID != task.ID || got.Data.Title != "Ship it" {
+		t.Errorf("GET task: got status %d, task %+v, error %v", rr.Code, got.Data, err)
+	}
+
+	missing := "/projects/999999"
+	misplaced := fmt.Sprintf("%s/tasks/%d", projectPath, foreign.ID)
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", missing + "/members", ""},
+		{"POST", missing + "/members", `{"email":"bob@example.com"}`},
+		{"DELETE", fmt.Sprintf("%s/members/%d", missing, ada.ID), ""},
+		{"POST", missing + "/tasks/bulk", `{"action":"archive","task_ids":[1]}`},
+		{"GET", missing + "/export", ""},
+		{"POST", missing + "/clone", `{}`},
+		{"POST", missing + "/template", `{"name":"Launch"}`},
+		{"POST", missing + "/webhooks", `{"url":"https://example.com/hook"}`},
+		{"POST", missing + "/calendar", ""},
+		{"GET", missing + "/calendar", ""},
+		{"GET", misplaced, ""},
+		{"PATCH", misplaced, `{"title":"Moved"}`},
+		{"GET", misplaced + "/comments", ""},
+		{"POST", misplaced + "/comments", `{"body":"Hello"}`},
+		{"GET", misplaced + "/recurrence", ""},
+		{"PUT", misplaced + "/recurrence", `{"rule":"FREQ=DAILY"}`},
+		{"DELETE", misplaced + "/recurrence", ""},
+	}
+	for _, tt := range tests {
+		if rr := serve(handler, token, tt.method, tt.path, tt.body); rr.Code != http.StatusNotFound {
+			t.Errorf("%s %s: got status %d, want 404: %s", tt.method, tt.path, rr.Code, rr.Body)
+		}
+	}
+}
+
+var errDatabaseDown = errors.New("database is down")
+
+// failingStore fails every lookup, as a store does while its database is
+// unreachable.
+type failingStore struct{ store.Store }
+
+func (failingStore) FindProject(context.Context, uint) (models.Project, error) {
+	return models.Project{}, errDatabaseDown
+}
+
+func (failingStore) FindTask(context.Context, uint) (models.Task, error) {
+	return models.Task{}, errDatabaseDown
+}
+
+func (failingStore) IsMember(context.Context, uint, uint) (bool, error) {
+	return false, errDatabaseDown
+}
+
+func TestDatabaseErrorsAreNotReportedAsNotFound(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = testJWTSecret
+	srv := newServer(cfg, nil, failingStore{memstore.New()})
+	handler := newTestRouter(t, srv)
+	token := testToken(1)
+
+	for _, path := range []string{"/projects/1", "/projects/1/members", "/projects/1/export", "/projects/1/tasks/2/comments"} {
+		rr := serve(handler, token, "GET", path, "")
+		var p problem.Problem
+		json.NewDecoder(rr.Body).Decode(&p)
+		if rr.Code != http.StatusInternalServerError || p.Code != problem.CodeInternal {
+			t.Errorf("GET %s with the store down: got status %d, code %q, want 500", path, rr.Code, p.Code)
+		}
+	}
+
+	srv = newTestServer(t)
+	handler = newTestRouter(t, srv)
+	if rr := serve(handler, token, "DELETE", "/templates/999", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("DELETE a missing template: got status %d, want 404", rr.Code)
+	}
+	sqlDB, err := srv.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	for _, route := range []struct{ method, path string }{
+		{"GET", "/templates/1"},
+		{"DELETE", "/templates/1"},
+		{"POST", "/notifications/1/read"},
+	} {
+		if rr := serve(handler, token, route.method, route.path, ""); rr.Code != http.StatusInternalServerError {
+			t.Errorf("%s %s with the database down: got status %d, want 500", route.method, route.path, rr.Code)
+		}
+	}
+}
+
+func TestRoutesRequireMembership(t *testing.T) {
+	repos := memstore.New()
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = testJWTSecret
+	handler := newTestRouter(t, newServer(cfg, nil, repos))
+	ctx := context.Background()
+
+	owner := models.User{Email: "owner@example.com", Password: "password123", Name: "Owner"}
+	outsider := models.User{Email: "outsider@example.com", Password: "password123", Name: "Outsider"}
+	for _, user := range []*models.User{&owner, &outsider} {
+		if err := repos.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := models.Project{Title: "Launch"}
+	if err := repos.CreateProject(ctx, &project, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	task := models.Task{ProjectID: project.ID, Title: "Ship", Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium}
+	if err := repos.CreateTask(ctx, &task); err != nil {
+		t.Fatal(err)
+	}
+	token := testToken(outsider.ID)
+	projectPath := fmt.Sprintf("/projects/%d", project.ID)
+	taskPath := fmt.Sprintf("%s/tasks/%d", projectPath, task.ID)
+
+	rr := serve(handler, token, "GET", "/projects", "")
+	var list struct{ Data []models.Project }
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("GET /projects: got status %d, error %v", rr.Code, err)
+	}
+	if len(list.Data) != 0 {
+		t.Errorf("GET /projects listed %d projects of which the user is not a member", len(list.Data))
+	}
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", projectPath, ""},
+		{"PUT", projectPath, `{"title":"Mine now"}`},
+		{"PATCH", projectPath, `{"title":"Mine now"}`},
+		{"DELETE", projectPath, ""},
+		{"GET", projectPath + "/members", ""},
+		{"POST", projectPath + "/members", `{"email":"outsider@example.com"}`},
+		{"GET", taskPath, ""},
+		{"PATCH", taskPath, `{"title":"Mine now"}`},
+		{"GET", taskPath + "/comments", ""},
+	}
+	for _, tt := range tests {
+		if rr := serve(handler, token, tt.method, tt.path, tt.body); rr.Code != http.StatusNotFound {
+			t.Errorf("%s %s as a non-member: got status %d, want 404", tt.method, tt.path, rr.Code)
+		}
+	}
+	if stored, _ := repos.FindProject(ctx, project.ID); stored.Title != "Launch" {
+		t.Errorf("a non-member renamed the project to %q", stored.Title)
+	}
+}
+
+func TestWebhookRoutesRequireMembership(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	ctx := context.Background()
+
+	owner := models.User{Email: "owner@example.com", Password: "password123", Name: "Owner"}
+	outsider := models.User{Email: "outsider@example.com", Password: "password123", Name: "Outsider"}
+	for _, user := range []*models.User{&owner, &outsider} {
+		if err := srv.Users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := models.Project{Title: "Launch"}
+	if err := srv.Projects.CreateProject(ctx, &project, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	webhooksPath := fmt.Sprintf("/projects/%d/webhooks", project.ID)
+
+	for _, url := range []string{"http://127.0.0.1:6379/", "http://169.254.169.254/latest/meta-data/", "http://10.0.0.5/", "http://[::1]/", "http://localhost:5000/"} {
+		body := fmt.Sprintf(`{"url":%q}`, url)
+		if rr := serve(handler, testToken(owner.ID), "POST", webhooksPath, body); rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("POST webhook for %s: got status %d, want 422", url, rr.Code)
+		}
+	}
+
+	rr := serve(handler, testToken(owner.ID), "POST", webhooksPath, `{"url":"https://example.com/hook"}`)
+	var created struct{ Data WebhookResponse }
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("POST webhooks as the owner: got status %d, error %v", rr.Code, err)
+	}
+	delivery := models.WebhookDelivery{SubscriptionID: created.Data.ID, EventType: "task.created", Payload: "{}", Status: models.DeliveryFailed}
+	if err := srv.DB.Create(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+	webhookPath := fmt.Sprintf("%s/%d", webhooksPath, created.Data.ID)
+	deliveryPath := fmt.Sprintf("%s/deliveries/%d", webhookPath, delivery.ID)
+
+	token := testToken(outsider.ID)
+	tests := []struct {
+		method, path, body string
+	}{
+		{"POST", webhooksPath, `{"url":"https://attacker.example.com/hook"}`},
+		{"GET", webhooksPath, ""},
+		{"GET", webhookPath, ""},
+		{"PUT", webhookPath, `{"url":"https://attacker.example.com/hook"}`},
+		{"DELETE", webhookPath, ""},
+		{"GET", webhookPath + "/deliveries", ""},
+		{"GET", deliveryPath, ""},
+		{"POST", deliveryPath + "/redeliver", ""},
+	}
+	for _, tt := range tests {
+		if rr := serve(handler, token, tt.method, tt.path, tt.body); rr.Code != http.StatusNotFound {
+			t.Errorf("%s %s as a non-member: got status %d, want 404", tt.method, tt.path, rr.Code)
+		}
+	}
+
+	var subs []models.WebhookSubscription
+	srv.DB.Find(&subs)
+	if len(subs) != 1 || subs[0].URL != "https://example.com/hook" {
+		t.Errorf("a non-member changed the webhooks: %+v", subs)
+	}
+	if rr := serve(handler, testToken(owner.ID), "GET", webhookPath, ""); rr.Code != http.StatusOK {
+		t.Errorf("GET webhook as the owner: got status %d", rr.Code)
+	}
+}
+
+func TestCalendarFeedRequiresMembership(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	ctx := context.Background()
+
+	owner := models.User{Email: "owner@example.com", Password: "password123", Name: "Owner"}
+	member := models.User{Email: "member@example.com", Password: "password123", Name: "Member"}
+	outsider := models.User{Email: "outsider@example.com", Password: "password123", Name: "Outsider"}
+	for _, user := range []*models.User{&owner, &member, &outsider} {
+		if err := srv.Users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := models.Project{Title: "Launch"}
+	if err := srv.Projects.CreateProject(ctx, &project, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Projects.AddMember(ctx, project.ID, member.ID); err != nil {
+		t.Fatal(err)
+	}
+	task := models.Task{ProjectID: project.ID, Title: "Secret launch date", Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium, DueDate: time.Now().Add(24 * time.Hour), AssignedTo: member.ID}
+	if err := srv.Tasks.CreateTask(ctx, &task); err != nil {
+		t.Fatal(err)
+	}
+	calendarPath := fmt.Sprintf("/projects/%d/calendar", project.ID)
+
+	if rr := serve(handler, testToken(outsider.ID), "POST", calendarPath, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("POST calendar as a non-member: got status %d, want 404", rr.Code)
+	}
+
+	feedPaths := map[string]string{}
+	for _, path := range []string{calendarPath, "/me/calendar"} {
+		rr := serve(handler, testToken(member.ID), "POST", path, "")
+		var created struct{ Data CalendarFeedResponse }
+		if err := json.NewDecoder(rr.Body).Decode(&created); err != nil || rr.Code != http.StatusOK {
+			t.Fatalf("POST %s as a member: got status %d, error %v", path, rr.Code, err)
+		}
+		feedPaths[path] = strings.TrimPrefix(created.Data.URL, srv.Config.HTTP.PublicURL)
+	}
+	for _, feedPath := range feedPaths {
+		rr := serve(handler, "", "GET", feedPath, "")
+		if rr.Code != http.StatusOK || !bytes.Contains(rr.Body.Bytes(), []byte(task.Title)) {
+			t.Fatalf("GET %s as a member: got status %d without the task", feedPath, rr.Code)
+		}
+	}
+
+	removePath := fmt.Sprintf("/projects/%d/members/%d", project.ID, member.ID)
+	if rr := serve(handler, testToken(owner.ID), "DELETE", removePath, ""); rr.Code != http.StatusOK {
+		t.Fatalf("DELETE member: got status %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(handler, "", "GET", feedPaths[calendarPath], ""); rr.Code != http.StatusNotFound {
+		t.Errorf("GET project feed after removal: got status %d, want 404", rr.Code)
+	}
+	rr := serve(handler, "", "GET", feedPaths["/me/calendar"], "")
+	if rr.Code != http.StatusOK || bytes.Contains(rr.Body.Bytes(), []byte(task.Title)) {
+		t.Errorf("GET personal feed after removal: got status %d, want 200 without the project's task", rr.Code)
+	}
+}
+
+func TestExportRequiresMembership(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	ctx := context.Background()
+
+	owner := models.User{Email: "owner@example.com", Password: "password123", Name: "Owner"}
+	outsider := models.User{Email: "outsider@example.com", Password: "password123", Name: "Outsider"}
+	for _, user := range []*models.User{&owner, &outsider} {
+		if err := srv.Users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := models.Project{Title: "Launch"}
+	if err := srv.Projects.CreateProject(ctx, &project, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Assigned to a non-member, whose email the export looks up separately.
+	task := models.Task{ProjectID: project.ID, Title: "Ship", Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium, AssignedTo: outsider.ID}
+	if err := srv.Tasks.CreateTask(ctx, &task); err != nil {
+		t.Fatal(err)
+	}
+	exportPath := fmt.Sprintf("/projects/%d/export", project.ID)
+
+	for _, path := range []string{exportPath, exportPath + "?format=csv"} {
+		if rr := serve(handler, testToken(outsider.ID), "GET", path, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("GET %s as a non-member: got status %d, want 404", path, rr.Code)
+		}
+	}
+
+	rr := serve(handler, testToken(owner.ID), "GET", exportPath+"?format=csv", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Ship") || !strings.Contains(rr.Body.String(), outsider.Email) {
+		t.Errorf("GET CSV export as the owner: got status %d, body %q", rr.Code, rr.Body)
+	}
+}
+
+func TestIdempotencyKeyStoredAfterClientDisconnects(t *testing.T) {
+	srv := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), userIDKey, uint(1)))
+	defer cancel()
+	handler := srv.idempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.Write([]byte(`{"message":"done"}`))
+	}))
+
+	req := httptest.NewRequest("POST", "/projects", bytes.NewBufferString(`{"title":"Launch"}`)).WithContext(ctx)
+	req.Header.Set("Idempotency-Key", "disconnect")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record models.IdempotencyKey
+	if err := srv.DB.Where("idempotency_key = ?", "disconnect").First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !record.Completed || string(record.Body) != `{"message":"done"}` {
+		t.Errorf("key left as %+v after the request was cancelled", record)
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	ctx := context.Background()
+
+	user := models.User{Email: "test@example.com", Password: "password123", Name: "Test User"}
+	if err := srv.Users.CreateUser(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	token := testToken(user.ID)
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/projects", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		req.Header.Set("Idempotency-Key", key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	countProjects := func() int64 {
+		var n int64
+		srv.DB.Model(&models.Project{}).Count(&n)
+		return n
+	}
+
+	first := post("launch", `{"title":"Launch"}`)
+	if first.Code != http.StatusOK {
+		t.Fatalf("first request: got status %d: %s", first.Code, first.Body)
+	}
+	replay := post("launch", `{"title":"Launch"}`)
+	if replay.Code != http.StatusOK || replay.Header().Get("Idempotent-Replayed") != "true" || replay.Body.String() != first.Body.String() {
+		t.Errorf("retry: got status %d, replayed %q, body %s", replay.Code, replay.Header().Get("Idempotent-Replayed"), replay.Body)
+	}
+	if n := countProjects(); n != 1 {
+		t.Errorf("retry created another project, have %d", n)
+	}
+
+	var p problem.Problem
+	reused := post("launch", `{"title":"Relaunch"}`)
+	json.NewDecoder(reused.Body).Decode(&p)
+	if reused.Code != http.StatusUnprocessableEntity || p.Code != problem.CodeIdempotencyKeyReused {
+		t.Errorf("key reused with another body: got status %d, code %q, want 422", reused.Code, p.Code)
+	}
+
+	body := `{"title":"Pending"}`
+	pending := models.IdempotencyKey{
+		UserID:      user.ID,
+		Key:         "pending",
+		RequestHash: requestHash(httptest.NewRequest("POST", "/projects", nil), []byte(body)),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	if err := srv.DB.Create(&pending).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rr := post("pending", body); rr.Code != http.StatusConflict {
+		t.Errorf("key still in flight: got status %d, want 409", rr.Code)
+	}
+
+	srv.DB.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", "launch").Update("expires_at", time.Now().Add(-time.Minute))
+	if rr := post("launch", `{"title":"Relaunch"}`); rr.Code != http.StatusOK || rr.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expired key: got status %d, replayed %q, want a new response", rr.Code, rr.Header().Get("Idempotent-Replayed"))
+	}
+	if n := countProjects(); n != 2 {
+		t.Errorf("have %d projects after the key expired, want 2", n)
+	}
+
+	srv.DB.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", "pending").Update("expires_at", time.Now().Add(-time.Minute))
+	if err := srv.deleteExpiredIdempotencyKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	srv.DB.Model(&models.IdempotencyKey{}).Pluck("idempotency_key", &keys)
+	if !reflect.DeepEqual(keys, []string{"launch"}) {
+		t.Errorf("keys left after purging = %v, want only the live one", keys)
+	}
+}
+
+func TestUpdateReminderPreferencesKeepsMissingFields(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+
+	user := models.User{Email: "user@example.com", Password: "password123", Name: "User"}
+	if err := srv.Users.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	token := testToken(user.ID)
+
+	update := func(body string) ReminderPreferenceResponse {
+		t.Helper()
+		rr := serve(handler, token, "PUT", "/me/reminders", body)
+		var resp struct {
+			Data ReminderPreferenceResponse `json:"data"`
+		}
+		if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &resp) != nil {
+			t.Fatalf("PUT /me/reminders %s: got status %d, body %q", body, rr.Code, rr.Body)
+		}
+		return resp.Data
+	}
+
+	got := update(`{"offsets_minutes":[30]}`)
+	if !got.Enabled || !got.Overdue || !reflect.DeepEqual(got.OffsetsMinutes, []int{30}) {
+		t.Errorf("after setting offsets: %+v", got)
+	}
+	got = update(`{"enabled":false}`)
+	if got.Enabled || !got.Overdue || !reflect.DeepEqual(got.OffsetsMinutes, []int{30}) {
+		t.Errorf("after disabling: %+v", got)
+	}
+	got = update(`{"overdue":false}`)
+	if got.Enabled || got.Overdue || !reflect.DeepEqual(got.OffsetsMinutes, []int{30}) {
+		t.Errorf("after turning off overdue reminders: %+v", got)
+	}
+}
+
+func TestUpdateEmailPreferencesKeepsMissingFields(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+
+	user := models.User{Email: "user@example.com", Password: "password123", Name: "User"}
+	if err := srv.Users.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	token := testToken(user.ID)
+
+	update := func(body string) models.EmailPreference {
+		t.Helper()
+		rr := serve(handler, token, "PUT", "/me/email", body)
+		var resp struct {
+			Data models.EmailPreference `json:"data"`
+		}
+		if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &resp) != nil {
+			t.Fatalf("PUT /me/email %s: got status %d, body %q", body, rr.Code, rr.Body)
+		}
+		return resp.Data
+	}
+
+	if got := update(`{"digest":false}`); !got.Assignments || !got.Mentions || got.Digest {
+		t.Errorf("after turning off the digest: %+v", got)
+	}
+	if got := update(`{"mentions":false}`); !got.Assignments || got.Mentions || got.Digest {
+		t.Errorf("after turning off mentions: %+v", got)
+	}
+	if got := update(`{}`); !got.Assignments || got.Mentions || got.Digest {
+		t.Errorf("after an empty update: %+v", got)
+	}
+	if pref, _ := mailer.Preference(srv.DB, user.ID); !pref.Assignments || pref.Mentions || pref.Digest {
+		t.Errorf("stored preferences: %+v", pref)
+	}
+}
+
+func TestUnsubscribeRequiresPost(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+
+	user := models.User{Email: "user@example.com", Password: "password123", Name: "User"}
+	if err := srv.Users.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	pref, err := mailer.Preference(srv.DB, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/unsubscribe?" + url.Values{"token": {pref.UnsubscribeToken}, "type": {"mentions"}}.Encode()
+
+	// Link scanners only GET, which must leave the preferences alone.
+	rr := serve(handler, "", "GET", path, "")
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") || !strings.Contains(rr.Body.String(), `<form method="post">`) {
+		t.Errorf("GET %s: got status %d, body %q", path, rr.Code, rr.Body)
+	}
+	if pref, _ = mailer.Preference(srv.DB, user.ID); !pref.Mentions {
+		t.Error("GET unsubscribed the user")
+	}
+	if rr := serve(handler, "", "GET", "/unsubscribe?token=unknown", ""); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("GET with an unknown token: got status %d, want 422", rr.Code)
+	}
+
+	rr = serve(handler, "", "POST", path, "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "You have been unsubscribed") {
+		t.Errorf("POST %s: got status %d, body %q", path, rr.Code, rr.Body)
+	}
+	if pref, _ = mailer.Preference(srv.DB, user.ID); pref.Mentions || !pref.Assignments || !pref.Digest {
+		t.Errorf("after POST: %+v, want only mentions turned off", pref)
+	}
+
+	req := httptest.NewRequest("POST", path, nil)
+	req.Header.Set("Accept", "text/html")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "You have been unsubscribed from Kanban mentions emails") {
+		t.Errorf("POST from a browser: got status %d, body %q", rr.Code, rr.Body)
+	}
+}
+
+// newBulkTestProject creates a user and a project with three medium priority
+// tasks, the second of which cannot be updated.
+func newBulkTestProject(t *testing.T, srv *Server) (token string, project models.Project, tasks []models.Task) {
+	t.Helper()
+	ctx := context.Background()
+
+	user := models.User{Email: "owner@example.com", Password: "password123", Name: "Owner"}
+	if err := srv.Users.CreateUser(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	project = models.Project{Title: "Launch"}
+	if err := srv.Projects.CreateProject(ctx, &project, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"Write", "Review", "Ship"} {
+		task := models.Task{ProjectID: project.ID, Title: title, Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium}
+		if err := srv.Tasks.CreateTask(ctx, &task); err != nil {
+			t.Fatal(err)
+		}
+		tasks = append(tasks, task)
+	}
+	trigger := fmt.Sprintf(`CREATE TRIGGER locked_task BEFORE UPDATE ON tasks WHEN NEW.id = %d
+		BEGIN SELECT RAISE(ABORT, 'task is locked'); END`, tasks[1].ID)
+	if err := srv.DB.Exec(trigger).Error; err != nil {
+		t.Fatal(err)
+	}
+	return testToken(user.ID), project, tasks
+}
+
+func TestBulkTasksAtomicRollsBack(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	token, project, tasks := newBulkTestProject(t, srv)
+
+	body := fmt.Sprintf(`{"action":"reprioritize","priority":"high","task_ids":[%d,%d,%d]}`, tasks[0].ID, tasks[1].ID, tasks[2].ID)
+	rr := serve(handler, token, "POST", fmt.Sprintf("/projects/%d/tasks/bulk", project.ID), body)
+	var resp struct {
+		Result BulkTaskResult `json:"result"`
+	}
+	if rr.Code != http.StatusUnprocessableEntity || json.Unmarshal(rr.Body.Bytes(), &resp) != nil {
+		t.Fatalf("got status %d, body %q, want 422", rr.Code, rr.Body)
+	}
+	if len(resp.Result.Results) != 1 || resp.Result.Results[0].TaskID != tasks[1].ID || resp.Result.Results[0].OK {
+		t.Errorf("results = %+v, want only the locked task failed", resp.Result.Results)
+	}
+
+	// The first task was updated before the failure, and rolled back.
+	for _, task := range tasks {
+		got, err := srv.Tasks.FindTask(context.Background(), task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Priority != models.TaskPriorityMedium {
+			t.Errorf("task %q priority = %s after a failed atomic batch, want medium", got.Title, got.Priority)
+		}
+	}
+}
+
+func TestBulkTasksAllowPartial(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	token, project, tasks := newBulkTestProject(t, srv)
+
+	body := fmt.Sprintf(`{"action":"reprioritize","priority":"high","allow_partial":true,"task_ids":[%d,%d,%d,999]}`, tasks[0].ID, tasks[1].ID, tasks[2].ID)
+	rr := serve(handler, token, "POST", fmt.Sprintf("/projects/%d/tasks/bulk", project.ID), body)
+	var resp struct {
+		Data BulkTaskResult `json:"data"`
+	}
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &resp) != nil {
+		t.Fatalf("got status %d, body %q, want 200", rr.Code, rr.Body)
+	}
+
+	ok := make(map[uint]bool)
+	for _, item := range resp.Data.Results {
+		ok[item.TaskID] = item.OK
+	}
+	want := map[uint]bool{tasks[0].ID: true, tasks[1].ID: false, tasks[2].ID: true, 999: false}
+	if resp.Data.Atomic || resp.Data.Succeeded != 2 || resp.Data.Failed != 2 || !reflect.DeepEqual(ok, want) {
+		t.Errorf("result = %+v, want the locked and unknown tasks failed", resp.Data)
+	}
+
+	for i, task := range tasks {
+		got, err := srv.Tasks.FindTask(context.Background(), task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := models.TaskPriorityHigh
+		if i == 1 {
+			want = models.TaskPriorityMedium
+		}
+		if got.Priority != want {
+			t.Errorf("task %q priority = %s, want %s", got.Title, got.Priority, want)
+		}
+	}
+}
+
+func TestBulkTaskLabelFilterIsScopedToProject(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	token, project, tasks := newBulkTestProject(t, srv)
+
+	other := models.Project{Title: "Other"}
+	if err := srv.DB.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	foreign := models.Label{ProjectID: other.ID, Name: "bug"}
+	local := models.Label{ProjectID: project.ID, Name: "bug"}
+	for _, label := range []*models.Label{&foreign, &local} {
+		if err := srv.DB.Create(label).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := srv.DB.Model(&tasks[0]).Association("Labels").Append(&foreign); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.DB.Model(&tasks[2]).Association("Labels").Append(&local); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := serve(handler, token, "POST", fmt.Sprintf("/projects/%d/tasks/bulk", project.ID), `{"action":"archive","filter":"label:bug"}`)
+	var resp struct {
+		Data BulkTaskResult `json:"data"`
+	}
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &resp) != nil {
+		t.Fatalf("got status %d, body %q, want 200", rr.Code, rr.Body)
+	}
+	if len(resp.Data.Results) != 1 || resp.Data.Results[0].TaskID != tasks[2].ID {
+		t.Errorf("results = %+v, want only the task with the project's own label", resp.Data.Results)
+	}
+}
+
+func TestBulkTasksAtomicRelabelLeavesNoLabels(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	token, project, tasks := newBulkTestProject(t, srv)
+
+	trigger := fmt.Sprintf(`CREATE TRIGGER locked_labels BEFORE INSERT ON task_labels WHEN NEW.task_id = %d
+		BEGIN SELECT RAISE(ABORT, 'task is locked'); END`, tasks[1].ID)
+	if err := srv.DB.Exec(trigger).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := fmt.Sprintf(`{"action":"relabel","add_labels":["urgent"],"task_ids":[%d,%d,%d]}`, tasks[0].ID, tasks[1].ID, tasks[2].ID)
+	rr := serve(handler, token, "POST", fmt.Sprintf("/projects/%d/tasks/bulk", project.ID), body)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d, body %q, want 422", rr.Code, rr.Body)
+	}
+	var labels int64
+	if err := srv.DB.Model(&models.Label{}).Where("project_id = ?", project.ID).Count(&labels).Error; err != nil {
+		t.Fatal(err)
+	}
+	if labels != 0 {
+		t.Errorf("found %d labels after a failed atomic batch, want 0", labels)
+	}
+}
+
+func TestBulkTasksAssignRequiresMember(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	token, project, tasks := newBulkTestProject(t, srv)
+
+	outsider := models.User{Email: "outsider@example.com", Password: "password123", Name: "Outsider"}
+	if err := srv.Users.CreateUser(context.Background(), &outsider); err != nil {
+		t.Fatal(err)
+	}
+	body := fmt.Sprintf(`{"action":"assign","assigned_to":%d,"task_ids":[%d]}`, outsider.ID, tasks[0].ID)
+	rr := serve(handler, token, "POST", fmt.Sprintf("/projects/%d/tasks/bulk", project.ID), body)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("assigning a non-member: got status %d, body %q, want 422", rr.Code, rr.Body)
+	}
+}
+
+func TestBulkTasksDeleteRemovesComments(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	token, project, tasks := newBulkTestProject(t, srv)
+
+	comment := models.Comment{TaskID: tasks[0].ID, UserID: 1, Body: "Done?"}
+	if err := srv.DB.Omit("User").Create(&comment).Error; err != nil {
+		t.Fatal(err)
+	}
+	body := fmt.Sprintf(`{"action":"delete","task_ids":[%d]}`, tasks[0].ID)
+	rr := serve(handler, token, "POST", fmt.Sprintf("/projects/%d/tasks/bulk", project.ID), body)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, body %q, want 200", rr.Code, rr.Body)
+	}
+	var comments int64
+	if err := srv.DB.Model(&models.Comment{}).Where("task_id = ?", tasks[0].ID).Count(&comments).Error; err != nil {
+		t.Fatal(err)
+	}
+	if comments != 0 {
+		t.Errorf("found %d comments of the deleted task, want 0", comments)
+	}
+}
+
+func TestSetTaskRecurrenceAgain(t *testing.T) {
+	srv := newTestServer(t)
+	handler := newTestRouter(t, srv)
+	ctx := context.Background()
+
+	user := models.User{Email: "owner@example.com", Password: "password123", Name: "Owner"}
+	if err := srv.Users.CreateUser(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	project := models.Project{Title: "Chores"}
+	if err := srv.Projects.CreateProject(ctx, &project, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	task := models.Task{ProjectID: project.ID, Title: "Water plants", Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium, DueDate: start}
+	if err := srv.Tasks.CreateTask(ctx, &task); err != nil {
+		t.Fatal(err)
+	}
+	token := testToken(user.ID)
+	path := fmt.Sprintf("/projects/%d/tasks/%d/recurrence", project.ID, task.ID)
+
+	set := func(body string) models.Recurrence {
+		t.Helper()
+		rr := serve(handler, token, "PUT", path, body)
+		var resp struct {
+			Data RecurrenceResponse `json:"data"`
+		}
+		if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &resp) != nil {
+			t.Fatalf("PUT %s %s: got status %d, body %q", path, body, rr.Code, rr.Body)
+		}
+		return resp.Data.Recurrence
+	}
+
+	first := set(`{"rule":"FREQ=DAILY"}`)
+	if again := set(`{"rule":"FREQ=WEEKLY"}`); again.ID != first.ID || again.Rule != "FREQ=WEEKLY" {
+		t.Errorf("re-setting the only occurrence: got %+v, want series %d restarted", again, first.ID)
+	}
+
+	// The scheduler created the next occurrence; moving the first task onto
+	// its date must not clash with it.
+	next := start.AddDate(0, 0, 7)
+	occurrence := models.Task{ProjectID: project.ID, Title: "Water plants", Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium,
+		DueDate: next, RecurrenceID: &first.ID, OccurrenceAt: &next}
+	if err := srv.DB.Create(&occurrence).Error; err != nil {
+		t.Fatal(err)
+	}
+	moved := set(`{"rule":"FREQ=DAILY","start":"` + next.Format(time.RFC3339) + `"}`)
+	if moved.ID == first.ID || !moved.Start.Equal(next) {
+		t.Errorf("re-setting a shared series: got %+v, want a new series from %s", moved, next)
+	}
+	var old models.Recurrence
+	if err := srv.DB.First(&old, first.ID).Error; err != nil || !old.Ended {
+		t.Errorf("previous series = %+v, %v, want it ended", old, err)
+	}
+}
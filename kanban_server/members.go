@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"kanban_server/models"
+	"kanban_server/problem"
+	"kanban_server/service"
+)
+
+// MemberRequest names the user to add by either user_id or email.
+type MemberRequest struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email" validate:"omitempty,max=254"`
+}
+
+func (s *Server) getProjectMembers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+	users, err := s.Projects.ListMembers(r.Context(), project.ID)
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch members"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: users,
+	})
+}
+
+// addProjectMember adds a user, given by ID or email, to the project.
+func (s *Server) addProjectMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req MemberRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if (req.UserID == 0) == (req.Email == "") {
+		problem.Write(w, r, problem.Invalid("Provide either user_id or email"))
+		return
+	}
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	var err error
+	field := "user_id"
+	if req.Email != "" {
+		field = "email"
+		user, err = s.Kanban.FindUserByEmail(r.Context(), req.Email)
+	} else {
+		user, err = s.Kanban.FindUser(r.Context(), req.UserID)
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		problem.Write(w, r, problem.Field(field, "User not found"))
+		return
+	}
+	if err == nil {
+		err = s.Kanban.AddMember(r.Context(), currentUserID(r), project, user)
+	}
+	if err != nil {
+		writeError(w, r, err, "Failed to add member")
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Member added successfully",
+		Data:    user,
+	})
+}
+
+func (s *Server) removeProjectMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := pathID(r, "userId")
+	if _, err := s.Kanban.RemoveMember(r.Context(), currentUserID(r), project, userID); err != nil {
+		writeError(w, r, err, "Failed to remove member")
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Member removed successfully",
+	})
+}
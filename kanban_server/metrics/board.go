@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"kanban_server/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// scrapeTimeout bounds the queries run for one scrape.
+const scrapeTimeout = 5 * time.Second
+
+// boardCollector counts records in the database on every scrape, which
+// keeps the counts right whichever API or worker changed them.
+type boardCollector struct {
+	db           *gorm.DB
+	tasks        *prometheus.Desc
+	projects     *prometheus.Desc
+	users        *prometheus.Desc
+	webhookQueue *prometheus.Desc
+}
+
+// NewBoardCollector returns a collector of tasks and projects by status,
+// users, and the webhook deliveries waiting to be sent.
+func NewBoardCollector(db *gorm.DB) prometheus.Collector {
+	return &boardCollector{
+		db: db,
+		tasks: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "tasks"),
+			"Tasks by status.", []string{"status"}, nil),
+		projects: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "projects"),
+			"Projects by status.", []string{"status"}, nil),
+		users: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "users"),
+			"Registered users.", nil, nil),
+		webhookQueue: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "webhook_queue_depth"),
+			"Webhook deliveries waiting to be sent or retried.", nil, nil),
+	}
+}
+
+func (c *boardCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.tasks
+	ch <- c.projects
+	ch <- c.users
+	ch <- c.webhookQueue
+}
+
+func (c *boardCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	db := c.db.WithContext(ctx)
+
+	collectByStatus(ch, c.tasks, db.Model(&models.Task{}), models.TaskStatuses)
+	collectByStatus(ch, c.projects, db.Model(&models.Project{}), models.ProjectStatuses)
+
+	var users int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(c.users, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(users))
+	}
+
+	var queued int64
+	if err := db.Model(&models.WebhookDelivery{}).Where("status = ?", models.DeliveryPending).Count(&queued).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(c.webhookQueue, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.webhookQueue, prometheus.GaugeValue, float64(queued))
+	}
+}
+
+// collectByStatus reports the rows of query grouped by status. Every known
+// status is reported, with 0 if it has no rows, so that series do not
+// vanish.
+func collectByStatus(ch chan<- prometheus.Metric, desc *prometheus.Desc, query *gorm.DB, statuses []string) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := query.Select("status, count(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(desc, err)
+		return
+	}
+	counts := make(map[string]int64, len(statuses))
+	for _, status := range statuses {
+		counts[status] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] += row.Count
+	}
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(n), status)
+	}
+}
@@ -0,0 +1,157 @@
+// Package metrics exposes the server's Prometheus metrics: HTTP traffic by
+// route and status, the database pool, open websockets, the webhook queue and
+// counts of the records on the boards.
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the server's own metrics.
+const Namespace = "kanban"
+
+// Unmatched is the route label of requests no route matched.
+const Unmatched = "unmatched"
+
+// Metrics holds the registry served on /metrics and the HTTP metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+// New returns the HTTP metrics, registered with a fresh registry along with
+// the Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being served.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.inFlight,
+	)
+	return m
+}
+
+// Register adds collectors, such as those of the database pool or the
+// board, to the registry.
+func (m *Metrics) Register(cs ...prometheus.Collector) {
+	m.registry.MustRegister(cs...)
+}
+
+// Handler serves the metrics in the Prometheus exposition format. A
+// collector that fails leaves out its metrics rather than the whole scrape.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		Registry:      m.registry,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// Middleware counts and times requests. Requests are labelled with the
+// template of the route serving them, such as "/projects/{id}", so that IDs
+// do not multiply the series. Websockets are counted once upgraded but not
+// timed, since they last as long as the client stays.
+func (m *Metrics) Middleware(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(router, r)
+			m.inFlight.Inc()
+			defer m.inFlight.Dec()
+
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			status := strconv.Itoa(rec.status)
+			m.requests.WithLabelValues(r.Method, route, status).Inc()
+			if !rec.hijacked {
+				m.duration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+			}
+		})
+	}
+}
+
+// routeTemplate returns the template of the route router would serve r
+// with, or Unmatched.
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if router.Match(r, &match) && match.Route != nil {
+		if template, err := match.Route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return Unmatched
+}
+
+// statusRecorder notes the status of a response. It passes Hijack and
+// Flush through, for websockets and streamed responses.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	hijacked    bool
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("metrics: response does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.status, w.hijacked = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
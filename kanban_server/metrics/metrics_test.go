@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kanban_server/config"
+	"kanban_server/models"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm/logger"
+)
+
+func TestMiddlewareLabelsRouteTemplates(t *testing.T) {
+	m := New()
+	router := mux.NewRouter()
+	router.HandleFunc("/projects/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "2" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}).Methods("GET")
+	handler := m.Middleware(router)(router)
+
+	for _, path := range []string{"/projects/1", "/projects/3", "/projects/2", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	tests := []struct {
+		route, status string
+		want          float64
+	}{
+		{"/projects/{id}", "200", 2},
+		{"/projects/{id}", "404", 1},
+		{Unmatched, "404", 1},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues("GET", tt.route, tt.status)); got != tt.want {
+			t.Errorf("requests{route=%q,status=%q} = %v, want %v", tt.route, tt.status, got, tt.want)
+		}
+	}
+	if n := testutil.CollectAndCount(m.duration); n != 3 {
+		t.Errorf("got %d latency series, want 3", n)
+	}
+}
+
+func TestBoardCollector(t *testing.T) {
+	db, err := config.Open(config.DriverSQLite, filepath.Join(t.TempDir(), "kanban.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Discard
+	project := models.Project{Title: "Board", Status: models.ProjectStatusActive}
+	db.Create(&project)
+	db.Create(&[]models.Task{
+		{Title: "a", Status: models.TaskStatusTodo, Priority: "medium", ProjectID: project.ID},
+		{Title: "b", Status: models.TaskStatusTodo, Priority: "medium", ProjectID: project.ID},
+		{Title: "c", Status: models.TaskStatusDone, Priority: "medium", ProjectID: project.ID},
+	})
+
+	want := `
+# HELP kanban_tasks Tasks by status.
+# TYPE kanban_tasks gauge
+kanban_tasks{status="done"} 1
+kanban_tasks{status="in_progress"} 0
+kanban_tasks{status="todo"} 2
+# HELP kanban_webhook_queue_depth Webhook deliveries waiting to be sent or retried.
+# TYPE kanban_webhook_queue_depth gauge
+kanban_webhook_queue_depth 0
+`
+	if err := testutil.CollectAndCompare(NewBoardCollector(db), strings.NewReader(want), "kanban_tasks", "kanban_webhook_queue_depth"); err != nil {
+		t.Error(err)
+	}
+}
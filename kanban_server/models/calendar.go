@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarFeed is a secret URL serving an iCalendar feed of due dates: the
+// tasks assigned to the user, or all tasks of ProjectID when it is set.
+// Deleting the row revokes the URL.
+type CalendarFeed struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	ProjectID *uint     `json:"project_id,omitempty" gorm:"index"`
+	Token     string    `json:"-" gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time `json:"created_at"`
+}
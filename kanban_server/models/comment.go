@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+type Comment struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TaskID    uint      `json:"task_id" gorm:"not null;index"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	User      User      `json:"-"`
+	Body      string    `json:"body" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailPreference holds a user's email opt-outs. UnsubscribeToken lets the
+// links in emails change these settings without logging in.
+type EmailPreference struct {
+	UserID           uint      `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Assignments      bool      `json:"assignments" gorm:"not null"`
+	Mentions         bool      `json:"mentions" gorm:"not null"`
+	Digest           bool      `json:"digest" gorm:"not null"`
+	UnsubscribeToken string    `json:"-" gorm:"not null;uniqueIndex"`
+	LastDigestAt     time.Time `json:"-"`
+	CreatedAt        time.Time `json:"-"`
+	UpdatedAt        time.Time `json:"-"`
+}
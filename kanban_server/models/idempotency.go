@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey stores the first response to a mutating request so that
+// retries carrying the same Idempotency-Key header can be replayed.
+type IdempotencyKey struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Key         string `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_user_key"`
+	RequestHash string `gorm:"size:64;not null"`
+	Completed   bool   `gorm:"not null;default:false"`
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time
+}
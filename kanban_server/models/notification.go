@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification is an entry in a user's in-app inbox.
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index:idx_notification_inbox"`
+	Type      string     `json:"type" gorm:"not null"`
+	Message   string     `json:"message" gorm:"not null"`
+	ActorID   uint       `json:"actor_id,omitempty"`
+	ProjectID uint       `json:"project_id,omitempty"`
+	TaskID    uint       `json:"task_id,omitempty"`
+	CommentID uint       `json:"comment_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at" gorm:"index:idx_notification_inbox"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationMute silences one notification type for one user.
+type NotificationMute struct {
+	UserID    uint      `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Type      string    `json:"type" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"-"`
+}
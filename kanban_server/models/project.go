@@ -4,6 +4,34 @@ import (
 	"time"
 )
 
+// Allowed values for Project.Status.
+const (
+	ProjectStatusActive    = "active"
+	ProjectStatusOnHold    = "on_hold"
+	ProjectStatusCompleted = "completed"
+	ProjectStatusArchived  = "archived"
+)
+
+// Allowed values for Task.Status.
+const (
+	TaskStatusTodo       = "todo"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusDone       = "done"
+)
+
+// Allowed values for Task.Priority.
+const (
+	TaskPriorityLow    = "low"
+	TaskPriorityMedium = "medium"
+	TaskPriorityHigh   = "high"
+)
+
+var (
+	ProjectStatuses = []string{ProjectStatusActive, ProjectStatusOnHold, ProjectStatusCompleted, ProjectStatusArchived}
+	TaskStatuses    = []string{TaskStatusTodo, TaskStatusInProgress, TaskStatusDone}
+	TaskPriorities  = []string{TaskPriorityLow, TaskPriorityMedium, TaskPriorityHigh}
+)
+
 type Project struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
 	Title       string    `json:"title" gorm:"not null"`
@@ -13,6 +41,8 @@ type Project struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 	Users       []User    `json:"users,omitempty" gorm:"many2many:user_projects;"`
 	Tasks       []Task    `json:"tasks,omitempty"`
+	Columns     []Column  `json:"columns,omitempty"`
+	Labels      []Label   `json:"labels,omitempty"`
 }
 
 type Task struct {
@@ -24,7 +54,39 @@ type Task struct {
 	DueDate     time.Time `json:"due_date"`
 	ProjectID   uint      `json:"project_id"`
 	Project     Project   `json:"-"`
+	ColumnID    *uint     `json:"column_id"`
 	AssignedTo  uint      `json:"assigned_to"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Archived    bool      `json:"archived" gorm:"not null;default:false"`
+	Overdue     bool      `json:"overdue" gorm:"not null;default:false"`
+	// Escalated is set once the members of the project were told that the
+	// task is overdue, and cleared with Overdue.
+	Escalated bool `json:"-" gorm:"not null;default:false"`
+	// RecurrenceID and OccurrenceAt identify one occurrence of a repeating
+	// task; the unique index keeps an occurrence from being created twice.
+	RecurrenceID *uint      `json:"recurrence_id,omitempty" gorm:"uniqueIndex:idx_task_occurrence"`
+	OccurrenceAt *time.Time `json:"occurrence_at,omitempty" gorm:"uniqueIndex:idx_task_occurrence"`
+	Labels       []Label    `json:"labels,omitempty" gorm:"many2many:task_labels;"`
+	Comments     []Comment  `json:"comments,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Column is a named lane on a project's board. Tasks without a column are
+// shown in the lane matching their status.
+type Column struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ProjectID uint      `json:"project_id" gorm:"not null;index"`
+	Name      string    `json:"name" gorm:"not null"`
+	Position  int       `json:"position" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Label struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ProjectID uint      `json:"project_id" gorm:"not null;uniqueIndex:idx_label_project_name"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_label_project_name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Recurrence repeats a task according to an RFC 5545 RRULE. Every
+// occurrence is a separate task pointing back through Task.RecurrenceID;
+// LastOccurrenceAt and LastTaskID track the newest one.
+type Recurrence struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	ProjectID        uint      `json:"project_id" gorm:"not null;index"`
+	Rule             string    `json:"rule" gorm:"not null"`
+	Start            time.Time `json:"start" gorm:"not null"`
+	LastOccurrenceAt time.Time `json:"last_occurrence_at" gorm:"not null"`
+	LastTaskID       uint      `json:"last_task_id" gorm:"not null"`
+	Ended            bool      `json:"ended" gorm:"not null;default:false;index"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
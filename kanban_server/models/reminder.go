@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// ReminderPreference holds a user's due-date reminder settings. Offsets is a
+// comma separated list of minutes before the due date; users without a row
+// get the defaults of the reminders package.
+type ReminderPreference struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Enabled   bool      `json:"enabled" gorm:"not null;default:true"`
+	Offsets   string    `json:"-" gorm:"not null;default:''"`
+	Overdue   bool      `json:"overdue" gorm:"not null;default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReminderLog records each reminder sent so that it goes out only once per
+// due date, even with several workers. Moving the due date re-arms reminders.
+type ReminderLog struct {
+	ID            uint      `gorm:"primaryKey"`
+	TaskID        uint      `gorm:"not null;uniqueIndex:idx_reminder_once"`
+	UserID        uint      `gorm:"not null;uniqueIndex:idx_reminder_once"`
+	Kind          string    `gorm:"not null;uniqueIndex:idx_reminder_once"`
+	OffsetMinutes int       `gorm:"not null;uniqueIndex:idx_reminder_once"`
+	DueDate       time.Time `gorm:"not null;uniqueIndex:idx_reminder_once"`
+	SentAt        time.Time `gorm:"not null"`
+}
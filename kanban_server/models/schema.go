@@ -0,0 +1,11 @@
+package models
+
+import (
+	"time"
+)
+
+// SchemaMigration records that the database was migrated to Version.
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// ProjectTemplate is a reusable project skeleton. Document holds a
+// transfer.Document in JSON with due dates stored relative to the day the
+// template was saved.
+type ProjectTemplate struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Name         string    `json:"name" gorm:"not null"`
+	Description  string    `json:"description"`
+	OwnerID      uint      `json:"owner_id" gorm:"not null;index"`
+	IncludeTasks bool      `json:"include_tasks"`
+	Document     string    `json:"-" gorm:"type:text;not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Webhook delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSubscription posts a project's events to an external URL.
+type WebhookSubscription struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	ProjectID uint   `json:"project_id" gorm:"not null;index"`
+	URL       string `json:"url" gorm:"not null"`
+	Secret    string `json:"-" gorm:"not null"`
+	// Events is a comma separated list of event types, "*" for all.
+	Events    string    `json:"events" gorm:"not null"`
+	Active    bool      `json:"active" gorm:"not null"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event queued for one subscription, together with the
+// outcome of its latest attempt.
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null;index"`
+	EventID        string     `json:"event_id" gorm:"not null"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        string     `json:"-" gorm:"type:text;not null"`
+	Status         string     `json:"status" gorm:"not null;index:idx_delivery_queue"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index:idx_delivery_queue"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	RedeliveryOf   *uint      `json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
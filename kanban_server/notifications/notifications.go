@@ -0,0 +1,154 @@
+// Package notifications fills users' in-app inboxes.
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kanban_server/models"
+	"kanban_server/reminders"
+
+	"gorm.io/gorm"
+)
+
+// Notification types. The reminder types match the reminders package kinds.
+const (
+	TypeAssigned          = "task_assigned"
+	TypeComment           = "comment"
+	TypeMention           = "mention"
+	TypeDueSoon           = reminders.KindDueSoon
+	TypeOverdue           = reminders.KindOverdue
+	TypeOverdueEscalation = reminders.KindEscalation
+	TypeMemberAdded       = "member_added"
+	TypeMemberRemoved     = "member_removed"
+)
+
+// Types lists every notification type a user can mute.
+var Types = []string{
+	TypeAssigned,
+	TypeComment,
+	TypeMention,
+	TypeDueSoon,
+	TypeOverdue,
+	TypeOverdueEscalation,
+	TypeMemberAdded,
+	TypeMemberRemoved,
+}
+
+// Send stores notifications in their recipients' inboxes and returns the
+// stored ones. Notifications for the user who caused them and for types the
+// recipient muted are dropped.
+func Send(db *gorm.DB, notifications ...models.Notification) ([]models.Notification, error) {
+	var userIDs []uint
+	for _, n := range notifications {
+		userIDs = append(userIDs, n.UserID)
+	}
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	var mutes []models.NotificationMute
+	if err := db.Where("user_id IN ?", userIDs).Find(&mutes).Error; err != nil {
+		return nil, err
+	}
+	muted := make(map[models.NotificationMute]bool, len(mutes))
+	for _, m := range mutes {
+		muted[models.NotificationMute{UserID: m.UserID, Type: m.Type}] = true
+	}
+
+	var inbox []models.Notification
+	for _, n := range notifications {
+		if n.UserID == 0 || n.UserID == n.ActorID || muted[models.NotificationMute{UserID: n.UserID, Type: n.Type}] {
+			continue
+		}
+		inbox = append(inbox, n)
+	}
+	if len(inbox) == 0 {
+		return nil, nil
+	}
+	if err := db.Create(&inbox).Error; err != nil {
+		return nil, err
+	}
+	return inbox, nil
+}
+
+// InboxNotifier delivers due-date reminders to the in-app inbox.
+type InboxNotifier struct {
+	DB *gorm.DB
+}
+
+func (i InboxNotifier) Notify(ctx context.Context, n reminders.Notification) error {
+	_, err := Send(i.DB.WithContext(ctx), models.Notification{
+		UserID:    n.UserID,
+		Type:      n.Kind,
+		Message:   ReminderMessage(n),
+		ProjectID: n.ProjectID,
+		TaskID:    n.TaskID,
+	})
+	return err
+}
+
+// ReminderMessage describes a due-date reminder.
+func ReminderMessage(n reminders.Notification) string {
+	switch n.Kind {
+	case reminders.KindDueSoon:
+		return fmt.Sprintf("%q is due %s", n.TaskTitle, n.DueDate.Format("Jan 2 15:04"))
+	case reminders.KindOverdue:
+		return fmt.Sprintf("%q is overdue", n.TaskTitle)
+	default:
+		return fmt.Sprintf("%q has been overdue since %s", n.TaskTitle, n.DueDate.Format("Jan 2 15:04"))
+	}
+}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.+-])@([\w.+-]+(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+
+// ParseMentions returns the distinct handles mentioned in a comment body, in
+// order and lower-cased. A handle is either a full email address
+// ("@ana@example.com") or the local part of one ("@ana").
+func ParseMentions(body string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], "."))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// MatchMentions returns the users a set of handles refers to. Local-part
+// handles only match when exactly one user has that local part, so an
+// ambiguous "@ana" notifies nobody rather than the wrong person.
+func MatchMentions(handles []string, users []models.User) []models.User {
+	byEmail := make(map[string]models.User, len(users))
+	byLocal := make(map[string][]models.User, len(users))
+	for _, user := range users {
+		email := strings.ToLower(user.Email)
+		byEmail[email] = user
+		local, _, _ := strings.Cut(email, "@")
+		byLocal[local] = append(byLocal[local], user)
+	}
+
+	var matched []models.User
+	seen := make(map[uint]bool)
+	for _, handle := range handles {
+		var user models.User
+		if u, ok := byEmail[handle]; ok {
+			user = u
+		} else if candidates := byLocal[handle]; len(candidates) == 1 {
+			user = candidates[0]
+		} else {
+			continue
+		}
+		if !seen[user.ID] {
+			seen[user.ID] = true
+			matched = append(matched, user)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"reflect"
+	"testing"
+
+	"kanban_server/models"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"no mentions here", nil},
+		{"@ana please review", []string{"ana"}},
+		{"thanks @Ana and @bob.smith.", []string{"ana", "bob.smith"}},
+		{"cc @ana@example.com, @ana@Example.com", []string{"ana@example.com"}},
+		{"mail ana@example.com directly", nil},
+		{"@ana @ana", []string{"ana"}},
+	}
+	for _, tt := range tests {
+		if got := ParseMentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMentions(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestMatchMentions(t *testing.T) {
+	users := []models.User{
+		{ID: 1, Email: "ana@example.com"},
+		{ID: 2, Email: "Bob@example.com"},
+		{ID: 3, Email: "ana@other.org"},
+	}
+
+	got := MatchMentions([]string{"ana", "bob", "ana@other.org", "carol", "bob@example.com"}, users)
+	var ids []uint
+	for _, user := range got {
+		ids = append(ids, user.ID)
+	}
+	if want := []uint{2, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("MatchMentions matched %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,230 @@
+// Package openapi holds the OpenAPI 3 description of the REST API and a
+// middleware that checks requests, and optionally responses, against it.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"kanban_server/problem"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// Document is the OpenAPI document served at /openapi.json. It must be kept
+// in step with the routes registered in main.
+//
+//go:embed openapi.json
+var Document []byte
+
+func init() {
+	// Calendar feeds and the unsubscribe page are plain text as far as the
+	// document is concerned.
+	openapi3filter.RegisterBodyDecoder("text/calendar", openapi3filter.PlainBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.PlainBodyDecoder)
+}
+
+// Load parses and validates Document.
+func Load() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(Document)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Handler serves Document.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(Document)
+	})
+}
+
+// FieldError locates one problem with a request.
+type FieldError = problem.FieldError
+
+// Validator checks traffic against a document. Requests to routes the
+// document does not describe are passed through untouched.
+type Validator struct {
+	router routers.Router
+	// ValidateResponses makes handlers' responses be checked too. A response
+	// that does not match is replaced with a 500, so it is meant for tests
+	// rather than production.
+	ValidateResponses bool
+}
+
+// NewValidator returns a Validator for doc, which must have been validated.
+func NewValidator(doc *openapi3.T) (*Validator, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{router: router}, nil
+}
+
+func (v *Validator) options() *openapi3filter.Options {
+	return &openapi3filter.Options{
+		MultiError: true,
+		// Handlers tell an absent member from a default one, for instance in
+		// merge patches, so the body must reach them unchanged.
+		SkipSettingDefaults: true,
+		// Tokens are checked by the auth middleware.
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+}
+
+// Middleware rejects requests that do not match the document with a problem
+// listing every offending field: a 400 if part of the request could not be
+// parsed and a 422 otherwise.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    v.options(),
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			p := problem.Invalid("Request validation failed", FieldErrors(err)...)
+			if malformed(err) {
+				p.Status, p.Title, p.Code = http.StatusBadRequest, http.StatusText(http.StatusBadRequest), problem.CodeBadRequest
+			}
+			problem.Write(w, r, p)
+			return
+		}
+
+		if !v.ValidateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &bufferedWriter{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		out := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 rec.status,
+			Header:                 rec.header,
+			Options:                v.options(),
+		}
+		err = openapi3filter.ValidateResponse(r.Context(), out.SetBodyBytes(rec.body.Bytes()))
+		if err != nil {
+			log.Printf("Response to %s %s does not match the API document: %v\n", r.Method, r.URL.Path, err)
+			problem.Write(w, r, problem.Internal("Response does not match the API document: "+err.Error()))
+			return
+		}
+
+		for key, values := range rec.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+	})
+}
+
+// bufferedWriter holds a response until it has been validated.
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header { return w.header }
+
+func (w *bufferedWriter) WriteHeader(status int) { w.status = status }
+
+func (w *bufferedWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+
+// FieldErrors flattens a validation error into one entry per problem.
+func FieldErrors(err error) []FieldError {
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var fieldErrors []FieldError
+		for _, e := range multi {
+			fieldErrors = append(fieldErrors, FieldErrors(e)...)
+		}
+		return fieldErrors
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return []FieldError{{Message: err.Error()}}
+	}
+
+	if p := reqErr.Parameter; p != nil {
+		return []FieldError{{Field: p.Name, In: p.In, Message: reason(reqErr)}}
+	}
+
+	var schemaErrs openapi3.MultiError
+	if errors.As(reqErr.Err, &schemaErrs) {
+		var fieldErrors []FieldError
+		for _, e := range schemaErrs {
+			fieldErrors = append(fieldErrors, bodyError(e))
+		}
+		return fieldErrors
+	}
+	return []FieldError{bodyError(reqErr)}
+}
+
+func bodyError(err error) FieldError {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return FieldError{
+			Field:   strings.Join(schemaErr.JSONPointer(), "."),
+			In:      "body",
+			Message: schemaErr.Reason,
+		}
+	}
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		return FieldError{In: "body", Message: reason(reqErr)}
+	}
+	return FieldError{In: "body", Message: err.Error()}
+}
+
+// reason describes a request error without the value that caused it.
+func reason(err *openapi3filter.RequestError) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err.Err, &schemaErr) {
+		return schemaErr.Reason
+	}
+	if err.Err != nil && err.Reason == "" {
+		return err.Err.Error()
+	}
+	if err.Err != nil {
+		return err.Reason + ": " + err.Err.Error()
+	}
+	return err.Reason
+}
+
+// malformed reports whether err includes a value that could not be parsed,
+// such as a body that is not JSON or a non-numeric ID.
+func malformed(err error) bool {
+	if multi, ok := err.(openapi3.MultiError); ok {
+		for _, e := range multi {
+			if malformed(e) {
+				return true
+			}
+		}
+		return false
+	}
+	var parseErr *openapi3filter.ParseError
+	return errors.As(err, &parseErr)
+}
@@ -0,0 +1,231 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kanban_server/problem"
+)
+
+func newTestValidator(t *testing.T) *Validator {
+	t.Helper()
+	doc, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	v, err := NewValidator(doc)
+	if err != nil {
+		t.Fatalf("NewValidator: %v", err)
+	}
+	return v
+}
+
+func decodeFieldErrors(t *testing.T, rec *httptest.ResponseRecorder) []FieldError {
+	t.Helper()
+	if got := rec.Header().Get("Content-Type"); got != problem.ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, problem.ContentType)
+	}
+	var resp problem.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Detail == "" || resp.Status != rec.Code {
+		t.Errorf("problem %+v does not describe status %d", resp, rec.Code)
+	}
+	return resp.Errors
+}
+
+func TestMiddlewareRejectsInvalidRequests(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		want        []FieldError
+	}{
+		{
+			name:   "missing required member",
+			method: "POST",
+			path:   "/projects",
+			body:   `{"description":"x"}`,
+			status: http.StatusUnprocessableEntity,
+			want:   []FieldError{{Field: "title", In: "body", Message: `property "title" is missing`}},
+		},
+		{
+			name:   "wrong member type",
+			method: "POST",
+			path:   "/projects",
+			body:   `{"title":42}`,
+			status: http.StatusUnprocessableEntity,
+			want:   []FieldError{{Field: "title", In: "body", Message: `value must be a string`}},
+		},
+		{
+			name:   "every problem is reported",
+			method: "POST",
+			path:   "/projects/1/tasks/bulk",
+			body:   `{"action":"explode","task_ids":[1,"two"]}`,
+			status: http.StatusUnprocessableEntity,
+			want: []FieldError{
+				{Field: "action", In: "body", Message: `value is not one of the allowed values ["move","assign","relabel","reprioritize","archive","delete"]`},
+				{Field: "task_ids.1", In: "body", Message: `value must be an integer`},
+			},
+		},
+		{
+			name:        "merge patch member",
+			method:      "PATCH",
+			path:        "/projects/1/tasks/2",
+			contentType: "application/merge-patch+json",
+			body:        `{"priority":"urgent"}`,
+			status:      http.StatusUnprocessableEntity,
+			want:        []FieldError{{Field: "priority", In: "body", Message: `value is not one of the allowed values ["low","medium","high"]`}},
+		},
+		{
+			name:   "path parameter",
+			method: "GET",
+			path:   "/projects/abc",
+			status: http.StatusBadRequest,
+			want:   []FieldError{{Field: "id", In: "path", Message: `value abc: an invalid integer: invalid syntax`}},
+		},
+		{
+			name:   "query parameter",
+			method: "GET",
+			path:   "/notifications?limit=1000",
+			status: http.StatusUnprocessableEntity,
+			want:   []FieldError{{Field: "limit", In: "query", Message: `number must be at most 200`}},
+		},
+		{
+			name:   "malformed body",
+			method: "POST",
+			path:   "/projects",
+			body:   `{"title":`,
+			status: http.StatusBadRequest,
+			want:   []FieldError{{In: "body", Message: "failed to decode request body: unexpected EOF"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				contentType := tt.contentType
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				req.Header.Set("Content-Type", contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if called {
+				t.Fatal("handler was called for an invalid request")
+			}
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := decodeFieldErrors(t, rec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePassesValidRequests(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"POST", "/projects", `{"title":"Launch","description":"Q3"}`},
+		{"PATCH", "/projects/1", `{"description":null}`},
+		{"GET", "/notifications?unread=true&limit=10", ""},
+		// Routes the document does not describe are left alone.
+		{"POST", "/graphql", `{"query":"{ me { id } }"}`},
+	}
+
+	for _, tt := range tests {
+		var body string
+		handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			body = string(b)
+		}))
+
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s %s: status = %d, body %s", tt.method, tt.path, rec.Code, rec.Body)
+		}
+		if body != tt.body {
+			t.Errorf("%s %s: handler read body %q, want %q", tt.method, tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestMiddlewareValidatesResponses(t *testing.T) {
+	v := newTestValidator(t)
+	v.ValidateResponses = true
+
+	respond := func(body string) http.Handler {
+		return v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Test", "kept")
+			io.WriteString(w, body)
+		}))
+	}
+
+	good := `{"message":"","data":{"id":1,"title":"Launch","status":"active"}}`
+	rec := httptest.NewRecorder()
+	respond(good).ServeHTTP(rec, httptest.NewRequest("GET", "/projects/1", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != good {
+		t.Errorf("valid response = %d %s, want it unchanged", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("X-Test") != "kept" {
+		t.Error("response headers were not copied")
+	}
+
+	rec = httptest.NewRecorder()
+	v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		io.WriteString(w, "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")
+	})).ServeHTTP(rec, httptest.NewRequest("GET", "/calendar/abc.ics", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("calendar response status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	respond(`{"message":"","data":{"id":"1","status":"paused"}}`).ServeHTTP(rec, httptest.NewRequest("GET", "/projects/1", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("invalid response status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestHandlerServesDocument(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	var doc map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	if doc["openapi"] != "3.0.3" {
+		t.Errorf("openapi = %v, want 3.0.3", doc["openapi"])
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"kanban_server/problem"
+	"kanban_server/validation"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// patchField describes a JSON member that PATCH requests may change, the
+// column it is stored in and how to convert it. The rules the value must
+// follow are the validate tags of projectPatch and taskPatch.
+type patchField struct {
+	column   string
+	nullable bool
+	decode   func(raw json.RawMessage) (interface{}, error)
+}
+
+var projectPatchFields = map[string]patchField{
+	"title":       {column: "title", decode: decodeString},
+	"description": {column: "description", nullable: true, decode: decodeString},
+	"status":      {column: "status", decode: decodeString},
+}
+
+// projectPatch is a patched project as far as validation is concerned.
+type projectPatch struct {
+	Title       string `json:"title" validate:"required,notblank,max=200"`
+	Description string `json:"description" validate:"max=10000"`
+	Status      string `json:"status" validate:"projectstatus"`
+}
+
+var taskPatchFields = map[string]patchField{
+	"title":       {column: "title", decode: decodeString},
+	"description": {column: "description", nullable: true, decode: decodeString},
+	"status":      {column: "status", decode: decodeString},
+	"priority":    {column: "priority", decode: decodeString},
+	"due_date":    {column: "due_date", nullable: true, decode: decodeTime},
+	"assigned_to": {column: "assigned_to", nullable: true, decode: decodeUint},
+	"column_id":   {column: "column_id", nullable: true, decode: decodeOptionalUint},
+	"archived":    {column: "archived", decode: decodeBool},
+}
+
+// taskPatch is a patched task as far as validation is concerned.
+type taskPatch struct {
+	Title       string     `json:"title" validate:"required,notblank,max=200"`
+	Description string     `json:"description" validate:"max=10000"`
+	Status      string     `json:"status" validate:"taskstatus"`
+	Priority    string     `json:"priority" validate:"taskpriority"`
+	DueDate     *time.Time `json:"due_date" validate:"omitempty,sanedate"`
+}
+
+func (s *Server) patchProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	updates, err := readPatch(r, project, projectPatchFields, &projectPatch{})
+	if err != nil {
+		writeError(w, r, err, "Failed to update project")
+		return
+	}
+
+	project, err = s.Kanban.UpdateProject(r.Context(), currentUserID(r), project, updates)
+	if err != nil {
+		writeError(w, r, err, "Failed to update project")
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Project updated successfully",
+		Data:    project,
+	})
+}
+
+func (s *Server) patchTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+
+	updates, err := readPatch(r, task, taskPatchFields, &taskPatch{})
+	if err != nil {
+		writeError(w, r, err, "Failed to update task")
+		return
+	}
+
+	task, err = s.Kanban.UpdateTask(r.Context(), currentUserID(r), task, updates)
+	if err != nil {
+		writeError(w, r, err, "Failed to update task")
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Task updated successfully",
+		Data:    task,
+	})
+}
+
+// readPatch applies the request body to the JSON form of current and returns
+// the column updates for every member the patch changed. Merge patches
+// (RFC 7396) and JSON Patch documents (RFC 6902) are selected by Content-Type;
+// plain application/json is treated as a merge patch. The patched document is
+// decoded into rules, whose validate tags are checked for the changed
+// members. Problems with the request are returned as *problem.Problem, with
+// every invalid member at once.
+func readPatch(r *http.Request, current interface{}, fields map[string]patchField, rules interface{}) (map[string]interface{}, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, problem.BadRequest("Invalid request body")
+	}
+
+	original, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var patched []byte
+	switch mediaType {
+	case mergePatchContentType, "application/json", "":
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return nil, problem.BadRequest("Merge patch must be a JSON object")
+		}
+		patched, err = jsonpatch.MergePatch(original, body)
+	case jsonPatchContentType:
+		var ops jsonpatch.Patch
+		ops, err = jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, problem.BadRequest("Invalid JSON Patch document")
+		}
+		patched, err = ops.Apply(original)
+	default:
+		return nil, problem.New(http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, fmt.Sprintf("Unsupported Content-Type %q", mediaType))
+	}
+	if err != nil {
+		return nil, problem.Conflict(fmt.Sprintf("Failed to apply patch: %v", err))
+	}
+
+	changes, err := changedMembers(original, patched)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	updates := make(map[string]interface{}, len(changes))
+	var errs []problem.FieldError
+	failed := make(map[string]bool)
+	fail := func(name, message string) {
+		errs = append(errs, problem.FieldError{Field: name, In: "body", Message: message})
+		failed[name] = true
+	}
+	for _, name := range names {
+		raw := changes[name]
+		field, ok := fields[name]
+		if !ok {
+			fail(name, fmt.Sprintf("Field %q cannot be modified", name))
+			continue
+		}
+		if raw == nil || string(raw) == "null" {
+			if !field.nullable {
+				fail(name, fmt.Sprintf("Field %q cannot be removed", name))
+				continue
+			}
+			raw = nil
+		}
+		value, err := field.decode(raw)
+		if err != nil {
+			fail(name, fmt.Sprintf("Invalid value for %q: %v", name, err))
+			continue
+		}
+		updates[field.column] = value
+	}
+
+	// Members of the wrong type were reported above and are left out of
+	// rules by Unmarshal.
+	json.Unmarshal(patched, rules)
+	for _, e := range validation.FieldErrors(rules) {
+		if _, changed := changes[e.Field]; changed && !failed[e.Field] {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, problem.Invalid("Invalid patch", errs...)
+	}
+	return updates, nil
+}
+
+// changedMembers compares two JSON objects and returns the top-level members
+// whose value differs. Members missing from patched map to nil.
+func changedMembers(original, patched []byte) (map[string]json.RawMessage, error) {
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(original, &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return nil, problem.Invalid("Patch must produce a JSON object")
+	}
+
+	changes := make(map[string]json.RawMessage)
+	for name, value := range after {
+		if old, ok := before[name]; !ok || !jsonEqual(old, value) {
+			changes[name] = value
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changes[name] = nil
+		}
+	}
+	return changes, nil
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func decodeString(raw json.RawMessage) (interface{}, error) {
+	var s string
+	if raw != nil {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, errors.New("expected a string")
+		}
+	}
+	return s, nil
+}
+
+func decodeTime(raw json.RawMessage) (interface{}, error) {
+	var t time.Time
+	if raw != nil {
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, errors.New("expected an RFC 3339 timestamp")
+		}
+	}
+	return t, nil
+}
+
+func decodeUint(raw json.RawMessage) (interface{}, error) {
+	var n uint
+	if raw != nil {
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return nil, errors.New("expected a non-negative integer")
+		}
+	}
+	return n, nil
+}
+
+func decodeOptionalUint(raw json.RawMessage) (interface{}, error) {
+	if raw == nil {
+		return (*uint)(nil), nil
+	}
+	n, err := decodeUint(raw)
+	if err != nil {
+		return nil, err
+	}
+	id := n.(uint)
+	return &id, nil
+}
+
+func decodeBool(raw json.RawMessage) (interface{}, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, errors.New("expected a boolean")
+	}
+	return b, nil
+}
@@ -0,0 +1,154 @@
+// Package problem writes REST API errors as RFC 7807 problem details: an
+// application/problem+json body with the HTTP status, a human-readable detail,
+// a machine-readable code, the fields at fault and the request ID.
+package problem
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"kanban_server/requestid"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// Codes identify the kind of problem. Clients should branch on the code
+// rather than on the detail, which is meant for people.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeTooLarge             = "request_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeValidation           = "validation_failed"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeInternal             = "internal_error"
+)
+
+// FieldError locates one problem with a request. Field is the dotted path of
+// a body member ("copy.tasks", "task_ids.2") or the name of a parameter.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	In      string `json:"in"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details object. Type is always
+// "about:blank", so Title is the status text and Code tells problems with
+// the same status apart.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Extensions are further members of the object, such as the per-task
+	// results of a failed bulk operation.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// New returns a problem with the given status, code and detail.
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// BadRequest reports a request that could not be read, such as a malformed
+// body.
+func BadRequest(detail string) *Problem {
+	return New(http.StatusBadRequest, CodeBadRequest, detail)
+}
+
+// Unauthorized reports a missing or invalid token.
+func Unauthorized(detail string) *Problem {
+	return New(http.StatusUnauthorized, CodeUnauthorized, detail)
+}
+
+// NotFound reports a missing resource.
+func NotFound(detail string) *Problem {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+// Conflict reports a request that clashes with the current state, such as a
+// duplicate.
+func Conflict(detail string) *Problem {
+	return New(http.StatusConflict, CodeConflict, detail)
+}
+
+// Invalid reports a well-formed request whose content breaks a rule.
+func Invalid(detail string, errs ...FieldError) *Problem {
+	p := New(http.StatusUnprocessableEntity, CodeValidation, detail)
+	p.Errors = errs
+	return p
+}
+
+// Field reports a single invalid body member.
+func Field(field, message string) *Problem {
+	return Invalid(message, FieldError{Field: field, In: "body", Message: message})
+}
+
+// Param reports a single invalid query or path parameter.
+func Param(in, name, message string) *Problem {
+	return Invalid(message, FieldError{Field: name, In: in, Message: message})
+}
+
+// Internal reports a failure of the server. The detail must not reveal the
+// underlying error.
+func Internal(detail string) *Problem {
+	return New(http.StatusInternalServerError, CodeInternal, detail)
+}
+
+// With sets an extension member and returns p.
+func (p *Problem) With(name string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]interface{}{}
+	}
+	p.Extensions[name] = value
+	return p
+}
+
+// Error returns the detail, so that a problem can travel as an error.
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+// MarshalJSON writes the standard members followed by the extensions.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	data, err := json.Marshal((*plain)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+	extensions, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	data[len(data)-1] = ','
+	return append(data, extensions[1:]...), nil
+}
+
+// Write sends p as the response to r, filling in the instance and request
+// ID.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	p.Instance = r.URL.Path
+	p.RequestID = requestid.FromContext(r.Context())
+
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(p)
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,58 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"kanban_server/requestid"
+)
+
+func TestWrite(t *testing.T) {
+	req := httptest.NewRequest("POST", "/projects/1/tasks/bulk", nil)
+	req = req.WithContext(requestid.NewContext(req.Context(), "req-1"))
+	rec := httptest.NewRecorder()
+
+	p := Field("title", "title is required").With("results", []int{1, 2})
+	Write(rec, req, p)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d, want 422", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("got Content-Type %q, want %q", got, ContentType)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"type":       "about:blank",
+		"title":      "Unprocessable Entity",
+		"status":     float64(422),
+		"detail":     "title is required",
+		"instance":   "/projects/1/tasks/bulk",
+		"code":       CodeValidation,
+		"request_id": "req-1",
+		"errors": []interface{}{
+			map[string]interface{}{"field": "title", "in": "body", "message": "title is required"},
+		},
+		"results": []interface{}{float64(1), float64(2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestMarshalWithoutExtensions(t *testing.T) {
+	data, err := json.Marshal(NotFound("Project not found"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"about:blank","title":"Not Found","status":404,"detail":"Project not found","code":"not_found"}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
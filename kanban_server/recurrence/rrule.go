@@ -0,0 +1,424 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules used
+// for repeating tasks and the scheduler that creates their occurrences.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds the search for the next occurrence so that rules which
+// never match (e.g. BYMONTHDAY=31;BYMONTH=2) cannot loop forever.
+const maxPeriods = 5000
+
+// Weekday is a BYDAY entry. N selects the nth matching weekday of the month
+// (negative counts from the end); zero means every such weekday.
+type Weekday struct {
+	Day time.Weekday
+	N   int
+}
+
+// Rule is a parsed RRULE. Supported parts are FREQ, INTERVAL, COUNT, UNTIL,
+// BYDAY, BYMONTHDAY and BYMONTH; weeks start on Monday and BYDAY ordinals
+// always count within a month.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []Weekday
+	ByMonthDay []int
+	ByMonth    []int
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Parse parses an RRULE value such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE".
+// A leading "RRULE:" is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate rule part %s", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch Frequency(value) {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = Frequency(value)
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %s", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = parsePositive(value)
+		case "COUNT":
+			rule.Count, err = parsePositive(value)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(value, -31, 31)
+		case "BYMONTH":
+			rule.ByMonth, err = parseIntList(value, 1, 12)
+		case "WKST":
+			if value != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL cannot be combined")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return nil, errors.New("BYDAY ordinals require FREQ=MONTHLY or FREQ=YEARLY")
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq == Weekly {
+		return nil, errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	return rule, nil
+}
+
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errors.New("must be a positive integer")
+	}
+	return n, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("expected YYYYMMDD or YYYYMMDDTHHMMSSZ")
+}
+
+func parseByDay(value string) ([]Weekday, error) {
+	var days []Weekday
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid day %q", item)
+		}
+		day, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid day %q", item)
+			}
+		}
+		days = append(days, Weekday{Day: day, N: n})
+	}
+	return days, nil
+}
+
+func parseIntList(value string, min, max int) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("invalid value %q", item)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// String formats the rule back into RRULE syntax.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, day := range r.ByDay {
+			code := strings.ToUpper(day.Day.String()[:2])
+			if day.N != 0 {
+				code = strconv.Itoa(day.N) + code
+			}
+			days = append(days, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+// Next returns the first occurrence of a series starting at dtstart that is
+// strictly after after. The second result is false once the series has
+// ended through COUNT or UNTIL. Candidates before dtstart are ignored and
+// dtstart itself only counts if it matches the rule.
+func (r *Rule) Next(dtstart, after time.Time) (time.Time, bool) {
+	count := 0
+	first := 0
+	if r.Count == 0 {
+		first = r.periodsBetween(dtstart, after)
+	}
+	for period := first; period < first+maxPeriods; period++ {
+		for _, t := range r.occurrencesInPeriod(dtstart, period) {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return time.Time{}, false
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return time.Time{}, false
+			}
+			if t.After(after) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// periodsBetween returns a period index at or before the one containing t,
+// so that series without COUNT need not be walked from the start.
+func (r *Rule) periodsBetween(dtstart, t time.Time) int {
+	if !t.After(dtstart) {
+		return 0
+	}
+	var n int
+	switch r.Freq {
+	case Daily:
+		n = int(t.Sub(dtstart).Hours() / 24)
+	case Weekly:
+		n = int(t.Sub(dtstart).Hours() / (24 * 7))
+	case Monthly:
+		n = (t.Year()-dtstart.Year())*12 + int(t.Month()) - int(dtstart.Month())
+	case Yearly:
+		n = t.Year() - dtstart.Year()
+	}
+	// Step back one period to absorb DST shifts and partial periods.
+	n = n/r.Interval - 1
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// occurrencesInPeriod returns the sorted candidate occurrences of the nth
+// period (day, week, month or year, scaled by INTERVAL) after dtstart.
+func (r *Rule) occurrencesInPeriod(dtstart time.Time, n int) []time.Time {
+	hour, min, sec := dtstart.Clock()
+	loc := dtstart.Location()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, loc)
+	}
+	step := n * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		days = []time.Time{at(dtstart.Year(), dtstart.Month(), dtstart.Day()+step)}
+	case Weekly:
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := at(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step)
+		if len(r.ByDay) == 0 {
+			days = []time.Time{monday.AddDate(0, 0, offset)}
+		}
+		for _, day := range r.ByDay {
+			days = append(days, monday.AddDate(0, 0, (int(day.Day)+6)%7))
+		}
+	case Monthly:
+		first := at(dtstart.Year(), dtstart.Month()+time.Month(step), 1)
+		days = r.daysInMonth(first, dtstart)
+	case Yearly:
+		year := dtstart.Year() + step
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []int{int(dtstart.Month())}
+		}
+		for _, month := range months {
+			days = append(days, r.daysInMonth(at(year, time.Month(month), 1), dtstart)...)
+		}
+	}
+
+	var result []time.Time
+	for _, day := range days {
+		if r.matches(day) {
+			result = append(result, day)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return dedupe(result)
+}
+
+// daysInMonth expands BYMONTHDAY and BYDAY within the month starting at
+// first, defaulting to dtstart's day of month. When both are given, only
+// days matching both are kept, as RFC 5545 specifies. Days that do not exist
+// are skipped.
+func (r *Rule) daysInMonth(first, dtstart time.Time) []time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+
+	var monthDays []int
+	for _, day := range r.ByMonthDay {
+		if day < 0 {
+			day = last + day + 1
+		}
+		monthDays = append(monthDays, day)
+	}
+
+	var candidates []int
+	switch {
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matching []int
+			for day := 1; day <= last; day++ {
+				if first.AddDate(0, 0, day-1).Weekday() == wd.Day {
+					matching = append(matching, day)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				candidates = append(candidates, matching...)
+			case wd.N > 0 && wd.N <= len(matching):
+				candidates = append(candidates, matching[wd.N-1])
+			case wd.N < 0 && -wd.N <= len(matching):
+				candidates = append(candidates, matching[len(matching)+wd.N])
+			}
+		}
+	case len(monthDays) > 0:
+		candidates = monthDays
+	default:
+		candidates = []int{dtstart.Day()}
+	}
+
+	var days []time.Time
+	for _, day := range candidates {
+		if day < 1 || day > last {
+			continue
+		}
+		if len(r.ByDay) > 0 && len(monthDays) > 0 && !containsInt(monthDays, day) {
+			continue
+		}
+		days = append(days, first.AddDate(0, 0, day-1))
+	}
+	return days
+}
+
+// matches applies the BYxxx parts that limit rather than expand the set for
+// the rule's frequency.
+func (r *Rule) matches(t time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(t.Month())) {
+		return false
+	}
+	if r.Freq == Daily {
+		if len(r.ByDay) > 0 {
+			ok := false
+			for _, day := range r.ByDay {
+				ok = ok || day.Day == t.Weekday()
+			}
+			if !ok {
+				return false
+			}
+		}
+		if len(r.ByMonthDay) > 0 {
+			last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+			ok := false
+			for _, day := range r.ByMonthDay {
+				if day < 0 {
+					day = last + day + 1
+				}
+				ok = ok || day == t.Day()
+			}
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(times []time.Time) []time.Time {
+	result := times[:0]
+	for i, t := range times {
+		if i == 0 || !t.Equal(times[i-1]) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	valid := map[string]string{
+		"FREQ=DAILY": "FREQ=DAILY",
+		"RRULE:freq=weekly;byday=MO,WE;interval=2":  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+		"FREQ=MONTHLY;BYDAY=-1FR;COUNT=3":           "FREQ=MONTHLY;COUNT=3;BYDAY=-1FR",
+		"FREQ=YEARLY;BYMONTH=1,4,7,10;BYMONTHDAY=1": "FREQ=YEARLY;BYMONTHDAY=1;BYMONTH=1,4,7,10",
+		"FREQ=DAILY;UNTIL=20250131":                 "FREQ=DAILY;UNTIL=20250131T235959Z",
+	}
+	for input, want := range valid {
+		rule, err := Parse(input)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", input, err)
+			continue
+		}
+		if got := rule.String(); got != want {
+			t.Errorf("Parse(%q).String() = %q, want %q", input, got, want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250101",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;BYSETPOS=1",
+	}
+	for _, input := range invalid {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) expected error", input)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Wednesday 2025-01-15 09:00 UTC.
+	start := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 9, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		rule  string
+		after time.Time
+		want  []time.Time
+	}{
+		{"FREQ=DAILY;INTERVAL=2", start, []time.Time{day(2025, 1, 17), day(2025, 1, 19)}},
+		{"FREQ=WEEKLY;BYDAY=MO,FR", start, []time.Time{day(2025, 1, 17), day(2025, 1, 20), day(2025, 1, 24)}},
+		{"FREQ=WEEKLY;INTERVAL=2", start, []time.Time{day(2025, 1, 29), day(2025, 2, 12)}},
+		{"FREQ=MONTHLY", start, []time.Time{day(2025, 2, 15), day(2025, 3, 15)}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", start, []time.Time{day(2025, 1, 31), day(2025, 2, 28)}},
+		{"FREQ=MONTHLY;BYDAY=1MO", start, []time.Time{day(2025, 2, 3), day(2025, 3, 3)}},
+		{"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", start, []time.Time{day(2025, 6, 13), day(2026, 2, 13), day(2026, 3, 13)}},
+		{"FREQ=YEARLY;BYMONTH=5;BYDAY=MO;BYMONTHDAY=-7,-6,-5,-4,-3,-2,-1", start, []time.Time{day(2025, 5, 26), day(2026, 5, 25)}},
+		{"FREQ=YEARLY;BYMONTH=1,4,7,10;BYMONTHDAY=1", start, []time.Time{day(2025, 4, 1), day(2025, 7, 1)}},
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", day(2025, 1, 17), []time.Time{day(2025, 1, 20)}},
+		{"FREQ=DAILY", start.Add(-time.Hour), []time.Time{start}},
+	}
+
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := tt.after
+		for i, want := range tt.want {
+			got, ok := rule.Next(start, after)
+			if !ok || !got.Equal(want) {
+				t.Errorf("%s occurrence %d after %s = %s (%v), want %s", tt.rule, i, after, got, ok, want)
+				break
+			}
+			after = got
+		}
+	}
+}
+
+func TestNextEnds(t *testing.T) {
+	start := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	rule, _ := Parse("FREQ=DAILY;COUNT=2")
+	second, ok := rule.Next(start, start)
+	if !ok {
+		t.Fatal("expected a second occurrence")
+	}
+	if _, ok := rule.Next(start, second); ok {
+		t.Error("COUNT=2 produced a third occurrence")
+	}
+
+	rule, _ = Parse("FREQ=WEEKLY;UNTIL=20250210")
+	if _, ok := rule.Next(start, start.AddDate(0, 0, 7)); ok {
+		t.Error("occurrence after UNTIL")
+	}
+
+	// Months without a 31st are skipped rather than clamped.
+	rule, _ = Parse("FREQ=MONTHLY")
+	next, _ := rule.Next(start, start)
+	if next.Month() != time.March || next.Day() != 31 {
+		t.Errorf("next monthly occurrence = %s, want March 31", next)
+	}
+
+	rule, _ = Parse("FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30")
+	if _, ok := rule.Next(start, start); ok {
+		t.Error("impossible rule produced an occurrence")
+	}
+}
+
+func TestNextFarFromStart(t *testing.T) {
+	start := time.Date(2000, 1, 3, 9, 0, 0, 0, time.UTC)
+	rule, _ := Parse("FREQ=DAILY;INTERVAL=3")
+
+	after := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+	got, ok := rule.Next(start, after)
+	if !ok {
+		t.Fatal("long running series ended")
+	}
+	if days := int(got.Sub(start).Hours() / 24); days%3 != 0 || got.Sub(after) > 72*time.Hour {
+		t.Errorf("Next = %s, not on the 3 day cadence right after %s", got, after)
+	}
+}
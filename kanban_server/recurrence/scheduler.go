@@ -0,0 +1,168 @@
+package recurrence
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"kanban_server/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxCatchUp limits how many missed occurrences of one series are created in
+// a single pass, e.g. after the server was down for a long time.
+const maxCatchUp = 50
+
+// Scheduler creates the next occurrence of a repeating task once the current
+// one is done or the next occurrence's time has come. Several schedulers may
+// run against the same database: each occurrence is claimed with a
+// compare-and-set on Recurrence.LastOccurrenceAt and protected by the unique
+// (recurrence_id, occurrence_at) index on tasks.
+type Scheduler struct {
+	db       *gorm.DB
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewScheduler(db *gorm.DB, interval time.Duration) *Scheduler {
+	return &Scheduler{db: db, interval: interval, now: time.Now}
+}
+
+// Run processes all series immediately and then every interval until ctx is
+// cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if created, err := s.RunOnce(ctx); err != nil {
+			log.Println("Recurrence scheduler:", err)
+		} else if created > 0 {
+			log.Printf("Recurrence scheduler created %d occurrences\n", created)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce makes a single pass over all active series and returns the number
+// of tasks created.
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	var recurrences []models.Recurrence
+	if err := s.db.WithContext(ctx).Where("ended = ?", false).Find(&recurrences).Error; err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, rec := range recurrences {
+		for i := 0; i < maxCatchUp; i++ {
+			if ctx.Err() != nil {
+				return created, ctx.Err()
+			}
+			ok, err := s.advance(ctx, &rec)
+			if err != nil {
+				log.Printf("Recurrence %d: %v\n", rec.ID, err)
+				break
+			}
+			if !ok {
+				break
+			}
+			created++
+		}
+	}
+	return created, nil
+}
+
+// advance creates the occurrence following rec's last one if it is due and
+// updates rec in place. It reports whether a task was created.
+func (s *Scheduler) advance(ctx context.Context, rec *models.Recurrence) (bool, error) {
+	db := s.db.WithContext(ctx)
+
+	rule, err := Parse(rec.Rule)
+	if err != nil {
+		return false, s.end(db, rec)
+	}
+	next, ok := rule.Next(rec.Start, rec.LastOccurrenceAt)
+	if !ok {
+		return false, s.end(db, rec)
+	}
+
+	var last models.Task
+	if err := db.Preload("Labels").First(&last, rec.LastTaskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, s.end(db, rec)
+		}
+		return false, err
+	}
+
+	if last.Status != models.TaskStatusDone && s.now().Before(next) {
+		return false, nil
+	}
+
+	var task models.Task
+	err = db.Transaction(func(tx *gorm.DB) error {
+		claim := tx.Model(&models.Recurrence{}).
+			Where("id = ? AND last_occurrence_at = ?", rec.ID, rec.LastOccurrenceAt).
+			Update("last_occurrence_at", next)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return errClaimed
+		}
+
+		occurrenceAt := next
+		task = models.Task{
+			Title:        last.Title,
+			Description:  last.Description,
+			Status:       models.TaskStatusTodo,
+			Priority:     last.Priority,
+			DueDate:      next,
+			ProjectID:    last.ProjectID,
+			AssignedTo:   last.AssignedTo,
+			RecurrenceID: &rec.ID,
+			OccurrenceAt: &occurrenceAt,
+		}
+		result := tx.Omit("Labels").Clauses(clause.OnConflict{DoNothing: true}).Create(&task)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// The occurrence already exists, adopt it rather than duplicate it.
+			if err := tx.Where("recurrence_id = ? AND occurrence_at = ?", rec.ID, next).First(&task).Error; err != nil {
+				return err
+			}
+		} else if len(last.Labels) > 0 {
+			if err := tx.Model(&task).Association("Labels").Append(last.Labels); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.Recurrence{}).Where("id = ?", rec.ID).Update("last_task_id", task.ID).Error
+	})
+	if errors.Is(err, errClaimed) {
+		// Another scheduler advanced this series; pick up its state.
+		return false, db.First(rec, rec.ID).Error
+	}
+	if err != nil {
+		return false, err
+	}
+
+	rec.LastOccurrenceAt = next
+	rec.LastTaskID = task.ID
+	return true, nil
+}
+
+var errClaimed = errors.New("occurrence claimed by another scheduler")
+
+func (s *Scheduler) end(db *gorm.DB, rec *models.Recurrence) error {
+	rec.Ended = true
+	return db.Model(rec).Update("ended", true).Error
+}
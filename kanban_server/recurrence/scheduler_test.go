@@ -0,0 +1,111 @@
+package recurrence
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"kanban_server/config"
+	"kanban_server/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	db, err := config.Open(config.DriverSQLite, path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.Logger = logger.Discard
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestSchedulerCreatesEachOccurrenceOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kanban.db")
+	db := openTestDB(t, path)
+
+	start := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	now := func() time.Time { return start.AddDate(0, 0, 5).Add(time.Hour) }
+
+	project := models.Project{Title: "Chores"}
+	if err := db.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	first := models.Task{Title: "Water plants", Status: models.TaskStatusDone, DueDate: start, ProjectID: project.ID}
+	if err := db.Create(&first).Error; err != nil {
+		t.Fatal(err)
+	}
+	rec := models.Recurrence{ProjectID: project.ID, Rule: "FREQ=DAILY", Start: start, LastOccurrenceAt: start, LastTaskID: first.ID}
+	if err := db.Create(&rec).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Two instances race over the same series.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 2; i++ {
+		s := NewScheduler(openTestDB(t, path), time.Minute)
+		s.now = now
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := s.RunOnce(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			created += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// A restarted instance picks up where they left off.
+	restarted := NewScheduler(openTestDB(t, path), time.Minute)
+	restarted.now = now
+	n, err := restarted.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	created += n
+
+	// The occurrences of the 16th to the 20th are due; the 21st is not, and
+	// the 20th is not done yet.
+	if created != 5 {
+		t.Errorf("created %d occurrences, want 5", created)
+	}
+	var tasks []models.Task
+	if err := db.Where("recurrence_id = ?", rec.ID).Order("occurrence_at").Find(&tasks).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 5 {
+		t.Fatalf("found %d occurrences, want 5", len(tasks))
+	}
+	for i, task := range tasks {
+		if want := start.AddDate(0, 0, i+1); !task.OccurrenceAt.Equal(want) {
+			t.Errorf("occurrence %d at %s, want %s", i, task.OccurrenceAt, want)
+		}
+	}
+
+	if err := db.First(&rec, rec.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rec.LastTaskID != tasks[4].ID {
+		t.Errorf("last task = %d, want %d", rec.LastTaskID, tasks[4].ID)
+	}
+
+	// Running again changes nothing.
+	if n, err := restarted.RunOnce(context.Background()); err != nil || n != 0 {
+		t.Errorf("second pass created %d occurrences (%v), want 0", n, err)
+	}
+}
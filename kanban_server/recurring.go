@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/problem"
+	"kanban_server/recurrence"
+
+	"gorm.io/gorm"
+)
+
+type RecurrenceRequest struct {
+	Rule  string     `json:"rule" validate:"required"`
+	Start *time.Time `json:"start" validate:"omitempty,sanedate"`
+}
+
+type RecurrenceResponse struct {
+	models.Recurrence
+	NextOccurrence *time.Time `json:"next_occurrence"`
+}
+
+func newRecurrenceResponse(rec models.Recurrence) RecurrenceResponse {
+	resp := RecurrenceResponse{Recurrence: rec}
+	if rule, err := recurrence.Parse(rec.Rule); err == nil && !rec.Ended {
+		if next, ok := rule.Next(rec.Start, rec.LastOccurrenceAt); ok {
+			resp.NextOccurrence = &next
+		}
+	}
+	return resp
+}
+
+// setTaskRecurrence makes the task repeat. A task that is not yet part of a
+// series becomes its first occurrence. If the task is the only occurrence of
+// its series, the rule is replaced and the series restarted from the task;
+// otherwise that series is ended and the task starts a new one, so that its
+// occurrence cannot clash with those of the other tasks.
+func (s *Server) setTaskRecurrence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req RecurrenceRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	rule, err := recurrence.Parse(req.Rule)
+	if err != nil {
+		problem.Write(w, r, problem.Field("rule", "Invalid recurrence rule: "+err.Error()))
+		return
+	}
+
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+
+	start := time.Now().Truncate(time.Second)
+	switch {
+	case req.Start != nil:
+		start = *req.Start
+	case task.OccurrenceAt != nil:
+		start = *task.OccurrenceAt
+	case !task.DueDate.IsZero():
+		start = task.DueDate
+	}
+
+	var rec models.Recurrence
+	err = s.db(r).Transaction(func(tx *gorm.DB) error {
+		if task.RecurrenceID != nil {
+			var others int64
+			if err := tx.Model(&models.Task{}).Where("recurrence_id = ? AND id <> ?", *task.RecurrenceID, task.ID).Count(&others).Error; err != nil {
+				return err
+			}
+			if others > 0 {
+				if err := tx.Model(&models.Recurrence{}).Where("id = ?", *task.RecurrenceID).Update("ended", true).Error; err != nil {
+					return err
+				}
+			} else if err := tx.First(&rec, *task.RecurrenceID).Error; err != nil {
+				return err
+			}
+		}
+		rec.ProjectID = task.ProjectID
+		rec.Rule = rule.String()
+		rec.Start = start
+		rec.LastOccurrenceAt = start
+		rec.LastTaskID = task.ID
+		rec.Ended = false
+		if err := tx.Save(&rec).Error; err != nil {
+			return err
+		}
+		return tx.Model(&task).Updates(map[string]interface{}{
+			"recurrence_id": rec.ID,
+			"occurrence_at": start,
+		}).Error
+	})
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to save recurrence"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Recurrence saved successfully",
+		Data:    newRecurrenceResponse(rec),
+	})
+}
+
+func (s *Server) getTaskRecurrence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+
+	if task.RecurrenceID == nil {
+		problem.Write(w, r, problem.NotFound("Task does not repeat"))
+		return
+	}
+	var rec models.Recurrence
+	if err := s.db(r).First(&rec, *task.RecurrenceID).Error; err != nil {
+		writeLookupError(w, r, err, "Task does not repeat")
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: newRecurrenceResponse(rec),
+	})
+}
+
+// stopTaskRecurrence ends the task's series. Existing occurrences are kept.
+func (s *Server) stopTaskRecurrence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+
+	if task.RecurrenceID == nil {
+		problem.Write(w, r, problem.NotFound("Task does not repeat"))
+		return
+	}
+
+	if err := s.db(r).Model(&models.Recurrence{}).Where("id = ?", *task.RecurrenceID).Update("ended", true).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to stop recurrence"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Recurrence stopped successfully",
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/problem"
+	"kanban_server/reminders"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReminderPreferenceRequest changes the fields that are present and leaves
+// the others as they are. The longest offset allowed is reminders.MaxOffset,
+// 30 days.
+type ReminderPreferenceRequest struct {
+	Enabled        *bool `json:"enabled"`
+	Overdue        *bool `json:"overdue"`
+	OffsetsMinutes []int `json:"offsets_minutes" validate:"max=10,dive,min=1,max=43200"`
+}
+
+type ReminderPreferenceResponse struct {
+	Enabled        bool  `json:"enabled"`
+	Overdue        bool  `json:"overdue"`
+	OffsetsMinutes []int `json:"offsets_minutes"`
+}
+
+func reminderPreferenceResponse(pref models.ReminderPreference) ReminderPreferenceResponse {
+	offsets, err := reminders.ParseOffsets(pref.Offsets)
+	if err != nil || len(offsets) == 0 {
+		offsets = reminders.DefaultOffsets
+	}
+	minutes := make([]int, len(offsets))
+	for i, offset := range offsets {
+		minutes[i] = int(offset / time.Minute)
+	}
+	return ReminderPreferenceResponse{
+		Enabled:        pref.Enabled,
+		Overdue:        pref.Overdue,
+		OffsetsMinutes: minutes,
+	}
+}
+
+// reminderPreference returns the current user's saved preferences, or the
+// defaults if they saved none.
+func (s *Server) reminderPreference(r *http.Request) (models.ReminderPreference, error) {
+	var prefs []models.ReminderPreference
+	if err := s.db(r).Where("user_id = ?", currentUserID(r)).Limit(1).Find(&prefs).Error; err != nil {
+		return models.ReminderPreference{}, err
+	}
+	if len(prefs) > 0 {
+		return prefs[0], nil
+	}
+	return models.ReminderPreference{UserID: currentUserID(r), Enabled: true, Overdue: true}, nil
+}
+
+func (s *Server) getReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pref, err := s.reminderPreference(r)
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch reminder preferences"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: reminderPreferenceResponse(pref),
+	})
+}
+
+func (s *Server) updateReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ReminderPreferenceRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	seen := make(map[int]bool)
+	var offsets []time.Duration
+	for _, minutes := range req.OffsetsMinutes {
+		if !seen[minutes] {
+			seen[minutes] = true
+			offsets = append(offsets, time.Duration(minutes)*time.Minute)
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+
+	pref, err := s.reminderPreference(r)
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to save reminder preferences"))
+		return
+	}
+	if req.Enabled != nil {
+		pref.Enabled = *req.Enabled
+	}
+	if req.Overdue != nil {
+		pref.Overdue = *req.Overdue
+	}
+	if req.OffsetsMinutes != nil {
+		pref.Offsets = reminders.FormatOffsets(offsets)
+	}
+	err = s.db(r).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ReminderPreference{UserID: pref.UserID}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.ReminderPreference{}).Where("user_id = ?", pref.UserID).Updates(map[string]interface{}{
+			"enabled": pref.Enabled,
+			"overdue": pref.Overdue,
+			"offsets": pref.Offsets,
+		}).Error
+	})
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to save reminder preferences"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Reminder preferences saved successfully",
+		Data:    reminderPreferenceResponse(pref),
+	})
+}
@@ -0,0 +1,79 @@
+// Package reminders notifies assignees about upcoming due dates and flags
+// and escalates overdue tasks.
+package reminders
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Kinds of reminder notifications.
+const (
+	KindDueSoon    = "due_soon"
+	KindOverdue    = "overdue"
+	KindEscalation = "overdue_escalation"
+)
+
+// Notification is a single reminder for one user about one task.
+type Notification struct {
+	UserID    uint
+	TaskID    uint
+	ProjectID uint
+	Kind      string
+	TaskTitle string
+	DueDate   time.Time
+	// Offset is how long before the due date a due_soon reminder was
+	// scheduled; it is zero for other kinds.
+	Offset time.Duration
+}
+
+// Notifier delivers reminders. Implementations must be safe for concurrent
+// use.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier writes reminders to the standard logger.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	log.Printf("Reminder %s for user %d: task %d %q due %s\n",
+		n.Kind, n.UserID, n.TaskID, n.TaskTitle, n.DueDate.Format(time.RFC3339))
+	return nil
+}
+
+// MemoryNotifier keeps reminders in memory, for tests.
+type MemoryNotifier struct {
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (m *MemoryNotifier) Notify(ctx context.Context, n Notification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, n)
+	return nil
+}
+
+// Sent returns a copy of the reminders delivered so far.
+func (m *MemoryNotifier) Sent() []Notification {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Notification(nil), m.sent...)
+}
+
+// MultiNotifier delivers every reminder to all of its notifiers and returns
+// the first error.
+type MultiNotifier []Notifier
+
+func (m MultiNotifier) Notify(ctx context.Context, n Notification) error {
+	var first error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, n); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,322 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kanban_server/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultOffsets are used for users who have not saved preferences.
+var DefaultOffsets = []time.Duration{24 * time.Hour, time.Hour}
+
+// MaxOffset is the longest reminder offset a user may configure.
+const MaxOffset = 30 * 24 * time.Hour
+
+// An escalation that fails is retried after minRetryDelay, doubling up to
+// maxRetryDelay.
+const (
+	minRetryDelay = time.Minute
+	maxRetryDelay = 6 * time.Hour
+)
+
+// Worker periodically sends due-soon reminders, marks tasks overdue and
+// escalates tasks that stay overdue. Every reminder is recorded in
+// models.ReminderLog before it is sent, so it goes out once even when
+// several workers share a database. Tasks that were flagged overdue and
+// escalated are not looked at again until they are rescheduled.
+type Worker struct {
+	db            *gorm.DB
+	notifier      Notifier
+	interval      time.Duration
+	escalateAfter time.Duration
+	now           func() time.Time
+
+	// retries holds the failed escalations by task ID. It is only used by
+	// RunOnce, which is not called concurrently.
+	retries map[uint]retry
+}
+
+type retry struct {
+	delay time.Duration
+	at    time.Time
+}
+
+func NewWorker(db *gorm.DB, notifier Notifier, interval, escalateAfter time.Duration) *Worker {
+	return &Worker{
+		db:            db,
+		notifier:      notifier,
+		interval:      interval,
+		escalateAfter: escalateAfter,
+		now:           time.Now,
+		retries:       make(map[uint]retry),
+	}
+}
+
+// Run checks due dates immediately and then every interval until ctx is
+// cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.RunOnce(ctx); err != nil {
+			log.Println("Reminder worker:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce makes a single pass over all open tasks with a due date.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	db := w.db.WithContext(ctx)
+	now := w.now()
+
+	// Tasks that were completed or rescheduled are no longer overdue.
+	err := db.Model(&models.Task{}).
+		Where("overdue = ? AND (status = ? OR archived = ? OR due_date >= ?)", true, models.TaskStatusDone, true, now).
+		Updates(map[string]interface{}{"overdue": false, "escalated": false}).Error
+	if err != nil {
+		return err
+	}
+
+	// Upcoming tasks, overdue tasks not yet flagged and those still to be
+	// escalated.
+	pending := w.db.Where("due_date > ? OR overdue = ?", now, false)
+	if w.escalateAfter > 0 {
+		pending = pending.Or("escalated = ? AND due_date <= ?", false, now.Add(-w.escalateAfter))
+	}
+	var tasks []models.Task
+	err = db.Where("status <> ? AND archived = ? AND due_date > ? AND due_date <= ?",
+		models.TaskStatusDone, false, time.Time{}, now.Add(MaxOffset)).
+		Where(pending).
+		Find(&tasks).Error
+	if err != nil {
+		return err
+	}
+
+	prefs, err := w.preferences(db, tasks)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		pref := prefs[task.AssignedTo]
+
+		if task.DueDate.After(now) {
+			if task.AssignedTo == 0 || !pref.Enabled {
+				continue
+			}
+			crossed := CrossedOffsets(pref.offsets, task.DueDate, now)
+			if len(crossed) > 0 {
+				w.dueSoon(ctx, db, task, crossed)
+			}
+			continue
+		}
+
+		if !task.Overdue {
+			if err := db.Model(&task).Update("overdue", true).Error; err != nil {
+				return err
+			}
+			if task.AssignedTo != 0 && pref.Overdue {
+				if err := w.send(ctx, db, task, task.AssignedTo, KindOverdue, 0); err != nil {
+					log.Println("Reminder worker:", err)
+				}
+			}
+		}
+
+		if w.escalateAfter > 0 && !task.Escalated && now.Sub(task.DueDate) >= w.escalateAfter {
+			if err := w.escalate(ctx, db, task); err != nil {
+				log.Printf("Reminder worker: escalating task %d: %v\n", task.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dueSoon sends the reminder for the smallest crossed offset and records the
+// larger ones as sent, so a worker that was down does not send a burst of
+// stale reminders.
+func (w *Worker) dueSoon(ctx context.Context, db *gorm.DB, task models.Task, crossed []time.Duration) {
+	for _, offset := range crossed[1:] {
+		if _, err := w.record(db, task, task.AssignedTo, KindDueSoon, offset); err != nil {
+			log.Println("Reminder worker:", err)
+		}
+	}
+	if err := w.send(ctx, db, task, task.AssignedTo, KindDueSoon, crossed[0]); err != nil {
+		log.Println("Reminder worker:", err)
+	}
+}
+
+// escalate notifies the project's members other than the assignee and marks
+// the task escalated. If that fails, it is not attempted again until the
+// retry delay has passed.
+func (w *Worker) escalate(ctx context.Context, db *gorm.DB, task models.Task) error {
+	now := w.now()
+	failed, retrying := w.retries[task.ID]
+	if retrying && now.Before(failed.at) {
+		return nil
+	}
+
+	err := w.notifyMembers(ctx, db, task)
+	if err == nil {
+		err = db.Model(&task).Update("escalated", true).Error
+	}
+	if err != nil {
+		delay := minRetryDelay
+		if retrying {
+			delay = min(2*failed.delay, maxRetryDelay)
+		}
+		w.retries[task.ID] = retry{delay: delay, at: now.Add(delay)}
+		return err
+	}
+	delete(w.retries, task.ID)
+	return nil
+}
+
+func (w *Worker) notifyMembers(ctx context.Context, db *gorm.DB, task models.Task) error {
+	var project models.Project
+	if err := db.Preload("Users").First(&project, task.ProjectID).Error; err != nil {
+		return err
+	}
+	for _, user := range project.Users {
+		if user.ID == task.AssignedTo {
+			continue
+		}
+		if err := w.send(ctx, db, task, user.ID, KindEscalation, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send records the reminder and delivers it if this worker recorded it first.
+// It only fails if the reminder could not be recorded; delivery errors are
+// logged.
+func (w *Worker) send(ctx context.Context, db *gorm.DB, task models.Task, userID uint, kind string, offset time.Duration) error {
+	recorded, err := w.record(db, task, userID, kind, offset)
+	if err != nil || !recorded {
+		return err
+	}
+
+	err = w.notifier.Notify(ctx, Notification{
+		UserID:    userID,
+		TaskID:    task.ID,
+		ProjectID: task.ProjectID,
+		Kind:      kind,
+		TaskTitle: task.Title,
+		DueDate:   task.DueDate,
+		Offset:    offset,
+	})
+	if err != nil {
+		log.Printf("Reminder worker: notifying user %d about task %d: %v\n", userID, task.ID, err)
+	}
+	return nil
+}
+
+// record stores a reminder and reports whether it had not been sent before.
+func (w *Worker) record(db *gorm.DB, task models.Task, userID uint, kind string, offset time.Duration) (bool, error) {
+	entry := models.ReminderLog{
+		TaskID:        task.ID,
+		UserID:        userID,
+		Kind:          kind,
+		OffsetMinutes: int(offset / time.Minute),
+		DueDate:       task.DueDate,
+		SentAt:        w.now(),
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	if result.Error != nil {
+		return false, fmt.Errorf("recording reminder for task %d: %w", task.ID, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+type preference struct {
+	Enabled bool
+	Overdue bool
+	offsets []time.Duration
+}
+
+// preferences loads the reminder settings of every assignee of tasks.
+func (w *Worker) preferences(db *gorm.DB, tasks []models.Task) (map[uint]preference, error) {
+	var userIDs []uint
+	for _, task := range tasks {
+		if task.AssignedTo != 0 {
+			userIDs = append(userIDs, task.AssignedTo)
+		}
+	}
+
+	var rows []models.ReminderPreference
+	if len(userIDs) > 0 {
+		if err := db.Where("user_id IN ?", userIDs).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	prefs := make(map[uint]preference, len(userIDs))
+	for _, id := range userIDs {
+		prefs[id] = preference{Enabled: true, Overdue: true, offsets: DefaultOffsets}
+	}
+	for _, row := range rows {
+		offsets, err := ParseOffsets(row.Offsets)
+		if err != nil || len(offsets) == 0 {
+			offsets = DefaultOffsets
+		}
+		prefs[row.UserID] = preference{Enabled: row.Enabled, Overdue: row.Overdue, offsets: offsets}
+	}
+	return prefs, nil
+}
+
+// CrossedOffsets returns the offsets whose reminder time (due minus offset)
+// has passed, smallest first.
+func CrossedOffsets(offsets []time.Duration, due, now time.Time) []time.Duration {
+	var crossed []time.Duration
+	for _, offset := range offsets {
+		if !now.Before(due.Add(-offset)) {
+			crossed = append(crossed, offset)
+		}
+	}
+	sort.Slice(crossed, func(i, j int) bool { return crossed[i] < crossed[j] })
+	return crossed
+}
+
+// ParseOffsets parses the stored comma separated list of minutes.
+func ParseOffsets(s string) ([]time.Duration, error) {
+	var offsets []time.Duration
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		minutes, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, time.Duration(minutes)*time.Minute)
+	}
+	return offsets, nil
+}
+
+// FormatOffsets is the inverse of ParseOffsets.
+func FormatOffsets(offsets []time.Duration) string {
+	items := make([]string, len(offsets))
+	for i, offset := range offsets {
+		items[i] = strconv.Itoa(int(offset / time.Minute))
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,197 @@
+package reminders
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"kanban_server/config"
+	"kanban_server/models"
+
+	"gorm.io/gorm/logger"
+)
+
+func TestCrossedOffsets(t *testing.T) {
+	due := time.Date(2025, 5, 10, 17, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{24 * time.Hour, time.Hour, 15 * time.Minute}
+
+	tests := []struct {
+		now  time.Time
+		want []time.Duration
+	}{
+		{due.Add(-48 * time.Hour), nil},
+		{due.Add(-24 * time.Hour), []time.Duration{24 * time.Hour}},
+		{due.Add(-30 * time.Minute), []time.Duration{time.Hour, 24 * time.Hour}},
+		{due.Add(-time.Minute), []time.Duration{15 * time.Minute, time.Hour, 24 * time.Hour}},
+	}
+	for _, tt := range tests {
+		if got := CrossedOffsets(offsets, due, tt.now); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CrossedOffsets at %s = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestOffsetsRoundTrip(t *testing.T) {
+	offsets := []time.Duration{48 * time.Hour, 90 * time.Minute}
+	stored := FormatOffsets(offsets)
+	if stored != "2880,90" {
+		t.Errorf("FormatOffsets = %q", stored)
+	}
+	parsed, err := ParseOffsets(stored)
+	if err != nil || !reflect.DeepEqual(parsed, offsets) {
+		t.Errorf("ParseOffsets(%q) = %v, %v", stored, parsed, err)
+	}
+	if _, err := ParseOffsets("60,soon"); err == nil {
+		t.Error("expected error for malformed offsets")
+	}
+}
+
+func TestMultiNotifier(t *testing.T) {
+	a, b := &MemoryNotifier{}, &MemoryNotifier{}
+	n := Notification{UserID: 1, TaskID: 2, Kind: KindOverdue}
+	if err := (MultiNotifier{a, b}).Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Sent()) != 1 || len(b.Sent()) != 1 || a.Sent()[0] != n {
+		t.Errorf("notifications not fanned out: %v %v", a.Sent(), b.Sent())
+	}
+}
+
+// newTestWorker returns a worker on a fresh SQLite database holding a
+// project with an assignee and another member, and a task assigned to the
+// first due at due. The worker's clock is read from *now.
+func newTestWorker(t *testing.T, due time.Time, now *time.Time) (*Worker, *MemoryNotifier, models.Task) {
+	t.Helper()
+	db, err := config.Open(config.DriverSQLite, filepath.Join(t.TempDir(), "kanban.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.Logger = logger.Discard
+
+	assignee := models.User{Email: "assignee@example.com", Name: "Assignee"}
+	member := models.User{Email: "member@example.com", Name: "Member"}
+	project := models.Project{Title: "Launch", Users: []models.User{assignee, member}}
+	if err := db.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	task := models.Task{Title: "Ship it", DueDate: due, ProjectID: project.ID, AssignedTo: project.Users[0].ID}
+	if err := db.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := &MemoryNotifier{}
+	w := NewWorker(db, notifier, time.Minute, 2*time.Hour)
+	w.now = func() time.Time { return *now }
+	return w, notifier, task
+}
+
+// tick runs the worker at the given time and returns the reminders it sent.
+func tick(t *testing.T, w *Worker, notifier *MemoryNotifier, now *time.Time, at time.Time) []Notification {
+	t.Helper()
+	before := len(notifier.Sent())
+	*now = at
+	if err := w.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return notifier.Sent()[before:]
+}
+
+func TestWorkerDueSoon(t *testing.T) {
+	due := time.Date(2025, 5, 10, 17, 0, 0, 0, time.UTC)
+	var now time.Time
+	w, notifier, task := newTestWorker(t, due, &now)
+
+	sent := tick(t, w, notifier, &now, due.Add(-30*time.Hour))
+	if len(sent) != 0 {
+		t.Errorf("sent %v before any offset", sent)
+	}
+
+	sent = tick(t, w, notifier, &now, due.Add(-23*time.Hour))
+	if len(sent) != 1 || sent[0].Kind != KindDueSoon || sent[0].Offset != 24*time.Hour || sent[0].UserID != task.AssignedTo {
+		t.Errorf("sent %v, want the 24h reminder to the assignee", sent)
+	}
+	if sent = tick(t, w, notifier, &now, due.Add(-22*time.Hour)); len(sent) != 0 {
+		t.Errorf("sent %v again on the next tick", sent)
+	}
+
+	sent = tick(t, w, notifier, &now, due.Add(-30*time.Minute))
+	if len(sent) != 1 || sent[0].Offset != time.Hour {
+		t.Errorf("sent %v, want the 1h reminder", sent)
+	}
+	if sent = tick(t, w, notifier, &now, due.Add(-time.Minute)); len(sent) != 0 {
+		t.Errorf("sent %v again on the next tick", sent)
+	}
+}
+
+func TestWorkerOverdueAndEscalation(t *testing.T) {
+	due := time.Date(2025, 5, 10, 17, 0, 0, 0, time.UTC)
+	var now time.Time
+	w, notifier, task := newTestWorker(t, due, &now)
+
+	sent := tick(t, w, notifier, &now, due.Add(time.Minute))
+	if len(sent) != 1 || sent[0].Kind != KindOverdue || sent[0].UserID != task.AssignedTo {
+		t.Errorf("sent %v, want the overdue reminder to the assignee", sent)
+	}
+	if sent = tick(t, w, notifier, &now, due.Add(time.Hour)); len(sent) != 0 {
+		t.Errorf("sent %v again on the next tick", sent)
+	}
+
+	sent = tick(t, w, notifier, &now, due.Add(2*time.Hour))
+	if len(sent) != 1 || sent[0].Kind != KindEscalation || sent[0].UserID == task.AssignedTo {
+		t.Errorf("sent %v, want the escalation to the other member", sent)
+	}
+	if sent = tick(t, w, notifier, &now, due.Add(3*time.Hour)); len(sent) != 0 {
+		t.Errorf("sent %v again on the next tick", sent)
+	}
+
+	if err := w.db.First(&task, task.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !task.Overdue || !task.Escalated {
+		t.Errorf("task overdue = %v, escalated = %v, want both set", task.Overdue, task.Escalated)
+	}
+
+	// Rescheduling clears the flags and re-arms the reminders.
+	if err := w.db.Model(&task).Update("due_date", due.Add(4*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	tick(t, w, notifier, &now, due.Add(3*time.Hour+time.Minute))
+	sent = tick(t, w, notifier, &now, due.Add(4*time.Hour+time.Minute))
+	if len(sent) != 1 || sent[0].Kind != KindOverdue {
+		t.Errorf("sent %v after rescheduling, want the overdue reminder", sent)
+	}
+}
+
+func TestWorkerEscalationBackoff(t *testing.T) {
+	due := time.Date(2025, 5, 10, 17, 0, 0, 0, time.UTC)
+	var now time.Time
+	w, notifier, task := newTestWorker(t, due, &now)
+	tick(t, w, notifier, &now, due.Add(time.Minute))
+
+	// Escalations cannot be recorded while the log is missing.
+	if err := w.db.Migrator().DropTable(&models.ReminderLog{}); err != nil {
+		t.Fatal(err)
+	}
+	start := due.Add(2 * time.Hour)
+	tick(t, w, notifier, &now, start)
+	tick(t, w, notifier, &now, start.Add(minRetryDelay))
+	if got := w.retries[task.ID].delay; got != 2*minRetryDelay {
+		t.Errorf("retry delay after two failures = %s, want %s", got, 2*minRetryDelay)
+	}
+
+	if err := w.db.AutoMigrate(&models.ReminderLog{}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := tick(t, w, notifier, &now, start.Add(2*minRetryDelay)); len(sent) != 0 {
+		t.Errorf("sent %v before the retry delay passed", sent)
+	}
+	sent := tick(t, w, notifier, &now, start.Add(3*minRetryDelay))
+	if len(sent) != 1 || sent[0].Kind != KindEscalation {
+		t.Errorf("sent %v, want the escalation once the retry delay passed", sent)
+	}
+	if _, ok := w.retries[task.ID]; ok {
+		t.Error("retry kept after the escalation succeeded")
+	}
+}
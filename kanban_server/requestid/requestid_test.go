@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "generated", incoming: ""},
+		{name: "adopted", incoming: "abc-123", keep: true},
+		{name: "too long", incoming: strings.Repeat("a", maxLength+1)},
+		{name: "control characters", incoming: "abc\x1b[31m"},
+		{name: "spaces", incoming: "a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = FromContext(r.Context())
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(Header, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(Header); got != seen || seen == "" {
+				t.Fatalf("header %q, context %q: want the same non-empty ID", got, seen)
+			}
+			if tt.keep && seen != tt.incoming {
+				t.Errorf("got ID %q, want the client's %q", seen, tt.incoming)
+			}
+			if !tt.keep && (seen == tt.incoming || len(seen) != 32) {
+				t.Errorf("got ID %q, want a generated one", seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Package rpc serves the gRPC API defined in proto/kanban/v1. It is a thin
+// layer over the service package, which the REST and GraphQL APIs share, and
+// like GraphQL it only shows callers the projects they are members of.
+package rpc
+
+//go:generate sh -c "cd .. && buf generate"
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"kanban_server/service"
+
+	kanbanv1 "kanban_server/proto/kanban/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator validates the token sent in the "authorization" metadata
+// and returns the user ID.
+type Authenticator func(token string) (uint, error)
+
+// NewServer returns a gRPC server with the kanban service registered.
+func NewServer(svc *service.Service, authenticate Authenticator, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryAuth(authenticate)),
+		grpc.ChainStreamInterceptor(streamAuth(authenticate)),
+	}, opts...)
+	server := grpc.NewServer(opts...)
+	kanbanv1.RegisterKanbanServiceServer(server, &Server{svc: svc})
+	return server
+}
+
+type contextKey struct{}
+
+func withUserID(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+func currentUserID(ctx context.Context) uint {
+	userID, _ := ctx.Value(contextKey{}).(uint)
+	return userID
+}
+
+func authenticateContext(ctx context.Context, authenticate Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokens := md.Get("authorization")
+	if len(tokens) == 0 || tokens[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+	userID, err := authenticate(tokens[0])
+	if err != nil || userID == 0 {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return withUserID(ctx, userID), nil
+}
+
+func unaryAuth(authenticate Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateContext(ctx, authenticate)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(authenticate Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateContext(ss.Context(), authenticate)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// EndStreams returns an option that ends every open stream, such as
+// WatchProject, with Unavailable once stop is done. GracefulStop waits for
+// streams, which would otherwise run until the client leaves.
+func EndStreams(stop context.Context) grpc.ServerOption {
+	return grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+		defer context.AfterFunc(stop, cancel)()
+
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		if stop.Err() != nil {
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+		return err
+	})
+}
+
+// contextStream replaces the context of a stream, e.g. to carry the
+// caller's user ID.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+var (
+	errProjectNotFound = status.Error(codes.NotFound, service.ErrProjectNotFound.Error())
+	errTaskNotFound    = status.Error(codes.NotFound, service.ErrTaskNotFound.Error())
+)
+
+// toStatus maps a service error onto a gRPC status. Unexpected errors are
+// logged and not shown to the caller.
+func toStatus(err error) error {
+	var invalid *service.ValidationError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &invalid):
+		return status.Error(codes.InvalidArgument, invalid.Error())
+	case errors.Is(err, service.ErrProjectNotFound):
+		return errProjectNotFound
+	case errors.Is(err, service.ErrTaskNotFound):
+		return errTaskNotFound
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	log.Println("gRPC request failed:", err)
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"kanban_server/events"
+	"kanban_server/models"
+	"kanban_server/service"
+	"kanban_server/store/memstore"
+
+	kanbanv1 "kanban_server/proto/kanban/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func dial(t *testing.T, authenticate Authenticator) kanbanv1.KanbanServiceClient {
+	t.Helper()
+	return dialService(t, &service.Service{}, authenticate)
+}
+
+func dialService(t *testing.T, svc *service.Service, authenticate Authenticator, opts ...grpc.ServerOption) kanbanv1.KanbanServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(svc, authenticate, opts...)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return kanbanv1.NewKanbanServiceClient(conn)
+}
+
+func TestAuthentication(t *testing.T) {
+	client := dial(t, func(token string) (uint, error) {
+		if token == "Bearer expired" {
+			return 0, errors.New("token expired")
+		}
+		return 0, nil
+	})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "missing token"},
+		{name: "rejected token", token: "Bearer expired"},
+		{name: "token without a user", token: "Bearer anonymous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if tt.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.token)
+			}
+
+			_, err := client.ListProjects(ctx, &kanbanv1.ListProjectsRequest{})
+			if code := status.Code(err); code != codes.Unauthenticated {
+				t.Errorf("ListProjects code = %v, want Unauthenticated", code)
+			}
+
+			stream, err := client.WatchProject(ctx, &kanbanv1.WatchProjectRequest{ProjectId: 1})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if code := status.Code(err); code != codes.Unauthenticated {
+				t.Errorf("WatchProject code = %v, want Unauthenticated", code)
+			}
+		})
+	}
+}
+
+func TestEndStreams(t *testing.T) {
+	repos := memstore.New()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user := models.User{Email: "ada@example.com", Password: "password123", Name: "Ada"}
+	if err := repos.CreateUser(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	project := models.Project{Title: "Board"}
+	if err := repos.CreateProject(ctx, &project, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	stop, stopStreams := context.WithCancel(context.Background())
+	bus := events.NewBus()
+	svc := &service.Service{Users: repos, Projects: repos, Tasks: repos, Bus: bus}
+	client := dialService(t, svc, func(string) (uint, error) { return user.ID, nil }, EndStreams(stop))
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer ada")
+	stream, err := client.WatchProject(ctx, &kanbanv1.WatchProjectRequest{ProjectId: uint64(project.ID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Publish until the watch has subscribed and an event arrives.
+	received := make(chan struct{})
+	go func() {
+		for {
+			bus.Publish(events.New(events.TaskCreated, project.ID, user.ID, models.Task{Title: "Ship"}))
+			select {
+			case <-received:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	_, err = stream.Recv()
+	close(received)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopStreams()
+	// Events already queued may still arrive before the stream ends.
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Recv after stopping = %v, want Unavailable", err)
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{err: &service.ValidationError{Field: "title", Reason: "is required"}, code: codes.InvalidArgument, message: "title is required"},
+		{err: fmt.Errorf("loading: %w", service.ErrProjectNotFound), code: codes.NotFound, message: "project not found"},
+		{err: service.ErrTaskNotFound, code: codes.NotFound, message: "task not found"},
+		{err: context.DeadlineExceeded, code: codes.DeadlineExceeded},
+		{err: errors.New("connection refused"), code: codes.Internal, message: "internal error"},
+	}
+	for _, tt := range tests {
+		got := status.Convert(toStatus(tt.err))
+		if got.Code() != tt.code {
+			t.Errorf("toStatus(%v) code = %v, want %v", tt.err, got.Code(), tt.code)
+		}
+		if tt.message != "" && got.Message() != tt.message {
+			t.Errorf("toStatus(%v) message = %q, want %q", tt.err, got.Message(), tt.message)
+		}
+	}
+	if toStatus(nil) != nil {
+		t.Error("toStatus(nil) is not nil")
+	}
+}
+
+func TestEventToProto(t *testing.T) {
+	e := events.New(events.TaskCreated, 3, 9, models.Task{ID: 5, Title: "Ship"})
+	msg, err := eventToProto(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.GetId() != e.ID || msg.GetType() != events.TaskCreated || msg.GetProjectId() != 3 || msg.GetActorId() != 9 {
+		t.Errorf("eventToProto() = %v", msg)
+	}
+	fields := msg.GetData().GetFields()
+	if fields["title"].GetStringValue() != "Ship" || fields["id"].GetNumberValue() != 5 {
+		t.Errorf("eventToProto() data = %v", msg.GetData())
+	}
+
+	msg, err = eventToProto(events.New(events.ProjectDeleted, 3, 9, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Data != nil {
+		t.Errorf("eventToProto() data = %v, want none", msg.Data)
+	}
+}
@@ -0,0 +1,283 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"kanban_server/events"
+	"kanban_server/models"
+	"kanban_server/service"
+
+	kanbanv1 "kanban_server/proto/kanban/v1"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements kanbanv1.KanbanServiceServer.
+type Server struct {
+	kanbanv1.UnimplementedKanbanServiceServer
+	svc *service.Service
+}
+
+// findProject loads a project the caller is a member of.
+func (s *Server) findProject(ctx context.Context, id uint64) (models.Project, error) {
+	project, err := s.svc.FindMemberProject(ctx, currentUserID(ctx), uint(id))
+	return project, toStatus(err)
+}
+
+// findTask loads a task in a project the caller is a member of.
+func (s *Server) findTask(ctx context.Context, id uint64) (models.Task, error) {
+	task, err := s.svc.FindMemberTask(ctx, currentUserID(ctx), uint(id))
+	return task, toStatus(err)
+}
+
+func (s *Server) ListProjects(ctx context.Context, req *kanbanv1.ListProjectsRequest) (*kanbanv1.ListProjectsResponse, error) {
+	projects, err := s.svc.ListProjects(ctx, currentUserID(ctx), req.GetStatus())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &kanbanv1.ListProjectsResponse{Projects: make([]*kanbanv1.Project, len(projects))}
+	for i, project := range projects {
+		resp.Projects[i] = projectToProto(project)
+	}
+	return resp, nil
+}
+
+func (s *Server) GetProject(ctx context.Context, req *kanbanv1.GetProjectRequest) (*kanbanv1.Project, error) {
+	project, err := s.findProject(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return projectToProto(project), nil
+}
+
+func (s *Server) CreateProject(ctx context.Context, req *kanbanv1.CreateProjectRequest) (*kanbanv1.Project, error) {
+	project, err := s.svc.CreateProject(ctx, currentUserID(ctx), service.NewProject{
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return projectToProto(project), nil
+}
+
+func (s *Server) UpdateProject(ctx context.Context, req *kanbanv1.UpdateProjectRequest) (*kanbanv1.Project, error) {
+	project, err := s.findProject(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	updates, err := service.ProjectChanges{
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      req.Status,
+	}.Updates()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	project, err = s.svc.UpdateProject(ctx, currentUserID(ctx), project, updates)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return projectToProto(project), nil
+}
+
+func (s *Server) DeleteProject(ctx context.Context, req *kanbanv1.DeleteProjectRequest) (*emptypb.Empty, error) {
+	project, err := s.findProject(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.svc.DeleteProject(ctx, currentUserID(ctx), project); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ListTasks(ctx context.Context, req *kanbanv1.ListTasksRequest) (*kanbanv1.ListTasksResponse, error) {
+	project, err := s.findProject(ctx, req.GetProjectId())
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.svc.ListTasks(ctx, project.ID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &kanbanv1.ListTasksResponse{Tasks: make([]*kanbanv1.Task, len(tasks))}
+	for i, task := range tasks {
+		resp.Tasks[i] = taskToProto(task)
+	}
+	return resp, nil
+}
+
+func (s *Server) GetTask(ctx context.Context, req *kanbanv1.GetTaskRequest) (*kanbanv1.Task, error) {
+	task, err := s.findTask(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return taskToProto(task), nil
+}
+
+func (s *Server) CreateTask(ctx context.Context, req *kanbanv1.CreateTaskRequest) (*kanbanv1.Task, error) {
+	project, err := s.findProject(ctx, req.GetProjectId())
+	if err != nil {
+		return nil, err
+	}
+
+	in := service.NewTask{
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Status:      req.GetStatus(),
+		Priority:    req.GetPriority(),
+		AssignedTo:  uint(req.GetAssigneeId()),
+	}
+	if req.DueDate != nil {
+		in.DueDate = req.DueDate.AsTime()
+	}
+	if req.GetColumnId() != 0 {
+		columnID := uint(req.GetColumnId())
+		in.ColumnID = &columnID
+	}
+	for _, id := range req.GetLabelIds() {
+		in.LabelIDs = append(in.LabelIDs, uint(id))
+	}
+
+	task, err := s.svc.CreateTask(ctx, currentUserID(ctx), project.ID, in)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return taskToProto(task), nil
+}
+
+func (s *Server) UpdateTask(ctx context.Context, req *kanbanv1.UpdateTaskRequest) (*kanbanv1.Task, error) {
+	task, err := s.findTask(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	changes := service.TaskChanges{
+		Title:        req.Title,
+		Description:  req.Description,
+		Status:       req.Status,
+		Priority:     req.Priority,
+		ClearDueDate: req.GetClearDueDate(),
+		Archived:     req.Archived,
+	}
+	if req.DueDate != nil {
+		dueDate := req.DueDate.AsTime()
+		changes.DueDate = &dueDate
+	}
+	if req.AssigneeId != nil {
+		assignee := uint(req.GetAssigneeId())
+		changes.AssignedTo = &assignee
+	}
+	if req.ColumnId != nil {
+		columnID := uint(req.GetColumnId())
+		changes.ColumnID = &columnID
+	}
+	updates, err := changes.Updates()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	task, err = s.svc.UpdateTask(ctx, currentUserID(ctx), task, updates)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return taskToProto(task), nil
+}
+
+func (s *Server) DeleteTask(ctx context.Context, req *kanbanv1.DeleteTaskRequest) (*emptypb.Empty, error) {
+	task, err := s.findTask(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.svc.DeleteTask(ctx, currentUserID(ctx), task); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) WatchProject(req *kanbanv1.WatchProjectRequest, stream kanbanv1.KanbanService_WatchProjectServer) error {
+	ctx := stream.Context()
+	project, err := s.findProject(ctx, req.GetProjectId())
+	if err != nil {
+		return err
+	}
+	for e := range s.svc.Watch(ctx, project.ID, currentUserID(ctx)) {
+		msg, err := eventToProto(e)
+		if err != nil {
+			return toStatus(err)
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func projectToProto(project models.Project) *kanbanv1.Project {
+	return &kanbanv1.Project{
+		Id:          uint64(project.ID),
+		Title:       project.Title,
+		Description: project.Description,
+		Status:      project.Status,
+		CreatedAt:   timestamp(project.CreatedAt),
+		UpdatedAt:   timestamp(project.UpdatedAt),
+	}
+}
+
+func taskToProto(task models.Task) *kanbanv1.Task {
+	msg := &kanbanv1.Task{
+		Id:          uint64(task.ID),
+		ProjectId:   uint64(task.ProjectID),
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		Priority:    task.Priority,
+		DueDate:     timestamp(task.DueDate),
+		AssigneeId:  uint64(task.AssignedTo),
+		Archived:    task.Archived,
+		Overdue:     task.Overdue,
+		CreatedAt:   timestamp(task.CreatedAt),
+		UpdatedAt:   timestamp(task.UpdatedAt),
+	}
+	if task.ColumnID != nil {
+		msg.ColumnId = uint64(*task.ColumnID)
+	}
+	return msg
+}
+
+// eventToProto converts e, carrying its data in the same JSON form that
+// webhooks receive.
+func eventToProto(e events.Event) (*kanbanv1.ProjectEvent, error) {
+	msg := &kanbanv1.ProjectEvent{
+		Id:         e.ID,
+		Type:       e.Type,
+		ProjectId:  uint64(e.ProjectID),
+		ActorId:    uint64(e.ActorID),
+		OccurredAt: timestamp(e.OccurredAt),
+	}
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 && data[0] == '{' {
+		msg.Data = &structpb.Struct{}
+		if err := protojson.Unmarshal(data, msg.Data); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// timestamp converts t, leaving the zero time unset.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"kanban_server/events"
+	"kanban_server/models"
+	"kanban_server/notifications"
+)
+
+// MaxCommentLength is the longest comment body accepted, in characters.
+const MaxCommentLength = 10000
+
+// AddComment adds the actor's comment to a task. Project members mentioned
+// in the body are notified, and so is the assignee if they were not
+// mentioned.
+func (s *Service) AddComment(ctx context.Context, actorID uint, task models.Task, body string) (models.Comment, error) {
+	comment := models.Comment{TaskID: task.ID, UserID: actorID, Body: strings.TrimSpace(body)}
+	if comment.Body == "" {
+		return comment, invalid("body", "is required")
+	}
+	if utf8.RuneCountInString(comment.Body) > MaxCommentLength {
+		return comment, invalid("body", "must be at most %d characters", MaxCommentLength)
+	}
+	if err := s.Tasks.CreateComment(ctx, &comment); err != nil {
+		return comment, err
+	}
+
+	var inbox []models.Notification
+	notified := make(map[uint]bool)
+	if handles := notifications.ParseMentions(comment.Body); len(handles) > 0 {
+		if members, err := s.Projects.ListMembers(ctx, task.ProjectID); err == nil {
+			for _, user := range notifications.MatchMentions(handles, members) {
+				notified[user.ID] = true
+				inbox = append(inbox, commentNotification(task, comment, user.ID, notifications.TypeMention,
+					fmt.Sprintf("You were mentioned on %q", task.Title)))
+			}
+		}
+	}
+	if task.AssignedTo != 0 && !notified[task.AssignedTo] {
+		inbox = append(inbox, commentNotification(task, comment, task.AssignedTo, notifications.TypeComment,
+			fmt.Sprintf("New comment on %q", task.Title)))
+	}
+	s.publish(events.CommentCreated, task.ProjectID, actorID, comment)
+	s.notify(ctx, actorID, inbox...)
+	return comment, nil
+}
+
+func commentNotification(task models.Task, comment models.Comment, userID uint, kind, message string) models.Notification {
+	return models.Notification{
+		UserID:    userID,
+		Type:      kind,
+		Message:   message,
+		ProjectID: task.ProjectID,
+		TaskID:    task.ID,
+		CommentID: comment.ID,
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"kanban_server/events"
+	"kanban_server/models"
+	"kanban_server/notifications"
+)
+
+// FindUser loads a user by ID.
+func (s *Service) FindUser(ctx context.Context, id uint) (models.User, error) {
+	user, err := s.Users.FindUser(ctx, id)
+	return user, notFound(err, ErrUserNotFound)
+}
+
+// FindUserByEmail loads a user by email address.
+func (s *Service) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
+	user, err := s.Users.FindUserByEmail(ctx, email)
+	return user, notFound(err, ErrUserNotFound)
+}
+
+// AddMember adds user to the project and tells them about it. Adding a
+// member again fails with ErrAlreadyMember.
+func (s *Service) AddMember(ctx context.Context, actorID uint, project models.Project, user models.User) error {
+	member, err := s.Projects.IsMember(ctx, project.ID, user.ID)
+	if err != nil {
+		return err
+	}
+	if member {
+		return ErrAlreadyMember
+	}
+	if err := s.Projects.AddMember(ctx, project.ID, user.ID); err != nil {
+		return err
+	}
+	s.publish(events.MemberAdded, project.ID, actorID, user)
+	s.notify(ctx, actorID, models.Notification{
+		UserID:    user.ID,
+		Type:      notifications.TypeMemberAdded,
+		Message:   fmt.Sprintf("You were added to %q", project.Title),
+		ProjectID: project.ID,
+	})
+	return nil
+}
+
+// RemoveMember removes a user from the project, tells them about it and
+// returns them. Users who are not members are reported as ErrMemberNotFound.
+func (s *Service) RemoveMember(ctx context.Context, actorID uint, project models.Project, userID uint) (models.User, error) {
+	member, err := s.Projects.IsMember(ctx, project.ID, userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !member {
+		return models.User{}, ErrMemberNotFound
+	}
+	user, err := s.Users.FindUser(ctx, userID)
+	if err != nil {
+		return user, notFound(err, ErrMemberNotFound)
+	}
+	if err := s.Projects.RemoveMember(ctx, project.ID, user.ID); err != nil {
+		return user, err
+	}
+	s.publish(events.MemberRemoved, project.ID, actorID, user)
+	s.notify(ctx, actorID, models.Notification{
+		UserID:    user.ID,
+		Type:      notifications.TypeMemberRemoved,
+		Message:   fmt.Sprintf("You were removed from %q", project.Title),
+		ProjectID: project.ID,
+	})
+	return user, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"kanban_server/events"
+	"kanban_server/models"
+	"kanban_server/store"
+)
+
+// NewProject describes a project to create.
+type NewProject struct {
+	Title       string
+	Description string
+}
+
+// ProjectChanges lists the members of a project to change. Nil members are
+// left as they are.
+type ProjectChanges struct {
+	Title       *string
+	Description *string
+	Status      *string
+}
+
+// Updates validates c and returns the column updates for UpdateProject.
+func (c ProjectChanges) Updates() (map[string]interface{}, error) {
+	updates := map[string]interface{}{}
+	if c.Title != nil {
+		if strings.TrimSpace(*c.Title) == "" {
+			return nil, invalid("title", "must not be empty")
+		}
+		updates["title"] = *c.Title
+	}
+	if c.Description != nil {
+		updates["description"] = *c.Description
+	}
+	if c.Status != nil {
+		if err := oneOf("status", *c.Status, models.ProjectStatuses); err != nil {
+			return nil, err
+		}
+		updates["status"] = *c.Status
+	}
+	return updates, nil
+}
+
+// ListProjects returns the projects userID is a member of, optionally only
+// those with the given status.
+func (s *Service) ListProjects(ctx context.Context, userID uint, status string) ([]models.Project, error) {
+	return s.Projects.ListProjects(ctx, store.ProjectFilter{MemberID: userID, Status: status})
+}
+
+// FindProject loads a project by ID.
+func (s *Service) FindProject(ctx context.Context, id uint) (models.Project, error) {
+	project, err := s.Projects.FindProject(ctx, id)
+	return project, notFound(err, ErrProjectNotFound)
+}
+
+// CreateProject creates an active project with the actor as its first
+// member.
+func (s *Service) CreateProject(ctx context.Context, actorID uint, in NewProject) (models.Project, error) {
+	project := models.Project{
+		Title:       strings.TrimSpace(in.Title),
+		Description: in.Description,
+		Status:      models.ProjectStatusActive,
+	}
+	if project.Title == "" {
+		return project, invalid("title", "is required")
+	}
+	err := s.Projects.CreateProject(ctx, &project, actorID)
+	return project, err
+}
+
+// UpdateProject applies validated column updates, as returned by
+// ProjectChanges.Updates, and returns the stored project.
+func (s *Service) UpdateProject(ctx context.Context, actorID uint, project models.Project, updates map[string]interface{}) (models.Project, error) {
+	if len(updates) == 0 {
+		return project, nil
+	}
+	if err := s.Projects.UpdateProject(ctx, &project, updates); err != nil {
+		return project, notFound(err, ErrProjectNotFound)
+	}
+	s.publish(events.ProjectUpdated, project.ID, actorID, project)
+	return project, nil
+}
+
+// DeleteProject deletes a project along with its tasks.
+func (s *Service) DeleteProject(ctx context.Context, actorID uint, project models.Project) error {
+	if err := s.Projects.DeleteProject(ctx, project.ID); err != nil {
+		return err
+	}
+	s.publish(events.ProjectDeleted, project.ID, actorID, project)
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package service holds the project, task, comment and member operations
+// shared by the REST, GraphQL and gRPC APIs: validating input, writing it,
+// and publishing the events and notifications a change causes. Who may see a
+// project is decided here as well: Authorize, FindMemberProject and
+// FindMemberTask admit project members only, so that the three APIs enforce
+// the same rule.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"kanban_server/events"
+	"kanban_server/models"
+	"kanban_server/notifications"
+	"kanban_server/store"
+)
+
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrMemberNotFound  = errors.New("member not found")
+	ErrAlreadyMember   = errors.New("user is already a member of this project")
+)
+
+// ValidationError reports input that breaks a rule. Its message is meant for
+// the caller, e.g. "title is required".
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+func invalid(field, reason string, args ...interface{}) error {
+	return &ValidationError{Field: field, Reason: fmt.Sprintf(reason, args...)}
+}
+
+// Service performs changes on behalf of an actor, the ID of the user making
+// them.
+type Service struct {
+	Users    store.Users
+	Projects store.Projects
+	Tasks    store.Tasks
+	Bus      *events.Bus
+	// Notify delivers in-app notifications caused by actorID.
+	Notify func(ctx context.Context, actorID uint, ns ...models.Notification)
+}
+
+func (s *Service) publish(eventType string, projectID, actorID uint, data interface{}) {
+	if s.Bus != nil {
+		s.Bus.Publish(events.New(eventType, projectID, actorID, data))
+	}
+}
+
+func (s *Service) notify(ctx context.Context, actorID uint, ns ...models.Notification) {
+	if s.Notify != nil && len(ns) > 0 {
+		s.Notify(ctx, actorID, ns...)
+	}
+}
+
+// IsMember reports whether userID belongs to the project.
+func (s *Service) IsMember(ctx context.Context, projectID, userID uint) (bool, error) {
+	return s.Projects.IsMember(ctx, projectID, userID)
+}
+
+// Authorize returns ErrProjectNotFound unless userID is a member of the
+// project. Non-members get the same error as for a missing project, so that
+// they cannot probe for project IDs.
+func (s *Service) Authorize(ctx context.Context, userID, projectID uint) error {
+	member, err := s.Projects.IsMember(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// FindMemberProject loads a project userID is a member of.
+func (s *Service) FindMemberProject(ctx context.Context, userID, id uint) (models.Project, error) {
+	if err := s.Authorize(ctx, userID, id); err != nil {
+		return models.Project{}, err
+	}
+	return s.FindProject(ctx, id)
+}
+
+// FindMemberTask loads a task in a project userID is a member of. Tasks of
+// other projects are reported as ErrTaskNotFound.
+func (s *Service) FindMemberTask(ctx context.Context, userID, id uint) (models.Task, error) {
+	task, err := s.FindTask(ctx, id)
+	if err != nil {
+		return models.Task{}, err
+	}
+	err = s.Authorize(ctx, userID, task.ProjectID)
+	if errors.Is(err, ErrProjectNotFound) {
+		err = ErrTaskNotFound
+	}
+	if err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+// AssignmentNotification tells the assignee of task about it.
+func AssignmentNotification(task models.Task) models.Notification {
+	return models.Notification{
+		UserID:    task.AssignedTo,
+		Type:      notifications.TypeAssigned,
+		Message:   fmt.Sprintf("You were assigned %q", task.Title),
+		ProjectID: task.ProjectID,
+		TaskID:    task.ID,
+	}
+}
+
+func oneOf(field, value string, allowed []string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return invalid(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// notFound maps store.ErrNotFound to err.
+func notFound(dbErr, err error) error {
+	if errors.Is(dbErr, store.ErrNotFound) {
+		return err
+	}
+	return dbErr
+}
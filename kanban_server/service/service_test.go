@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"kanban_server/events"
+	"kanban_server/models"
+	"kanban_server/notifications"
+	"kanban_server/store/memstore"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestTaskChangesUpdates(t *testing.T) {
+	due := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		changes TaskChanges
+		want    map[string]interface{}
+		field   string
+	}{
+		{
+			name:    "no changes",
+			changes: TaskChanges{},
+			want:    map[string]interface{}{},
+		},
+		{
+			name: "every member",
+			changes: TaskChanges{
+				Title:       ptr("Ship"),
+				Description: ptr(""),
+				Status:      ptr(models.TaskStatusDone),
+				Priority:    ptr(models.TaskPriorityHigh),
+				DueDate:     &due,
+				AssignedTo:  ptr(uint(3)),
+				ColumnID:    ptr(uint(7)),
+				Archived:    ptr(true),
+			},
+			want: map[string]interface{}{
+				"title":       "Ship",
+				"description": "",
+				"status":      models.TaskStatusDone,
+				"priority":    models.TaskPriorityHigh,
+				"due_date":    due,
+				"assigned_to": uint(3),
+				"column_id":   ptr(uint(7)),
+				"archived":    true,
+			},
+		},
+		{
+			name:    "zero column and cleared due date",
+			changes: TaskChanges{ColumnID: ptr(uint(0)), ClearDueDate: true},
+			want:    map[string]interface{}{"column_id": (*uint)(nil), "due_date": time.Time{}},
+		},
+		{name: "blank title", changes: TaskChanges{Title: ptr("  ")}, field: "title"},
+		{name: "unknown status", changes: TaskChanges{Status: ptr("blocked")}, field: "status"},
+		{name: "unknown priority", changes: TaskChanges{Priority: ptr("urgent")}, field: "priority"},
+		{name: "set and clear due date", changes: TaskChanges{DueDate: &due, ClearDueDate: true}, field: "due date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.changes.Updates()
+			if tt.field != "" {
+				var invalid *ValidationError
+				if !errors.As(err, &invalid) || invalid.Field != tt.field {
+					t.Fatalf("Updates() error = %v, want a validation error for %s", err, tt.field)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Updates() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Updates() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectChangesUpdates(t *testing.T) {
+	got, err := ProjectChanges{Title: ptr("Launch"), Status: ptr(models.ProjectStatusOnHold)}.Updates()
+	if err != nil {
+		t.Fatalf("Updates() error = %v", err)
+	}
+	want := map[string]interface{}{"title": "Launch", "status": models.ProjectStatusOnHold}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Updates() = %#v, want %#v", got, want)
+	}
+
+	if _, err := (ProjectChanges{Status: ptr("paused")}).Updates(); err == nil || err.Error() != "status must be one of active, on_hold, completed, archived" {
+		t.Errorf("Updates() with unknown status error = %v", err)
+	}
+}
+
+func TestFindMember(t *testing.T) {
+	repos := memstore.New()
+	svc := &Service{Users: repos, Projects: repos, Tasks: repos}
+	ctx := context.Background()
+
+	member := models.User{Email: "member@example.com", Password: "password123"}
+	outsider := models.User{Email: "outsider@example.com", Password: "password123"}
+	for _, user := range []*models.User{&member, &outsider} {
+		if err := repos.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := models.Project{Title: "Launch"}
+	if err := repos.CreateProject(ctx, &project, member.ID); err != nil {
+		t.Fatal(err)
+	}
+	task := models.Task{ProjectID: project.ID, Title: "Ship"}
+	if err := repos.CreateTask(ctx, &task); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := svc.FindMemberProject(ctx, member.ID, project.ID); err != nil || got.ID != project.ID {
+		t.Errorf("FindMemberProject as a member = %+v, %v", got, err)
+	}
+	if got, err := svc.FindMemberTask(ctx, member.ID, task.ID); err != nil || got.ID != task.ID {
+		t.Errorf("FindMemberTask as a member = %+v, %v", got, err)
+	}
+	if _, err := svc.FindMemberProject(ctx, outsider.ID, project.ID); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("FindMemberProject as an outsider: error = %v, want ErrProjectNotFound", err)
+	}
+	if _, err := svc.FindMemberProject(ctx, member.ID, project.ID+100); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("FindMemberProject for a missing project: error = %v, want ErrProjectNotFound", err)
+	}
+	if _, err := svc.FindMemberTask(ctx, outsider.ID, task.ID); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("FindMemberTask as an outsider: error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	bus := events.NewBus()
+	s := &Service{Bus: bus}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watched := s.Watch(ctx, 1, 42)
+
+	bus.Publish(events.New(events.TaskCreated, 2, 0, nil))
+	bus.Publish(events.New(events.TaskCreated, 1, 0, nil))
+	bus.Publish(events.New(events.MemberRemoved, 1, 0, models.User{ID: 7}))
+	bus.Publish(events.New(events.MemberRemoved, 1, 0, models.User{ID: 42}))
+	bus.Publish(events.New(events.TaskUpdated, 1, 0, nil))
+
+	var got []string
+	timeout := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case e, ok := <-watched:
+			if !ok {
+				done = true
+				break
+			}
+			got = append(got, e.Type)
+		case <-timeout:
+			t.Fatal("Watch did not end after the watcher was removed")
+		}
+	}
+
+	want := []string{events.TaskCreated, events.MemberRemoved, events.MemberRemoved}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("watched events = %v, want %v", got, want)
+	}
+}
+
+func TestWatchEndsWithContext(t *testing.T) {
+	s := &Service{Bus: events.NewBus()}
+	ctx, cancel := context.WithCancel(context.Background())
+	watched := s.Watch(ctx, 1, 42)
+	cancel()
+
+	select {
+	case _, ok := <-watched:
+		if ok {
+			t.Error("Watch sent an event after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Watch did not end with its context")
+	}
+}
+
+func TestAssigneeMustBeMember(t *testing.T) {
+	repos := memstore.New()
+	svc := &Service{Users: repos, Projects: repos, Tasks: repos}
+	ctx := context.Background()
+
+	member := models.User{Email: "member@example.com", Password: "password123"}
+	outsider := models.User{Email: "outsider@example.com", Password: "password123"}
+	for _, user := range []*models.User{&member, &outsider} {
+		if err := repos.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := models.Project{Title: "Launch"}
+	if err := repos.CreateProject(ctx, &project, member.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var verr *ValidationError
+	_, err := svc.CreateTask(ctx, member.ID, project.ID, NewTask{Title: "Ship", AssignedTo: outsider.ID})
+	if !errors.As(err, &verr) || verr.Field != "assignee" {
+		t.Errorf("CreateTask assigned to an outsider: error = %v, want an assignee error", err)
+	}
+
+	task, err := svc.CreateTask(ctx, member.ID, project.ID, NewTask{Title: "Ship", AssignedTo: member.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.UpdateTask(ctx, member.ID, task, map[string]interface{}{"assigned_to": outsider.ID})
+	if !errors.As(err, &verr) || verr.Field != "assignee" {
+		t.Errorf("UpdateTask assigning an outsider: error = %v, want an assignee error", err)
+	}
+}
+
+func TestAddComment(t *testing.T) {
+	repos := memstore.New()
+	var sent []models.Notification
+	svc := &Service{Users: repos, Projects: repos, Tasks: repos, Notify: func(ctx context.Context, actorID uint, ns ...models.Notification) {
+		sent = append(sent, ns...)
+	}}
+	ctx := context.Background()
+
+	author := models.User{Email: "author@example.com", Password: "password123"}
+	assignee := models.User{Email: "assignee@example.com", Password: "password123"}
+	mentioned := models.User{Email: "ana@example.com", Password: "password123"}
+	for _, user := range []*models.User{&author, &assignee, &mentioned} {
+		if err := repos.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := models.Project{Title: "Launch"}
+	if err := repos.CreateProject(ctx, &project, author.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []models.User{assignee, mentioned} {
+		if err := repos.AddMember(ctx, project.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	task := models.Task{ProjectID: project.ID, Title: "Ship", AssignedTo: assignee.ID}
+	if err := repos.CreateTask(ctx, &task); err != nil {
+		t.Fatal(err)
+	}
+
+	var verr *ValidationError
+	if _, err := svc.AddComment(ctx, author.ID, task, "  "); !errors.As(err, &verr) || verr.Field != "body" {
+		t.Errorf("AddComment with a blank body: error = %v, want a body error", err)
+	}
+
+	comment, err := svc.AddComment(ctx, author.ID, task, " Ready, @ana? ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.Body != "Ready, @ana?" || comment.UserID != author.ID {
+		t.Errorf("comment = %+v", comment)
+	}
+	stored, err := repos.ListComments(ctx, task.ID)
+	if err != nil || len(stored) != 1 || stored[0].ID != comment.ID {
+		t.Errorf("stored comments = %+v, %v", stored, err)
+	}
+
+	got := make(map[uint]string)
+	for _, n := range sent {
+		got[n.UserID] = n.Type
+		if n.CommentID != comment.ID {
+			t.Errorf("notification %+v does not refer to the comment", n)
+		}
+	}
+	want := map[uint]string{mentioned.ID: notifications.TypeMention, assignee.ID: notifications.TypeComment}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("notified %v, want %v", got, want)
+	}
+}
+
+func TestMembers(t *testing.T) {
+	repos := memstore.New()
+	svc := &Service{Users: repos, Projects: repos, Tasks: repos}
+	ctx := context.Background()
+
+	owner := models.User{Email: "owner@example.com", Password: "password123"}
+	user := models.User{Email: "user@example.com", Password: "password123"}
+	for _, u := range []*models.User{&owner, &user} {
+		if err := repos.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := models.Project{Title: "Launch"}
+	if err := repos.CreateProject(ctx, &project, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.FindUserByEmail(ctx, "nobody@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("FindUserByEmail of an unknown address: error = %v, want ErrUserNotFound", err)
+	}
+	if err := svc.AddMember(ctx, owner.ID, project, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AddMember(ctx, owner.ID, project, user); !errors.Is(err, ErrAlreadyMember) {
+		t.Errorf("adding a member again: error = %v, want ErrAlreadyMember", err)
+	}
+	if removed, err := svc.RemoveMember(ctx, owner.ID, project, user.ID); err != nil || removed.ID != user.ID {
+		t.Errorf("RemoveMember = %+v, %v", removed, err)
+	}
+	if _, err := svc.RemoveMember(ctx, owner.ID, project, user.ID); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("removing a non-member: error = %v, want ErrMemberNotFound", err)
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"kanban_server/events"
+	"kanban_server/models"
+)
+
+// NewTask describes a task to create. Empty Status and Priority get the
+// defaults, todo and medium.
+type NewTask struct {
+	Title       string
+	Description string
+	Status      string
+	Priority    string
+	DueDate     time.Time
+	AssignedTo  uint
+	ColumnID    *uint
+	LabelIDs    []uint
+}
+
+// TaskChanges lists the members of a task to change. Nil members are left as
+// they are; AssignedTo 0 unassigns the task and ColumnID 0 takes it out of
+// its column.
+type TaskChanges struct {
+	Title        *string
+	Description  *string
+	Status       *string
+	Priority     *string
+	DueDate      *time.Time
+	ClearDueDate bool
+	AssignedTo   *uint
+	ColumnID     *uint
+	Archived     *bool
+}
+
+// Updates validates c and returns the column updates for UpdateTask.
+func (c TaskChanges) Updates() (map[string]interface{}, error) {
+	updates := map[string]interface{}{}
+	if c.Title != nil {
+		if strings.TrimSpace(*c.Title) == "" {
+			return nil, invalid("title", "must not be empty")
+		}
+		updates["title"] = *c.Title
+	}
+	if c.Description != nil {
+		updates["description"] = *c.Description
+	}
+	if c.Status != nil {
+		if err := oneOf("status", *c.Status, models.TaskStatuses); err != nil {
+			return nil, err
+		}
+		updates["status"] = *c.Status
+	}
+	if c.Priority != nil {
+		if err := oneOf("priority", *c.Priority, models.TaskPriorities); err != nil {
+			return nil, err
+		}
+		updates["priority"] = *c.Priority
+	}
+	if c.DueDate != nil && c.ClearDueDate {
+		return nil, invalid("due date", "cannot be set and cleared at once")
+	}
+	if c.DueDate != nil {
+		updates["due_date"] = *c.DueDate
+	}
+	if c.ClearDueDate {
+		updates["due_date"] = time.Time{}
+	}
+	if c.AssignedTo != nil {
+		updates["assigned_to"] = *c.AssignedTo
+	}
+	if c.ColumnID != nil {
+		var columnID *uint
+		if *c.ColumnID != 0 {
+			columnID = c.ColumnID
+		}
+		updates["column_id"] = columnID
+	}
+	if c.Archived != nil {
+		updates["archived"] = *c.Archived
+	}
+	return updates, nil
+}
+
+// ListTasks returns the tasks of a project.
+func (s *Service) ListTasks(ctx context.Context, projectID uint) ([]models.Task, error) {
+	return s.Tasks.ListTasks(ctx, projectID)
+}
+
+// FindTask loads a task by ID.
+func (s *Service) FindTask(ctx context.Context, id uint) (models.Task, error) {
+	task, err := s.Tasks.FindTask(ctx, id)
+	return task, notFound(err, ErrTaskNotFound)
+}
+
+// CreateTask adds a task to a project and notifies its assignee.
+func (s *Service) CreateTask(ctx context.Context, actorID, projectID uint, in NewTask) (models.Task, error) {
+	task := models.Task{
+		Title:       strings.TrimSpace(in.Title),
+		Description: in.Description,
+		Status:      models.TaskStatusTodo,
+		Priority:    models.TaskPriorityMedium,
+		DueDate:     in.DueDate,
+		ProjectID:   projectID,
+		AssignedTo:  in.AssignedTo,
+	}
+	if task.Title == "" {
+		return task, invalid("title", "is required")
+	}
+	if in.Status != "" {
+		if err := oneOf("status", in.Status, models.TaskStatuses); err != nil {
+			return task, err
+		}
+		task.Status = in.Status
+	}
+	if in.Priority != "" {
+		if err := oneOf("priority", in.Priority, models.TaskPriorities); err != nil {
+			return task, err
+		}
+		task.Priority = in.Priority
+	}
+	if in.ColumnID != nil && *in.ColumnID != 0 {
+		task.ColumnID = in.ColumnID
+	}
+
+	if err := s.checkReferences(ctx, projectID, task.AssignedTo, task.ColumnID); err != nil {
+		return task, err
+	}
+	if len(in.LabelIDs) > 0 {
+		labels, err := s.Projects.FindLabels(ctx, projectID, in.LabelIDs)
+		if err != nil {
+			return task, err
+		}
+		if len(labels) != len(uniqueIDs(in.LabelIDs)) {
+			return task, invalid("labels", "must belong to the project")
+		}
+		task.Labels = labels
+	}
+
+	if err := s.Tasks.CreateTask(ctx, &task); err != nil {
+		return task, err
+	}
+	s.publish(events.TaskCreated, projectID, actorID, task)
+	if task.AssignedTo != 0 {
+		s.notify(ctx, actorID, AssignmentNotification(task))
+	}
+	return task, nil
+}
+
+// UpdateTask applies validated column updates, as returned by
+// TaskChanges.Updates, and returns the stored task. A new assignee is
+// notified.
+func (s *Service) UpdateTask(ctx context.Context, actorID uint, task models.Task, updates map[string]interface{}) (models.Task, error) {
+	if len(updates) == 0 {
+		return task, nil
+	}
+
+	assignee, _ := updates["assigned_to"].(uint)
+	columnID, _ := updates["column_id"].(*uint)
+	if err := s.checkReferences(ctx, task.ProjectID, assignee, columnID); err != nil {
+		return task, err
+	}
+
+	previousAssignee := task.AssignedTo
+	if err := s.Tasks.UpdateTask(ctx, &task, updates); err != nil {
+		return task, notFound(err, ErrTaskNotFound)
+	}
+	s.publish(events.TaskUpdated, task.ProjectID, actorID, task)
+	if task.AssignedTo != previousAssignee && task.AssignedTo != 0 {
+		s.notify(ctx, actorID, AssignmentNotification(task))
+	}
+	return task, nil
+}
+
+// DeleteTask deletes a task.
+func (s *Service) DeleteTask(ctx context.Context, actorID uint, task models.Task) error {
+	if err := s.Tasks.DeleteTask(ctx, task.ID); err != nil {
+		return err
+	}
+	s.publish(events.TaskDeleted, task.ProjectID, actorID, task)
+	return nil
+}
+
+// checkReferences verifies that a non-zero assignee exists and is a member
+// of the project, and that a column belongs to the project.
+func (s *Service) checkReferences(ctx context.Context, projectID, assignee uint, columnID *uint) error {
+	if assignee != 0 {
+		if _, err := s.Users.FindUser(ctx, assignee); err != nil {
+			return notFound(err, invalid("assignee", "not found"))
+		}
+		member, err := s.Projects.IsMember(ctx, projectID, assignee)
+		if err != nil {
+			return err
+		}
+		if !member {
+			return invalid("assignee", "not a project member")
+		}
+	}
+	if columnID != nil {
+		if _, err := s.Projects.FindColumn(ctx, projectID, *columnID); err != nil {
+			return notFound(err, invalid("column", "not found"))
+		}
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) map[uint]bool {
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
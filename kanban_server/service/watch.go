@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+
+	"kanban_server/events"
+	"kanban_server/models"
+)
+
+// WatchBuffer is how many events a slow watcher may fall behind before
+// further events are dropped for it. The bus must never block on a client.
+const WatchBuffer = 64
+
+// Watch streams the events of a project to userID. The channel is closed
+// when ctx ends, after the project is deleted, or after userID is removed
+// from it.
+func (s *Service) Watch(ctx context.Context, projectID, userID uint) <-chan events.Event {
+	out := make(chan events.Event)
+	if s.Bus == nil {
+		close(out)
+		return out
+	}
+
+	incoming := make(chan events.Event, WatchBuffer)
+	unsubscribe := s.Bus.Subscribe(func(e events.Event) {
+		if e.ProjectID != projectID {
+			return
+		}
+		select {
+		case incoming <- e:
+		default:
+		}
+	})
+
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-incoming:
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+				if endsWatch(e, userID) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// endsWatch reports whether the watcher lost access to the project with e.
+func endsWatch(e events.Event, userID uint) bool {
+	switch e.Type {
+	case events.ProjectDeleted:
+		return true
+	case events.MemberRemoved:
+		member, ok := e.Data.(models.User)
+		return ok && member.ID == userID
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"kanban_server/graph"
+
+	"google.golang.org/grpc"
+)
+
+// workerGroup runs the background workers until it is stopped.
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu sync.Mutex
+	// exited names the workers that returned before Stop was called.
+	exited []string
+}
+
+func newWorkerGroup() *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workerGroup{ctx: ctx, cancel: cancel}
+}
+
+// Go runs the named worker in the background.
+func (g *workerGroup) Go(name string, run func(context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		run(g.ctx)
+		if g.ctx.Err() == nil {
+			g.mu.Lock()
+			g.exited = append(g.exited, name)
+			g.mu.Unlock()
+		}
+	}()
+}
+
+// Exited names the workers that have stopped although the group was not.
+func (g *workerGroup) Exited() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.exited...)
+}
+
+// Stop cancels the workers and waits for them to return.
+func (g *workerGroup) Stop(ctx context.Context) error {
+	g.cancel()
+	return wait(ctx, &g.wg)
+}
+
+// shutdown stops the server within the deadline of ctx. The HTTP and gRPC
+// servers stop accepting connections and finish the requests in flight
+// while websocket clients are asked to go away. Then the workers stop, the
+// emails being sent go out and the database is closed.
+func (s *Server) shutdown(ctx context.Context, httpServer *http.Server, grpcServer *grpc.Server, graphQL *graph.Handler) error {
+	errs := make([]error, 3)
+	var servers sync.WaitGroup
+	servers.Add(3)
+	go func() {
+		defer servers.Done()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			errs[0] = fmt.Errorf("HTTP server: %w", err)
+		}
+	}()
+	go func() {
+		defer servers.Done()
+		if err := graphQL.Shutdown(ctx); err != nil {
+			errs[1] = fmt.Errorf("websockets: %w", err)
+		}
+	}()
+	go func() {
+		defer servers.Done()
+		if err := stopGRPC(ctx, grpcServer); err != nil {
+			errs[2] = fmt.Errorf("gRPC server: %w", err)
+		}
+	}()
+	servers.Wait()
+
+	if err := s.workers.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("workers: %w", err))
+	}
+	if err := wait(ctx, &s.background); err != nil {
+		errs = append(errs, fmt.Errorf("emails: %w", err))
+	}
+	if sqlDB, err := s.DB.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("database: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopGRPC lets the calls in flight finish, cutting them off when ctx ends.
+func stopGRPC(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// wait waits for wg, giving up when ctx ends.
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
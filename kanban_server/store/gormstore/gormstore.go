@@ -0,0 +1,213 @@
+// Package gormstore implements the store repositories with GORM.
+package gormstore
+
+import (
+	"context"
+	"errors"
+
+	"kanban_server/models"
+	"kanban_server/store"
+
+	"gorm.io/gorm"
+)
+
+// Store implements store.Store on a GORM database.
+type Store struct {
+	db *gorm.DB
+}
+
+var _ store.Store = (*Store)(nil)
+
+// New returns a store using db, which must be migrated.
+func New(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// notFound maps gorm.ErrRecordNotFound to store.ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	return s.db.WithContext(ctx).Create(user).Error
+}
+
+func (s *Store) FindUser(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).First(&user, id).Error
+	return user, notFound(err)
+}
+
+func (s *Store) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	return user, notFound(err)
+}
+
+func (s *Store) ListProjects(ctx context.Context, filter store.ProjectFilter) ([]models.Project, error) {
+	db := s.db.WithContext(ctx)
+	if filter.MemberID != 0 {
+		db = db.Joins("JOIN user_projects ON user_projects.project_id = projects.id").
+			Where("user_projects.user_id = ?", filter.MemberID)
+	}
+	if filter.Status != "" {
+		db = db.Where("projects.status = ?", filter.Status)
+	}
+	if filter.WithTasks {
+		db = db.Preload("Tasks", func(db *gorm.DB) *gorm.DB { return db.Order("tasks.id") })
+	}
+	projects := []models.Project{}
+	err := db.Order("projects.id").Find(&projects).Error
+	return projects, err
+}
+
+func (s *Store) FindProject(ctx context.Context, id uint) (models.Project, error) {
+	var project models.Project
+	err := s.db.WithContext(ctx).First(&project, id).Error
+	return project, notFound(err)
+}
+
+func (s *Store) CreateProject(ctx context.Context, project *models.Project, memberID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		return tx.Model(project).Association("Users").Append(&models.User{ID: memberID})
+	})
+}
+
+func (s *Store) UpdateProject(ctx context.Context, project *models.Project, updates map[string]interface{}) error {
+	db := s.db.WithContext(ctx)
+	if err := db.Model(project).Updates(updates).Error; err != nil {
+		return err
+	}
+	return notFound(db.First(project, project.ID).Error)
+}
+
+func (s *Store) DeleteProject(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		project := models.Project{ID: id}
+		if err := tx.Model(&project).Association("Users").Clear(); err != nil {
+			return err
+		}
+		var taskIDs []uint
+		if err := tx.Model(&models.Task{}).Where("project_id = ?", id).Pluck("id", &taskIDs).Error; err != nil {
+			return err
+		}
+		if len(taskIDs) > 0 {
+			if err := tx.Exec("DELETE FROM task_labels WHERE task_id IN ?", taskIDs).Error; err != nil {
+				return err
+			}
+			for _, model := range []interface{}{&models.Comment{}, &models.ReminderLog{}} {
+				if err := tx.Where("task_id IN ?", taskIDs).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+		}
+		subscriptions := tx.Model(&models.WebhookSubscription{}).Select("id").Where("project_id = ?", id)
+		if err := tx.Where("subscription_id IN (?)", subscriptions).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&models.Task{}, &models.Column{}, &models.Label{}, &models.Recurrence{},
+			&models.WebhookSubscription{}, &models.CalendarFeed{}, &models.Notification{},
+		} {
+			if err := tx.Where("project_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&project).Error
+	})
+}
+
+func (s *Store) IsMember(ctx context.Context, projectID, userID uint) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Table("user_projects").
+		Where("project_id = ? AND user_id = ?", projectID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *Store) ListMembers(ctx context.Context, projectID uint) ([]models.User, error) {
+	users := []models.User{}
+	err := s.db.WithContext(ctx).Joins("JOIN user_projects ON user_projects.user_id = users.id").
+		Where("user_projects.project_id = ?", projectID).Order("users.id").Find(&users).Error
+	return users, err
+}
+
+func (s *Store) AddMember(ctx context.Context, projectID, userID uint) error {
+	project := models.Project{ID: projectID}
+	return s.db.WithContext(ctx).Model(&project).Association("Users").Append(&models.User{ID: userID})
+}
+
+func (s *Store) RemoveMember(ctx context.Context, projectID, userID uint) error {
+	project := models.Project{ID: projectID}
+	return s.db.WithContext(ctx).Model(&project).Association("Users").Delete(&models.User{ID: userID})
+}
+
+func (s *Store) FindColumn(ctx context.Context, projectID, columnID uint) (models.Column, error) {
+	var column models.Column
+	err := s.db.WithContext(ctx).Where("project_id = ?", projectID).First(&column, columnID).Error
+	return column, notFound(err)
+}
+
+func (s *Store) FindLabels(ctx context.Context, projectID uint, ids []uint) ([]models.Label, error) {
+	labels := []models.Label{}
+	if len(ids) == 0 {
+		return labels, nil
+	}
+	err := s.db.WithContext(ctx).Where("project_id = ? AND id IN ?", projectID, ids).Order("id").Find(&labels).Error
+	return labels, err
+}
+
+func (s *Store) ListTasks(ctx context.Context, projectID uint) ([]models.Task, error) {
+	tasks := []models.Task{}
+	err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("id").Find(&tasks).Error
+	return tasks, err
+}
+
+func (s *Store) FindTask(ctx context.Context, id uint) (models.Task, error) {
+	var task models.Task
+	err := s.db.WithContext(ctx).First(&task, id).Error
+	return task, notFound(err)
+}
+
+func (s *Store) CreateTask(ctx context.Context, task *models.Task) error {
+	return s.db.WithContext(ctx).Create(task).Error
+}
+
+func (s *Store) UpdateTask(ctx context.Context, task *models.Task, updates map[string]interface{}) error {
+	db := s.db.WithContext(ctx)
+	if err := db.Model(task).Updates(updates).Error; err != nil {
+		return err
+	}
+	return notFound(db.First(task, task.ID).Error)
+}
+
+func (s *Store) DeleteTask(ctx context.Context, id uint) error {
+	task := models.Task{ID: id}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&task).Association("Labels").Clear(); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.Comment{}, &models.ReminderLog{}} {
+			if err := tx.Where("task_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&task).Error
+	})
+}
+
+func (s *Store) ListComments(ctx context.Context, taskID uint) ([]models.Comment, error) {
+	comments := []models.Comment{}
+	err := s.db.WithContext(ctx).Where("task_id = ?", taskID).Order("created_at, id").Find(&comments).Error
+	return comments, err
+}
+
+func (s *Store) CreateComment(ctx context.Context, comment *models.Comment) error {
+	return s.db.WithContext(ctx).Omit("User").Create(comment).Error
+}
@@ -0,0 +1,75 @@
+package gormstore
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kanban_server/config"
+	"kanban_server/models"
+	"kanban_server/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Harness {
+		db, err := config.Open(config.DriverSQLite, filepath.Join(t.TempDir(), "kanban.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, _ := db.DB()
+		t.Cleanup(func() { sqlDB.Close() })
+
+		create := func(t *testing.T, record interface{}) {
+			if err := db.Create(record).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		return storetest.Harness{
+			Store:     New(db),
+			AddColumn: func(t *testing.T, column *models.Column) { create(t, column) },
+			AddLabel:  func(t *testing.T, label *models.Label) { create(t, label) },
+			TaskLabelIDs: func(t *testing.T, taskID uint) []uint {
+				var ids []uint
+				if err := db.Table("task_labels").Where("task_id = ?", taskID).Order("label_id").Pluck("label_id", &ids).Error; err != nil {
+					t.Fatal(err)
+				}
+				return ids
+			},
+			AddRecords: func(t *testing.T, projectID, taskID uint) {
+				user := models.User{Email: fmt.Sprintf("commenter%d@example.com", taskID), Password: "password123", Name: "Commenter"}
+				create(t, &user)
+				create(t, &models.Comment{TaskID: taskID, UserID: user.ID, Body: "Looks good"})
+				create(t, &models.ReminderLog{TaskID: taskID, UserID: user.ID, Kind: "overdue", DueDate: time.Now(), SentAt: time.Now()})
+				sub := models.WebhookSubscription{ProjectID: projectID, URL: "https://example.com/hook", Secret: "s", Events: "*"}
+				create(t, &sub)
+				create(t, &models.WebhookDelivery{SubscriptionID: sub.ID, EventID: "e", EventType: "task.created", Payload: "{}", Status: models.DeliveryPending})
+				create(t, &models.CalendarFeed{UserID: user.ID, ProjectID: &projectID, Token: fmt.Sprintf("token%d", taskID)})
+				create(t, &models.Recurrence{ProjectID: projectID, Rule: "FREQ=DAILY", Start: time.Now(), LastOccurrenceAt: time.Now(), LastTaskID: taskID})
+				create(t, &models.Notification{UserID: user.ID, Type: "comment", Message: "New comment", ProjectID: projectID, TaskID: taskID})
+			},
+			CountRecords: func(t *testing.T, projectID, taskID uint) (map[string]int64, map[string]int64) {
+				count := func(model interface{}, query string, args ...interface{}) int64 {
+					var n int64
+					if err := db.Model(model).Where(query, args...).Count(&n).Error; err != nil {
+						t.Fatal(err)
+					}
+					return n
+				}
+				subscriptions := db.Model(&models.WebhookSubscription{}).Select("id").Where("project_id = ?", projectID)
+				project := map[string]int64{
+					"webhook_subscriptions": count(&models.WebhookSubscription{}, "project_id = ?", projectID),
+					"webhook_deliveries":    count(&models.WebhookDelivery{}, "subscription_id IN (?)", subscriptions),
+					"calendar_feeds":        count(&models.CalendarFeed{}, "project_id = ?", projectID),
+					"recurrences":           count(&models.Recurrence{}, "project_id = ?", projectID),
+					"notifications":         count(&models.Notification{}, "project_id = ?", projectID),
+				}
+				task := map[string]int64{
+					"comments":      count(&models.Comment{}, "task_id = ?", taskID),
+					"reminder_logs": count(&models.ReminderLog{}, "task_id = ?", taskID),
+				}
+				return project, task
+			},
+		}
+	})
+}
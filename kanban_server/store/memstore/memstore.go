@@ -0,0 +1,414 @@
+// Package memstore implements the store repositories in memory, for tests
+// that should not need a database. It follows gormstore's behaviour,
+// including password hashing, timestamps and unique user emails, but checks
+// no other constraints.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/store"
+
+	"gorm.io/gorm/schema"
+)
+
+// Store implements store.Store in memory. It is safe for concurrent use.
+type Store struct {
+	mu       sync.Mutex
+	lastID   uint
+	users    map[uint]models.User
+	projects map[uint]models.Project
+	members  map[uint]map[uint]bool
+	columns  map[uint]models.Column
+	labels   map[uint]models.Label
+	tasks    map[uint]models.Task
+	comments map[uint]models.Comment
+	// taskLabels holds the label IDs of each task.
+	taskLabels map[uint][]uint
+
+	// Now returns the time stored in CreatedAt and UpdatedAt.
+	Now func() time.Time
+}
+
+var _ store.Store = (*Store)(nil)
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{
+		users:      map[uint]models.User{},
+		projects:   map[uint]models.Project{},
+		members:    map[uint]map[uint]bool{},
+		columns:    map[uint]models.Column{},
+		labels:     map[uint]models.Label{},
+		tasks:      map[uint]models.Task{},
+		comments:   map[uint]models.Comment{},
+		taskLabels: map[uint][]uint{},
+		Now:        time.Now,
+	}
+}
+
+// nextID returns a new ID. IDs are unique across tables, which keeps tests
+// from passing by accident with the ID of another kind of record.
+func (s *Store) nextID() uint {
+	s.lastID++
+	return s.lastID
+}
+
+// AddColumn stores a column, which no repository creates yet.
+func (s *Store) AddColumn(column *models.Column) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	column.ID = s.nextID()
+	column.CreatedAt, column.UpdatedAt = s.Now(), s.Now()
+	s.columns[column.ID] = *column
+}
+
+// AddLabel stores a label, which no repository creates yet.
+func (s *Store) AddLabel(label *models.Label) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	label.ID = s.nextID()
+	label.CreatedAt, label.UpdatedAt = s.Now(), s.Now()
+	s.labels[label.ID] = *label
+}
+
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return fmt.Errorf("memstore: email %q is taken", user.Email)
+		}
+	}
+	// BeforeSave hashes the password, as it does when GORM saves the user.
+	if err := user.BeforeSave(nil); err != nil {
+		return err
+	}
+	user.ID = s.nextID()
+	user.CreatedAt, user.UpdatedAt = s.Now(), s.Now()
+	stored := *user
+	stored.Projects = nil
+	s.users[user.ID] = stored
+	return nil
+}
+
+func (s *Store) FindUser(ctx context.Context, id uint) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[id]
+	if !ok {
+		return user, store.ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *Store) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, store.ErrNotFound
+}
+
+func (s *Store) ListProjects(ctx context.Context, filter store.ProjectFilter) ([]models.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	projects := []models.Project{}
+	for _, project := range s.projects {
+		if filter.MemberID != 0 && !s.members[project.ID][filter.MemberID] {
+			continue
+		}
+		if filter.Status != "" && project.Status != filter.Status {
+			continue
+		}
+		if filter.WithTasks {
+			project.Tasks = s.projectTasks(project.ID)
+		}
+		projects = append(projects, project)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects, nil
+}
+
+func (s *Store) FindProject(ctx context.Context, id uint) (models.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	project, ok := s.projects[id]
+	if !ok {
+		return project, store.ErrNotFound
+	}
+	return project, nil
+}
+
+func (s *Store) CreateProject(ctx context.Context, project *models.Project, memberID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if project.Status == "" {
+		project.Status = models.ProjectStatusActive
+	}
+	project.ID = s.nextID()
+	project.CreatedAt, project.UpdatedAt = s.Now(), s.Now()
+	s.projects[project.ID] = withoutAssociations(*project)
+	s.members[project.ID] = map[uint]bool{memberID: true}
+	return nil
+}
+
+func (s *Store) UpdateProject(ctx context.Context, project *models.Project, updates map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.projects[project.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if err := s.apply(&stored, updates); err != nil {
+		return err
+	}
+	s.projects[stored.ID] = stored
+	*project = stored
+	return nil
+}
+
+func (s *Store) DeleteProject(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.projects, id)
+	delete(s.members, id)
+	for taskID, task := range s.tasks {
+		if task.ProjectID == id {
+			s.deleteTask(taskID)
+		}
+	}
+	for columnID, column := range s.columns {
+		if column.ProjectID == id {
+			delete(s.columns, columnID)
+		}
+	}
+	for labelID, label := range s.labels {
+		if label.ProjectID == id {
+			delete(s.labels, labelID)
+		}
+	}
+	return nil
+}
+
+func (s *Store) IsMember(ctx context.Context, projectID, userID uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.members[projectID][userID], nil
+}
+
+func (s *Store) ListMembers(ctx context.Context, projectID uint) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := []models.User{}
+	for userID := range s.members[projectID] {
+		if user, ok := s.users[userID]; ok {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (s *Store) AddMember(ctx context.Context, projectID, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.members[projectID] == nil {
+		s.members[projectID] = map[uint]bool{}
+	}
+	s.members[projectID][userID] = true
+	return nil
+}
+
+func (s *Store) RemoveMember(ctx context.Context, projectID, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.members[projectID], userID)
+	return nil
+}
+
+func (s *Store) FindColumn(ctx context.Context, projectID, columnID uint) (models.Column, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	column, ok := s.columns[columnID]
+	if !ok || column.ProjectID != projectID {
+		return models.Column{}, store.ErrNotFound
+	}
+	return column, nil
+}
+
+func (s *Store) FindLabels(ctx context.Context, projectID uint, ids []uint) ([]models.Label, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	labels := []models.Label{}
+	for _, label := range s.labels {
+		if label.ProjectID == projectID && containsID(ids, label.ID) {
+			labels = append(labels, label)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].ID < labels[j].ID })
+	return labels, nil
+}
+
+func (s *Store) ListTasks(ctx context.Context, projectID uint) ([]models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.projectTasks(projectID), nil
+}
+
+func (s *Store) projectTasks(projectID uint) []models.Task {
+	tasks := []models.Task{}
+	for _, task := range s.tasks {
+		if task.ProjectID == projectID {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
+
+func (s *Store) FindTask(ctx context.Context, id uint) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return task, store.ErrNotFound
+	}
+	return task, nil
+}
+
+func (s *Store) CreateTask(ctx context.Context, task *models.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task.Status == "" {
+		task.Status = models.TaskStatusTodo
+	}
+	if task.Priority == "" {
+		task.Priority = models.TaskPriorityMedium
+	}
+	task.ID = s.nextID()
+	task.CreatedAt, task.UpdatedAt = s.Now(), s.Now()
+	for _, label := range task.Labels {
+		s.taskLabels[task.ID] = append(s.taskLabels[task.ID], label.ID)
+	}
+	stored := *task
+	stored.Project, stored.Labels, stored.Comments = models.Project{}, nil, nil
+	s.tasks[task.ID] = stored
+	return nil
+}
+
+func (s *Store) UpdateTask(ctx context.Context, task *models.Task, updates map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.tasks[task.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if err := s.apply(&stored, updates); err != nil {
+		return err
+	}
+	s.tasks[stored.ID] = stored
+	*task = stored
+	return nil
+}
+
+func (s *Store) DeleteTask(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteTask(id)
+	return nil
+}
+
+func (s *Store) deleteTask(id uint) {
+	delete(s.tasks, id)
+	delete(s.taskLabels, id)
+	for commentID, comment := range s.comments {
+		if comment.TaskID == id {
+			delete(s.comments, commentID)
+		}
+	}
+}
+
+func (s *Store) ListComments(ctx context.Context, taskID uint) ([]models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	comments := []models.Comment{}
+	for _, comment := range s.comments {
+		if comment.TaskID == taskID {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].ID < comments[j].ID
+	})
+	return comments, nil
+}
+
+func (s *Store) CreateComment(ctx context.Context, comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	comment.ID = s.nextID()
+	comment.CreatedAt, comment.UpdatedAt = s.Now(), s.Now()
+	stored := *comment
+	stored.User = models.User{}
+	s.comments[comment.ID] = stored
+	return nil
+}
+
+// TaskLabelIDs returns the IDs of the labels attached to a task.
+func (s *Store) TaskLabelIDs(taskID uint) []uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint(nil), s.taskLabels[taskID]...)
+}
+
+var schemas sync.Map
+
+// apply sets the fields of model named by the column names in updates, the
+// way GORM's Updates does, and touches UpdatedAt.
+func (s *Store) apply(model interface{}, updates map[string]interface{}) error {
+	sch, err := schema.Parse(model, &schemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	value := reflect.ValueOf(model).Elem()
+	for column, v := range updates {
+		field := sch.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			return fmt.Errorf("memstore: %s has no column %q", sch.Name, column)
+		}
+		if err := field.Set(context.Background(), value, v); err != nil {
+			return err
+		}
+	}
+	if field := sch.LookUpField("updated_at"); field != nil {
+		return field.Set(context.Background(), value, s.Now())
+	}
+	return nil
+}
+
+func withoutAssociations(project models.Project) models.Project {
+	project.Users, project.Tasks, project.Columns, project.Labels = nil, nil, nil, nil
+	return project
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package memstore
+
+import (
+	"testing"
+
+	"kanban_server/models"
+	"kanban_server/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Harness {
+		s := New()
+		return storetest.Harness{
+			Store:        s,
+			AddColumn:    func(t *testing.T, column *models.Column) { s.AddColumn(column) },
+			AddLabel:     func(t *testing.T, label *models.Label) { s.AddLabel(label) },
+			TaskLabelIDs: func(t *testing.T, taskID uint) []uint { return s.TaskLabelIDs(taskID) },
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// Package store defines the repositories that users, projects and tasks are
+// read and written through. gormstore implements them on any database GORM
+// supports, Postgres in production and SQLite for local runs and tests;
+// memstore keeps everything in memory.
+//
+// Changes are passed as maps from column name to value, as built by
+// service.ProjectChanges and service.TaskChanges or by PATCH requests.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"kanban_server/models"
+)
+
+// ErrNotFound is returned when a lookup matches no record.
+var ErrNotFound = errors.New("record not found")
+
+// Users stores accounts.
+type Users interface {
+	// CreateUser stores a new user, hashing its password.
+	CreateUser(ctx context.Context, user *models.User) error
+	FindUser(ctx context.Context, id uint) (models.User, error)
+	FindUserByEmail(ctx context.Context, email string) (models.User, error)
+}
+
+// ProjectFilter selects projects for ListProjects. The zero value selects
+// every project.
+type ProjectFilter struct {
+	// MemberID, if set, keeps the projects that user is a member of.
+	MemberID uint
+	Status   string
+	// WithTasks loads the tasks of each project.
+	WithTasks bool
+}
+
+// Projects stores projects, their members and the columns and labels tasks
+// refer to.
+type Projects interface {
+	// ListProjects returns the matching projects ordered by ID.
+	ListProjects(ctx context.Context, filter ProjectFilter) ([]models.Project, error)
+	FindProject(ctx context.Context, id uint) (models.Project, error)
+	// CreateProject stores a new project with memberID as its first member.
+	CreateProject(ctx context.Context, project *models.Project, memberID uint) error
+	// UpdateProject applies column updates and reloads project.
+	UpdateProject(ctx context.Context, project *models.Project, updates map[string]interface{}) error
+	// DeleteProject deletes a project with its tasks, columns, labels,
+	// memberships and everything else that refers to it.
+	DeleteProject(ctx context.Context, id uint) error
+	IsMember(ctx context.Context, projectID, userID uint) (bool, error)
+	// ListMembers returns the members of a project ordered by ID.
+	ListMembers(ctx context.Context, projectID uint) ([]models.User, error)
+	// AddMember adds a user to a project. Adding a member again does nothing.
+	AddMember(ctx context.Context, projectID, userID uint) error
+	// RemoveMember removes a user from a project.
+	RemoveMember(ctx context.Context, projectID, userID uint) error
+	// FindColumn returns a column of the project.
+	FindColumn(ctx context.Context, projectID, columnID uint) (models.Column, error)
+	// FindLabels returns the labels of the project among ids.
+	FindLabels(ctx context.Context, projectID uint, ids []uint) ([]models.Label, error)
+}
+
+// Tasks stores tasks and their comments.
+type Tasks interface {
+	// ListTasks returns the tasks of a project ordered by ID.
+	ListTasks(ctx context.Context, projectID uint) ([]models.Task, error)
+	FindTask(ctx context.Context, id uint) (models.Task, error)
+	// CreateTask stores a new task along with its labels.
+	CreateTask(ctx context.Context, task *models.Task) error
+	// UpdateTask applies column updates and reloads task.
+	UpdateTask(ctx context.Context, task *models.Task, updates map[string]interface{}) error
+	// DeleteTask deletes a task with its comments and detaches its labels.
+	DeleteTask(ctx context.Context, id uint) error
+	// ListComments returns the comments on a task, oldest first.
+	ListComments(ctx context.Context, taskID uint) ([]models.Comment, error)
+	// CreateComment stores a new comment.
+	CreateComment(ctx context.Context, comment *models.Comment) error
+}
+
+// Store is an implementation of every repository.
+type Store interface {
+	Users
+	Projects
+	Tasks
+}
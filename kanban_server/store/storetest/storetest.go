@@ -0,0 +1,425 @@
+// Package storetest checks that an implementation of the store repositories
+// behaves like the others, so tests written against memstore hold for
+// gormstore too.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/store"
+)
+
+// Harness is a fresh, empty store under test and a way to add the records
+// that no repository creates.
+type Harness struct {
+	Store        store.Store
+	AddColumn    func(t *testing.T, column *models.Column)
+	AddLabel     func(t *testing.T, label *models.Label)
+	TaskLabelIDs func(t *testing.T, taskID uint) []uint
+	// AddRecords, if set, stores one of each record that refers to the
+	// project or task but belongs to no repository, such as comments,
+	// webhooks and calendar feeds. CountRecords returns how many of them are
+	// left by table, as project and task records.
+	AddRecords   func(t *testing.T, projectID, taskID uint)
+	CountRecords func(t *testing.T, projectID, taskID uint) (project, task map[string]int64)
+}
+
+// wantNoRecords checks that none of the records of the given kind added by
+// AddRecords are left.
+func wantNoRecords(t *testing.T, h Harness, projectID, taskID uint, kind string) {
+	t.Helper()
+	if h.CountRecords == nil {
+		return
+	}
+	projectRecords, taskRecords := h.CountRecords(t, projectID, taskID)
+	counts := taskRecords
+	if kind == "project" {
+		counts = projectRecords
+	}
+	for table, n := range counts {
+		if n != 0 {
+			t.Errorf("%d %s rows of the deleted %s are left", n, table, kind)
+		}
+	}
+}
+
+// Run runs the conformance tests, calling newHarness for each.
+func Run(t *testing.T, newHarness func(t *testing.T) Harness) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, h Harness)
+	}{
+		{"Users", testUsers},
+		{"Projects", testProjects},
+		{"ListProjects", testListProjects},
+		{"Members", testMembers},
+		{"ColumnsAndLabels", testColumnsAndLabels},
+		{"Tasks", testTasks},
+		{"Comments", testComments},
+		{"UpdateTask", testUpdateTask},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newHarness(t))
+		})
+	}
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func wantNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("%s: error = %v, want store.ErrNotFound", what, err)
+	}
+}
+
+func createUser(t *testing.T, s store.Store, email string) models.User {
+	t.Helper()
+	user := models.User{Email: email, Password: "password123", Name: "Test User"}
+	check(t, s.CreateUser(context.Background(), &user))
+	return user
+}
+
+func createProject(t *testing.T, s store.Store, title, status string, memberID uint) models.Project {
+	t.Helper()
+	project := models.Project{Title: title, Status: status}
+	check(t, s.CreateProject(context.Background(), &project, memberID))
+	return project
+}
+
+func createTask(t *testing.T, s store.Store, projectID uint, title string) models.Task {
+	t.Helper()
+	task := models.Task{Title: title, Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium, ProjectID: projectID}
+	check(t, s.CreateTask(context.Background(), &task))
+	return task
+}
+
+func testUsers(t *testing.T, h Harness) {
+	ctx := context.Background()
+	user := createUser(t, h.Store, "ada@example.com")
+	if user.ID == 0 {
+		t.Fatal("CreateUser did not set the ID")
+	}
+	if user.CheckPassword("password123") != nil {
+		t.Error("CreateUser did not hash the password")
+	}
+
+	byID, err := h.Store.FindUser(ctx, user.ID)
+	check(t, err)
+	byEmail, err := h.Store.FindUserByEmail(ctx, "ada@example.com")
+	check(t, err)
+	if byID.Email != user.Email || byEmail.ID != user.ID {
+		t.Errorf("FindUser = %+v, FindUserByEmail = %+v", byID, byEmail)
+	}
+
+	if err := h.Store.CreateUser(ctx, &models.User{Email: "ada@example.com", Password: "x", Name: "Ada"}); err == nil {
+		t.Error("CreateUser accepted a taken email")
+	}
+	_, err = h.Store.FindUser(ctx, user.ID+100)
+	wantNotFound(t, "FindUser", err)
+	_, err = h.Store.FindUserByEmail(ctx, "bob@example.com")
+	wantNotFound(t, "FindUserByEmail", err)
+}
+
+func testProjects(t *testing.T, h Harness) {
+	ctx := context.Background()
+	owner := createUser(t, h.Store, "owner@example.com")
+	other := createUser(t, h.Store, "other@example.com")
+	project := createProject(t, h.Store, "Launch", models.ProjectStatusActive, owner.ID)
+
+	for userID, want := range map[uint]bool{owner.ID: true, other.ID: false} {
+		member, err := h.Store.IsMember(ctx, project.ID, userID)
+		check(t, err)
+		if member != want {
+			t.Errorf("IsMember(%d) = %v, want %v", userID, member, want)
+		}
+	}
+
+	found, err := h.Store.FindProject(ctx, project.ID)
+	check(t, err)
+	if found.Title != "Launch" || found.Status != models.ProjectStatusActive {
+		t.Errorf("FindProject = %+v", found)
+	}
+
+	updated := found
+	err = h.Store.UpdateProject(ctx, &updated, map[string]interface{}{"title": "Relaunch", "status": models.ProjectStatusOnHold})
+	check(t, err)
+	if updated.Title != "Relaunch" || updated.Status != models.ProjectStatusOnHold || updated.Description != found.Description {
+		t.Errorf("UpdateProject left %+v", updated)
+	}
+	if updated.UpdatedAt.Before(found.UpdatedAt) {
+		t.Error("UpdateProject did not touch UpdatedAt")
+	}
+	reloaded, err := h.Store.FindProject(ctx, project.ID)
+	check(t, err)
+	if reloaded.Title != "Relaunch" {
+		t.Errorf("FindProject after update = %+v", reloaded)
+	}
+
+	column := models.Column{ProjectID: project.ID, Name: "Doing"}
+	h.AddColumn(t, &column)
+	label := models.Label{ProjectID: project.ID, Name: "bug"}
+	h.AddLabel(t, &label)
+	task := models.Task{Title: "Ship", Status: models.TaskStatusTodo, Priority: models.TaskPriorityMedium, ProjectID: project.ID, ColumnID: &column.ID, Labels: []models.Label{label}}
+	check(t, h.Store.CreateTask(ctx, &task))
+	if h.AddRecords != nil {
+		h.AddRecords(t, project.ID, task.ID)
+	}
+
+	check(t, h.Store.DeleteProject(ctx, project.ID))
+	wantNoRecords(t, h, project.ID, task.ID, "project")
+	wantNoRecords(t, h, project.ID, task.ID, "task")
+	_, err = h.Store.FindProject(ctx, project.ID)
+	wantNotFound(t, "FindProject after delete", err)
+	_, err = h.Store.FindTask(ctx, task.ID)
+	wantNotFound(t, "FindTask after deleting its project", err)
+	_, err = h.Store.FindColumn(ctx, project.ID, column.ID)
+	wantNotFound(t, "FindColumn after deleting its project", err)
+	member, err := h.Store.IsMember(ctx, project.ID, owner.ID)
+	check(t, err)
+	if member {
+		t.Error("IsMember after deleting the project = true")
+	}
+}
+
+func testListProjects(t *testing.T, h Harness) {
+	ctx := context.Background()
+	ada := createUser(t, h.Store, "ada@example.com")
+	bob := createUser(t, h.Store, "bob@example.com")
+	launch := createProject(t, h.Store, "Launch", models.ProjectStatusActive, ada.ID)
+	archive := createProject(t, h.Store, "Archive", models.ProjectStatusArchived, ada.ID)
+	hiring := createProject(t, h.Store, "Hiring", models.ProjectStatusActive, bob.ID)
+	task := createTask(t, h.Store, launch.ID, "Ship")
+
+	tests := []struct {
+		name   string
+		filter store.ProjectFilter
+		want   []uint
+	}{
+		{"all", store.ProjectFilter{}, []uint{launch.ID, archive.ID, hiring.ID}},
+		{"member", store.ProjectFilter{MemberID: ada.ID}, []uint{launch.ID, archive.ID}},
+		{"status", store.ProjectFilter{Status: models.ProjectStatusActive}, []uint{launch.ID, hiring.ID}},
+		{"member and status", store.ProjectFilter{MemberID: bob.ID, Status: models.ProjectStatusArchived}, []uint{}},
+	}
+	for _, tt := range tests {
+		projects, err := h.Store.ListProjects(ctx, tt.filter)
+		check(t, err)
+		got := []uint{}
+		for _, p := range projects {
+			got = append(got, p.ID)
+			if len(p.Tasks) != 0 {
+				t.Errorf("%s: ListProjects loaded tasks without WithTasks", tt.name)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ListProjects = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	projects, err := h.Store.ListProjects(ctx, store.ProjectFilter{WithTasks: true})
+	check(t, err)
+	if len(projects) != 3 || len(projects[0].Tasks) != 1 || projects[0].Tasks[0].ID != task.ID || len(projects[1].Tasks) != 0 {
+		t.Errorf("ListProjects with tasks = %+v", projects)
+	}
+}
+
+func testMembers(t *testing.T, h Harness) {
+	ctx := context.Background()
+	owner := createUser(t, h.Store, "owner@example.com")
+	other := createUser(t, h.Store, "other@example.com")
+	project := createProject(t, h.Store, "Launch", models.ProjectStatusActive, owner.ID)
+
+	memberIDs := func() []uint {
+		t.Helper()
+		users, err := h.Store.ListMembers(ctx, project.ID)
+		check(t, err)
+		ids := []uint{}
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+		return ids
+	}
+
+	if got := memberIDs(); !reflect.DeepEqual(got, []uint{owner.ID}) {
+		t.Errorf("ListMembers = %v, want the owner", got)
+	}
+	check(t, h.Store.AddMember(ctx, project.ID, other.ID))
+	check(t, h.Store.AddMember(ctx, project.ID, other.ID))
+	if got := memberIDs(); !reflect.DeepEqual(got, []uint{owner.ID, other.ID}) {
+		t.Errorf("ListMembers after AddMember = %v, want %v", got, []uint{owner.ID, other.ID})
+	}
+	member, err := h.Store.IsMember(ctx, project.ID, other.ID)
+	check(t, err)
+	if !member {
+		t.Error("IsMember after AddMember = false")
+	}
+
+	check(t, h.Store.RemoveMember(ctx, project.ID, owner.ID))
+	if got := memberIDs(); !reflect.DeepEqual(got, []uint{other.ID}) {
+		t.Errorf("ListMembers after RemoveMember = %v, want %v", got, []uint{other.ID})
+	}
+	if _, err := h.Store.FindUser(ctx, owner.ID); err != nil {
+		t.Errorf("RemoveMember deleted the user: %v", err)
+	}
+}
+
+func testColumnsAndLabels(t *testing.T, h Harness) {
+	ctx := context.Background()
+	owner := createUser(t, h.Store, "owner@example.com")
+	project := createProject(t, h.Store, "Launch", models.ProjectStatusActive, owner.ID)
+	other := createProject(t, h.Store, "Other", models.ProjectStatusActive, owner.ID)
+
+	column := models.Column{ProjectID: project.ID, Name: "Doing"}
+	h.AddColumn(t, &column)
+	found, err := h.Store.FindColumn(ctx, project.ID, column.ID)
+	check(t, err)
+	if found.Name != "Doing" {
+		t.Errorf("FindColumn = %+v", found)
+	}
+	_, err = h.Store.FindColumn(ctx, other.ID, column.ID)
+	wantNotFound(t, "FindColumn in another project", err)
+
+	bug := models.Label{ProjectID: project.ID, Name: "bug"}
+	h.AddLabel(t, &bug)
+	otherBug := models.Label{ProjectID: other.ID, Name: "bug"}
+	h.AddLabel(t, &otherBug)
+	labels, err := h.Store.FindLabels(ctx, project.ID, []uint{bug.ID, otherBug.ID, bug.ID + 100})
+	check(t, err)
+	if len(labels) != 1 || labels[0].ID != bug.ID {
+		t.Errorf("FindLabels = %+v", labels)
+	}
+	labels, err = h.Store.FindLabels(ctx, project.ID, nil)
+	check(t, err)
+	if len(labels) != 0 {
+		t.Errorf("FindLabels(nil) = %+v", labels)
+	}
+}
+
+func testTasks(t *testing.T, h Harness) {
+	ctx := context.Background()
+	owner := createUser(t, h.Store, "owner@example.com")
+	project := createProject(t, h.Store, "Launch", models.ProjectStatusActive, owner.ID)
+	other := createProject(t, h.Store, "Other", models.ProjectStatusActive, owner.ID)
+	label := models.Label{ProjectID: project.ID, Name: "bug"}
+	h.AddLabel(t, &label)
+
+	second := createTask(t, h.Store, project.ID, "Plan")
+	createTask(t, h.Store, other.ID, "Elsewhere")
+	labelled := models.Task{
+		Title:      "Ship",
+		Status:     models.TaskStatusInProgress,
+		Priority:   models.TaskPriorityHigh,
+		ProjectID:  project.ID,
+		AssignedTo: owner.ID,
+		Labels:     []models.Label{label},
+	}
+	check(t, h.Store.CreateTask(ctx, &labelled))
+	if got := h.TaskLabelIDs(t, labelled.ID); !reflect.DeepEqual(got, []uint{label.ID}) {
+		t.Errorf("labels of the created task = %v, want [%d]", got, label.ID)
+	}
+
+	tasks, err := h.Store.ListTasks(ctx, project.ID)
+	check(t, err)
+	if len(tasks) != 2 || tasks[0].ID != second.ID || tasks[1].ID != labelled.ID {
+		t.Fatalf("ListTasks = %+v", tasks)
+	}
+
+	found, err := h.Store.FindTask(ctx, labelled.ID)
+	check(t, err)
+	if found.Title != "Ship" || found.Priority != models.TaskPriorityHigh || found.AssignedTo != owner.ID || found.ProjectID != project.ID {
+		t.Errorf("FindTask = %+v", found)
+	}
+
+	if h.AddRecords != nil {
+		h.AddRecords(t, project.ID, labelled.ID)
+	}
+
+	check(t, h.Store.DeleteTask(ctx, labelled.ID))
+	wantNoRecords(t, h, project.ID, labelled.ID, "task")
+	_, err = h.Store.FindTask(ctx, labelled.ID)
+	wantNotFound(t, "FindTask after delete", err)
+	if got := h.TaskLabelIDs(t, labelled.ID); len(got) != 0 {
+		t.Errorf("labels of the deleted task = %v", got)
+	}
+}
+
+func testComments(t *testing.T, h Harness) {
+	ctx := context.Background()
+	owner := createUser(t, h.Store, "owner@example.com")
+	project := createProject(t, h.Store, "Launch", models.ProjectStatusActive, owner.ID)
+	task := createTask(t, h.Store, project.ID, "Ship")
+	other := createTask(t, h.Store, project.ID, "Plan")
+
+	first := models.Comment{TaskID: task.ID, UserID: owner.ID, Body: "First"}
+	check(t, h.Store.CreateComment(ctx, &first))
+	if first.ID == 0 || first.CreatedAt.IsZero() {
+		t.Errorf("created comment = %+v, want an ID and a creation time", first)
+	}
+	second := models.Comment{TaskID: task.ID, UserID: owner.ID, Body: "Second"}
+	check(t, h.Store.CreateComment(ctx, &second))
+	check(t, h.Store.CreateComment(ctx, &models.Comment{TaskID: other.ID, UserID: owner.ID, Body: "Elsewhere"}))
+
+	comments, err := h.Store.ListComments(ctx, task.ID)
+	check(t, err)
+	if len(comments) != 2 || comments[0].ID != first.ID || comments[1].ID != second.ID || comments[0].Body != "First" {
+		t.Fatalf("ListComments = %+v", comments)
+	}
+
+	check(t, h.Store.DeleteTask(ctx, task.ID))
+	if comments, err = h.Store.ListComments(ctx, task.ID); err != nil || len(comments) != 0 {
+		t.Errorf("ListComments after deleting the task = %+v, %v", comments, err)
+	}
+	check(t, h.Store.DeleteProject(ctx, project.ID))
+	if comments, err = h.Store.ListComments(ctx, other.ID); err != nil || len(comments) != 0 {
+		t.Errorf("ListComments after deleting the project = %+v, %v", comments, err)
+	}
+}
+
+func testUpdateTask(t *testing.T, h Harness) {
+	ctx := context.Background()
+	owner := createUser(t, h.Store, "owner@example.com")
+	project := createProject(t, h.Store, "Launch", models.ProjectStatusActive, owner.ID)
+	column := models.Column{ProjectID: project.ID, Name: "Doing"}
+	h.AddColumn(t, &column)
+	task := createTask(t, h.Store, project.ID, "Ship")
+	due := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	check(t, h.Store.UpdateTask(ctx, &task, map[string]interface{}{
+		"title":       "Ship it",
+		"status":      models.TaskStatusDone,
+		"due_date":    due,
+		"assigned_to": owner.ID,
+		"column_id":   &column.ID,
+		"archived":    true,
+	}))
+	if task.Title != "Ship it" || task.Status != models.TaskStatusDone || !task.DueDate.Equal(due) ||
+		task.AssignedTo != owner.ID || task.ColumnID == nil || *task.ColumnID != column.ID || !task.Archived {
+		t.Errorf("UpdateTask left %+v", task)
+	}
+
+	check(t, h.Store.UpdateTask(ctx, &task, map[string]interface{}{
+		"due_date":    time.Time{},
+		"assigned_to": uint(0),
+		"column_id":   (*uint)(nil),
+		"archived":    false,
+	}))
+	reloaded, err := h.Store.FindTask(ctx, task.ID)
+	check(t, err)
+	if !reloaded.DueDate.IsZero() || reloaded.AssignedTo != 0 || reloaded.ColumnID != nil || reloaded.Archived || reloaded.Title != "Ship it" {
+		t.Errorf("task after clearing = %+v", reloaded)
+	}
+
+	missing := models.Task{ID: task.ID + 100}
+	wantNotFound(t, "UpdateTask of a missing task", h.Store.UpdateTask(ctx, &missing, map[string]interface{}{"title": "x"}))
+}
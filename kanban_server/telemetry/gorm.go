@@ -0,0 +1,126 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey holds the span of a statement between its callbacks.
+const spanKey = "telemetry:span"
+
+// gormPlugin records a client span for each statement.
+type gormPlugin struct {
+	tracer trace.Tracer
+}
+
+// NewGORMPlugin returns a plugin, for gorm.DB.Use, that records a span for
+// each statement run with a context already in a trace, such as that of a
+// request passed with WithContext. Statements run by the workers outside of
+// any trace are not recorded. Spans carry the SQL text but never its
+// arguments, which may hold user data.
+func NewGORMPlugin(tp trace.TracerProvider) gorm.Plugin {
+	return gormPlugin{tracer: tp.Tracer(instrumentation)}
+}
+
+func (p gormPlugin) Name() string {
+	return "telemetry"
+}
+
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("telemetry:before_create", p.before),
+		cb.Create().After("gorm:create").Register("telemetry:after_create", p.after("INSERT")),
+		cb.Query().Before("gorm:query").Register("telemetry:before_query", p.before),
+		cb.Query().After("gorm:query").Register("telemetry:after_query", p.after("SELECT")),
+		cb.Update().Before("gorm:update").Register("telemetry:before_update", p.before),
+		cb.Update().After("gorm:update").Register("telemetry:after_update", p.after("UPDATE")),
+		cb.Delete().Before("gorm:delete").Register("telemetry:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("telemetry:after_delete", p.after("DELETE")),
+		cb.Row().Before("gorm:row").Register("telemetry:before_row", p.before),
+		cb.Row().After("gorm:row").Register("telemetry:after_row", p.after("")),
+		cb.Raw().Before("gorm:raw").Register("telemetry:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("telemetry:after_raw", p.after("")),
+	)
+}
+
+// statementSpan is the span of a statement and the context it replaced. It
+// is empty for statements not traced, so that a statement run again does
+// not end the span of its previous run.
+type statementSpan struct {
+	span   trace.Span
+	parent context.Context
+}
+
+func (p gormPlugin) before(db *gorm.DB) {
+	parent := db.Statement.Context
+	if parent == nil || !trace.SpanContextFromContext(parent).IsValid() {
+		db.InstanceSet(spanKey, statementSpan{})
+		return
+	}
+	// The name is only known once the SQL is built, so it is set after.
+	ctx, span := p.tracer.Start(parent, "db", trace.WithSpanKind(trace.SpanKindClient))
+	db.InstanceSet(spanKey, statementSpan{span: span, parent: parent})
+	db.Statement.Context = ctx
+}
+
+// after ends the span started by before. Statements built by Raw or Row
+// take their operation from the SQL rather than from operation.
+func (p gormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, _ := db.InstanceGet(spanKey)
+		s, _ := v.(statementSpan)
+		if s.span == nil {
+			return
+		}
+		db.Statement.Context = s.parent
+
+		query := db.Statement.SQL.String()
+		if operation == "" {
+			operation = firstWord(query)
+		}
+		name := operation
+		if table := db.Statement.Table; table != "" {
+			name += " " + table
+			s.span.SetAttributes(semconv.DBCollectionName(table))
+		}
+		s.span.SetName(name)
+		s.span.SetAttributes(
+			dbSystem(db.Dialector.Name()),
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+		if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.span.RecordError(err)
+			s.span.SetStatus(codes.Error, err.Error())
+		}
+		s.span.End()
+	}
+}
+
+// firstWord returns the SQL keyword starting query, such as "SELECT".
+func firstWord(query string) string {
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+	return "SQL"
+}
+
+// dbSystem returns the db.system.name attribute for a GORM dialector name.
+func dbSystem(dialector string) attribute.KeyValue {
+	switch dialector {
+	case "postgres":
+		return semconv.DBSystemNamePostgreSQL
+	case "sqlite":
+		return semconv.DBSystemNameSQLite
+	}
+	return semconv.DBSystemNameKey.String(dialector)
+}
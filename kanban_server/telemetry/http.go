@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span for each request, continuing the trace
+// of the traceparent header if there is one. Spans are named after the
+// route template, such as "GET /projects/{id}", so that IDs do not make
+// every name unique; requests no route matched are named by method alone.
+func Middleware(tp trace.TracerProvider) func(http.Handler) http.Handler {
+	traced := otelhttp.NewMiddleware("",
+		otelhttp.WithTracerProvider(tp),
+		otelhttp.WithPropagators(Propagator),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if template := routeTemplate(r); template != "" {
+				return r.Method + " " + template
+			}
+			return r.Method
+		}),
+	)
+	return func(next http.Handler) http.Handler {
+		return traced(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if template := routeTemplate(r); template != "" {
+				trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPRoute(template))
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// routeTemplate returns the template of the route mux matched for r, or ""
+// if none did.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return ""
+}
+
+// Transport wraps base, or http.DefaultTransport if base is nil, so that
+// each request sent is a client span whose context travels in the
+// traceparent header.
+func Transport(tp trace.TracerProvider, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base,
+		otelhttp.WithTracerProvider(tp),
+		otelhttp.WithPropagators(Propagator),
+	)
+}
@@ -0,0 +1,63 @@
+// Package telemetry records OpenTelemetry traces of the HTTP requests the
+// server handles, the database queries made for them and the webhooks it
+// sends. Trace context is read from and written to W3C traceparent headers.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"kanban_server/config"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// instrumentation names the tracers of this package.
+const instrumentation = "kanban_server/telemetry"
+
+// Propagator reads and writes the W3C traceparent, tracestate and baggage
+// headers.
+var Propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// NewExporter returns the exporter cfg selects, or nil when tracing is off.
+// The OTLP exporter connects lazily, so a collector that is down does not
+// stop the server starting.
+func NewExporter(ctx context.Context, cfg config.Tracing) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "none":
+		return nil, nil
+	case "otlp":
+		exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+		if err != nil {
+			return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+		}
+		return exporter, nil
+	}
+	return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+}
+
+// NewTracerProvider returns a provider sending the spans it samples to
+// exporter in batches, which ForceFlush and Shutdown send at once. Traces
+// continued from a request follow the caller's sampling decision; new ones
+// are sampled at cfg.SampleRatio. With a nil exporter nothing is recorded.
+func NewTracerProvider(cfg config.Tracing, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))
+	if exporter == nil {
+		sampler = sdktrace.NeverSample()
+	}
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName))),
+	}
+	if exporter != nil {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(options...)
+}
@@ -0,0 +1,162 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kanban_server/config"
+	"kanban_server/models"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTracing returns a provider recording every span in memory and a
+// function returning the spans ended so far.
+func newTracing(t *testing.T) (*sdktrace.TracerProvider, func() tracetest.SpanStubs) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := NewTracerProvider(config.Default().Tracing, exporter)
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return tp, func() tracetest.SpanStubs {
+		if err := tp.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return exporter.GetSpans()
+	}
+}
+
+func newDB(t *testing.T, tp trace.TracerProvider) *gorm.DB {
+	t.Helper()
+	db, err := config.Open(config.DriverSQLite, filepath.Join(t.TempDir(), "kanban.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Discard
+	if err := db.Use(NewGORMPlugin(tp)); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func attr(span tracetest.SpanStub, key attribute.Key) string {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestMiddlewareNamesSpansByRoute(t *testing.T) {
+	tp, spans := newTracing(t)
+	db := newDB(t, tp)
+	router := mux.NewRouter()
+	router.Use(Middleware(tp))
+	router.HandleFunc("/projects/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var project models.Project
+		db.WithContext(r.Context()).First(&project, mux.Vars(r)["id"])
+	}).Methods("GET")
+
+	const parent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	req := httptest.NewRequest("GET", "/projects/7", nil)
+	req.Header.Set("traceparent", parent)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := spans()
+	if len(got) != 2 {
+		t.Fatalf("got %d spans, want the query and the request", len(got))
+	}
+	query, request := got[0], got[1]
+	if request.Name != "GET /projects/{id}" || request.SpanKind != trace.SpanKindServer {
+		t.Errorf("request span is %s %q, want a server span named after the route", request.SpanKind, request.Name)
+	}
+	if route := attr(request, "http.route"); route != "/projects/{id}" {
+		t.Errorf("http.route = %q", route)
+	}
+	if id := request.SpanContext.TraceID().String(); id != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("request span is in trace %s, not the one in traceparent", id)
+	}
+	if request.Parent.SpanID().String() != "b7ad6b7169203331" {
+		t.Errorf("request span has parent %s, not the one in traceparent", request.Parent.SpanID())
+	}
+
+	if query.Name != "SELECT projects" || query.Parent.SpanID() != request.SpanContext.SpanID() {
+		t.Errorf("query span is %q with parent %s, want SELECT projects under the request", query.Name, query.Parent.SpanID())
+	}
+	if system := attr(query, "db.system.name"); system != "sqlite" {
+		t.Errorf("db.system.name = %q", system)
+	}
+	if text := attr(query, "db.query.text"); !strings.Contains(text, "= ?") {
+		t.Errorf("db.query.text = %q, want the SQL without its arguments", text)
+	}
+	if len(query.Events) != 0 {
+		t.Errorf("record not found was recorded as an error: %v", query.Events)
+	}
+}
+
+func TestGORMPluginIgnoresUntracedQueries(t *testing.T) {
+	tp, spans := newTracing(t)
+	db := newDB(t, tp)
+	db.Create(&models.Project{Title: "Board", Status: models.ProjectStatusActive})
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "job")
+	db.WithContext(ctx).Model(&models.Project{}).Where("id = ?", 1).Update("title", "Renamed")
+	db.WithContext(ctx).Exec("DELETE FROM tasks")
+	span.End()
+
+	var names []string
+	for _, s := range spans() {
+		names = append(names, s.Name)
+	}
+	want := []string{"UPDATE projects", "DELETE", "job"}
+	if len(names) != len(want) {
+		t.Fatalf("got spans %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("got spans %v, want %v", names, want)
+			break
+		}
+	}
+}
+
+func TestTransportPropagatesTraceContext(t *testing.T) {
+	tp, spans := newTracing(t)
+	var traceparent string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer receiver.Close()
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "delivery")
+	req, _ := http.NewRequestWithContext(ctx, "POST", receiver.URL, nil)
+	resp, err := (&http.Client{Transport: Transport(tp, nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	span.End()
+
+	got := spans()
+	if len(got) != 2 {
+		t.Fatalf("got %d spans, want the request and its parent", len(got))
+	}
+	client := got[0]
+	want := "00-" + client.SpanContext.TraceID().String() + "-" + client.SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("sent traceparent %q, want %q", traceparent, want)
+	}
+	if client.Parent.SpanID() != span.SpanContext().SpanID() {
+		t.Error("client span is not a child of the caller's span")
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/problem"
+	"kanban_server/transfer"
+
+	"github.com/gorilla/mux"
+)
+
+type TemplateRequest struct {
+	Name         string `json:"name" validate:"required,notblank,max=100"`
+	Description  string `json:"description" validate:"max=10000"`
+	IncludeTasks bool   `json:"include_tasks"`
+}
+
+type InstantiateTemplateRequest struct {
+	Title       string     `json:"title" validate:"required,notblank,max=200"`
+	Description *string    `json:"description" validate:"omitempty,max=10000"`
+	StartDate   *time.Time `json:"start_date" validate:"omitempty,sanedate"`
+}
+
+type CloneProjectRequest struct {
+	Title string                `json:"title" validate:"max=200"`
+	Copy  *transfer.CopyOptions `json:"copy"`
+}
+
+func (s *Server) createTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req TemplateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	doc, err := transfer.Export(s.db(r), project.ID)
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to read project"))
+		return
+	}
+	doc.Strip(transfer.CopyOptions{Columns: true, Labels: true, Tasks: req.IncludeTasks})
+	doc.Relativize(time.Now())
+	for i := range doc.Tasks {
+		doc.Tasks[i].Archived = false
+		doc.Tasks[i].Status = models.TaskStatusTodo
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to create template"))
+		return
+	}
+
+	template := models.ProjectTemplate{
+		Name:         req.Name,
+		Description:  req.Description,
+		OwnerID:      currentUserID(r),
+		IncludeTasks: req.IncludeTasks,
+		Document:     string(body),
+	}
+	if err := s.db(r).Create(&template).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to create template"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Template created successfully",
+		Data:    template,
+	})
+}
+
+func (s *Server) getTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var templates []models.ProjectTemplate
+	if err := s.db(r).Where("owner_id = ?", currentUserID(r)).Order("name").Find(&templates).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch templates"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: templates,
+	})
+}
+
+func (s *Server) getTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	var template models.ProjectTemplate
+	if err := s.db(r).Where("owner_id = ?", currentUserID(r)).First(&template, vars["id"]).Error; err != nil {
+		writeLookupError(w, r, err, "Template not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: map[string]interface{}{
+			"template": template,
+			"document": json.RawMessage(template.Document),
+		},
+	})
+}
+
+func (s *Server) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	result := s.db(r).Where("owner_id = ?", currentUserID(r)).Delete(&models.ProjectTemplate{}, vars["id"])
+	if result.Error != nil {
+		problem.Write(w, r, problem.Internal("Failed to delete template"))
+		return
+	}
+	if result.RowsAffected == 0 {
+		problem.Write(w, r, problem.NotFound("Template not found"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Template deleted successfully",
+	})
+}
+
+func (s *Server) instantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	var req InstantiateTemplateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	var template models.ProjectTemplate
+	if err := s.db(r).Where("owner_id = ?", currentUserID(r)).First(&template, vars["id"]).Error; err != nil {
+		writeLookupError(w, r, err, "Template not found")
+		return
+	}
+
+	var doc transfer.Document
+	if err := json.Unmarshal([]byte(template.Document), &doc); err != nil {
+		problem.Write(w, r, problem.Internal("Template is corrupt"))
+		return
+	}
+
+	doc.Project.Title = req.Title
+	if req.Description != nil {
+		doc.Project.Description = *req.Description
+	}
+	doc.Project.Status = models.ProjectStatusActive
+	start := time.Now()
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	doc.Anchor(start)
+
+	report, err := transfer.Import(s.db(r), &doc, currentUserID(r))
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to create project from template"))
+		return
+	}
+
+	s.respondWithProject(w, r, report.ProjectID, "Project created from template")
+}
+
+func (s *Server) cloneProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req CloneProjectRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	doc, err := transfer.Export(s.db(r), project.ID)
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to read project"))
+		return
+	}
+
+	opts := transfer.DefaultCopyOptions
+	if req.Copy != nil {
+		opts = *req.Copy
+	}
+	doc.Strip(opts)
+
+	doc.Project.Title = req.Title
+	if strings.TrimSpace(doc.Project.Title) == "" {
+		doc.Project.Title = "Copy of " + project.Title
+	}
+
+	report, err := transfer.Import(s.db(r), doc, currentUserID(r))
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to clone project"))
+		return
+	}
+
+	s.respondWithProject(w, r, report.ProjectID, "Project cloned successfully")
+}
+
+func (s *Server) respondWithProject(w http.ResponseWriter, r *http.Request, projectID uint, message string) {
+	project, err := s.Projects.FindProject(r.Context(), projectID)
+	if err == nil {
+		project.Tasks, err = s.Tasks.ListTasks(r.Context(), projectID)
+	}
+	if err == nil {
+		err = s.db(r).Where("project_id = ?", projectID).Order("id").Find(&project.Columns).Error
+	}
+	if err == nil {
+		err = s.db(r).Where("project_id = ?", projectID).Order("id").Find(&project.Labels).Error
+	}
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to read project"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: message,
+		Data:    project,
+	})
+}
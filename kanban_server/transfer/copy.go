@@ -0,0 +1,83 @@
+package transfer
+
+import (
+	"math"
+	"time"
+)
+
+// CopyOptions selects which parts of a project are kept when a document is
+// used to clone a project or to build a template.
+type CopyOptions struct {
+	Columns  bool `json:"columns"`
+	Labels   bool `json:"labels"`
+	Tasks    bool `json:"tasks"`
+	Comments bool `json:"comments"`
+	Members  bool `json:"members"`
+}
+
+// DefaultCopyOptions copies the board structure and tasks but not the people
+// or the discussion.
+var DefaultCopyOptions = CopyOptions{Columns: true, Labels: true, Tasks: true}
+
+// Strip removes the parts of doc not selected by opts. Comments are only
+// kept together with tasks, and assignees only together with members.
+func (doc *Document) Strip(opts CopyOptions) {
+	if !opts.Members {
+		doc.Members = []Member{}
+		for i := range doc.Tasks {
+			doc.Tasks[i].AssigneeEmail = ""
+		}
+	}
+	if !opts.Tasks || !opts.Comments {
+		doc.Comments = []Comment{}
+	}
+	if !opts.Tasks {
+		doc.Tasks = []Task{}
+	}
+	if !opts.Columns {
+		doc.Columns = []Column{}
+		for i := range doc.Tasks {
+			doc.Tasks[i].ColumnID = nil
+		}
+	}
+	if !opts.Labels {
+		doc.Labels = []Label{}
+		for i := range doc.Tasks {
+			doc.Tasks[i].LabelIDs = nil
+		}
+	}
+}
+
+// Relativize replaces absolute due dates with whole days after anchor so
+// that the document can be instantiated at a later date.
+func (doc *Document) Relativize(anchor time.Time) {
+	anchor = startOfDay(anchor)
+	for i := range doc.Tasks {
+		task := &doc.Tasks[i]
+		if task.DueDate == nil {
+			continue
+		}
+		days := int(math.Round(startOfDay(*task.DueDate).Sub(anchor).Hours() / 24))
+		task.DueInDays = &days
+		task.DueDate = nil
+	}
+}
+
+// Anchor turns relative due dates back into absolute ones counted from
+// start. Due times are set to the end of that day in start's location.
+func (doc *Document) Anchor(start time.Time) {
+	start = startOfDay(start)
+	for i := range doc.Tasks {
+		task := &doc.Tasks[i]
+		if task.DueInDays == nil {
+			continue
+		}
+		due := start.AddDate(0, 0, *task.DueInDays).Add(24*time.Hour - time.Second)
+		task.DueDate = &due
+		task.DueInDays = nil
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
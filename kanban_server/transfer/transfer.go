@@ -0,0 +1,468 @@
+// Package transfer converts projects to and from a portable, versioned
+// document so that boards can be moved between environments.
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"kanban_server/models"
+
+	"gorm.io/gorm"
+)
+
+// SchemaVersion is the version written by Export and the only version
+// accepted by Import.
+const SchemaVersion = 1
+
+// Document is the JSON export of one project. IDs are those of the source
+// environment and are only used to link records within the document; users
+// are identified by email.
+type Document struct {
+	SchemaVersion int       `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+	Project       Project   `json:"project"`
+	Members       []Member  `json:"members"`
+	Columns       []Column  `json:"columns"`
+	Labels        []Label   `json:"labels"`
+	Tasks         []Task    `json:"tasks"`
+	Comments      []Comment `json:"comments"`
+}
+
+type Project struct {
+	ID          uint   `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+type Member struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type Column struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+type Label struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+type Task struct {
+	ID            uint       `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status"`
+	Priority      string     `json:"priority"`
+	DueDate       *time.Time `json:"due_date,omitempty"`
+	DueInDays     *int       `json:"due_in_days,omitempty"`
+	ColumnID      *uint      `json:"column_id,omitempty"`
+	AssigneeEmail string     `json:"assignee_email,omitempty"`
+	Archived      bool       `json:"archived"`
+	LabelIDs      []uint     `json:"label_ids,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type Comment struct {
+	TaskID      uint      `json:"task_id"`
+	AuthorEmail string    `json:"author_email"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Report describes the outcome of an import.
+type Report struct {
+	ProjectID       uint     `json:"project_id"`
+	Tasks           int      `json:"tasks"`
+	Comments        int      `json:"comments"`
+	UnmappedMembers []string `json:"unmapped_members,omitempty"`
+	SkippedComments int      `json:"skipped_comments,omitempty"`
+}
+
+// Export loads a project with its board, tasks, labels, comments and members.
+func Export(db *gorm.DB, projectID uint) (*Document, error) {
+	var project models.Project
+	err := db.Preload("Users").Preload("Columns").Preload("Labels").
+		Preload("Tasks.Labels").Preload("Tasks.Comments.User").
+		First(&project, projectID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		Project: Project{
+			ID:          project.ID,
+			Title:       project.Title,
+			Description: project.Description,
+			Status:      project.Status,
+		},
+		Members:  []Member{},
+		Columns:  []Column{},
+		Labels:   []Label{},
+		Tasks:    []Task{},
+		Comments: []Comment{},
+	}
+
+	emails := make(map[uint]string)
+	for _, user := range project.Users {
+		emails[user.ID] = user.Email
+		doc.Members = append(doc.Members, Member{Email: user.Email, Name: user.Name})
+	}
+
+	for _, column := range project.Columns {
+		doc.Columns = append(doc.Columns, Column{ID: column.ID, Name: column.Name, Position: column.Position})
+	}
+	for _, label := range project.Labels {
+		doc.Labels = append(doc.Labels, Label{ID: label.ID, Name: label.Name, Color: label.Color})
+	}
+
+	assignees, err := UserEmails(db, project.Tasks, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range project.Tasks {
+		t := Task{
+			ID:            task.ID,
+			Title:         task.Title,
+			Description:   task.Description,
+			Status:        task.Status,
+			Priority:      task.Priority,
+			ColumnID:      task.ColumnID,
+			AssigneeEmail: assignees[task.AssignedTo],
+			Archived:      task.Archived,
+			CreatedAt:     task.CreatedAt,
+		}
+		if !task.DueDate.IsZero() {
+			due := task.DueDate
+			t.DueDate = &due
+		}
+		for _, label := range task.Labels {
+			t.LabelIDs = append(t.LabelIDs, label.ID)
+		}
+		doc.Tasks = append(doc.Tasks, t)
+
+		for _, comment := range task.Comments {
+			doc.Comments = append(doc.Comments, Comment{
+				TaskID:      task.ID,
+				AuthorEmail: comment.User.Email,
+				Body:        comment.Body,
+				CreatedAt:   comment.CreatedAt,
+			})
+		}
+	}
+
+	return doc, nil
+}
+
+// UserEmails adds the emails of task assignees missing from known to it and
+// returns it.
+func UserEmails(db *gorm.DB, tasks []models.Task, known map[uint]string) (map[uint]string, error) {
+	var missing []uint
+	for _, task := range tasks {
+		if _, ok := known[task.AssignedTo]; task.AssignedTo != 0 && !ok {
+			missing = append(missing, task.AssignedTo)
+		}
+	}
+	if len(missing) > 0 {
+		var users []models.User
+		if err := db.Where("id IN ?", missing).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			known[user.ID] = user.Email
+		}
+	}
+	return known, nil
+}
+
+// Validate checks the schema version and the references inside the document.
+func (doc *Document) Validate() error {
+	if doc.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("unsupported schema_version %d, expected %d", doc.SchemaVersion, SchemaVersion)
+	}
+	if doc.Project.Title == "" {
+		return errors.New("project.title is required")
+	}
+
+	columns := make(map[uint]bool, len(doc.Columns))
+	for _, column := range doc.Columns {
+		if column.Name == "" {
+			return errors.New("columns[].name is required")
+		}
+		if columns[column.ID] {
+			return fmt.Errorf("duplicate column id %d", column.ID)
+		}
+		columns[column.ID] = true
+	}
+	labels := make(map[uint]bool, len(doc.Labels))
+	for _, label := range doc.Labels {
+		if label.Name == "" {
+			return errors.New("labels[].name is required")
+		}
+		if labels[label.ID] {
+			return fmt.Errorf("duplicate label id %d", label.ID)
+		}
+		labels[label.ID] = true
+	}
+
+	tasks := make(map[uint]bool, len(doc.Tasks))
+	for _, task := range doc.Tasks {
+		if task.Title == "" {
+			return fmt.Errorf("tasks[%d].title is required", task.ID)
+		}
+		if tasks[task.ID] {
+			return fmt.Errorf("duplicate task id %d", task.ID)
+		}
+		tasks[task.ID] = true
+		if task.Status != "" && !contains(models.TaskStatuses, task.Status) {
+			return fmt.Errorf("tasks[%d].status %q is invalid", task.ID, task.Status)
+		}
+		if task.Priority != "" && !contains(models.TaskPriorities, task.Priority) {
+			return fmt.Errorf("tasks[%d].priority %q is invalid", task.ID, task.Priority)
+		}
+		if task.DueDate != nil && task.DueInDays != nil {
+			return fmt.Errorf("tasks[%d] cannot have both due_date and due_in_days", task.ID)
+		}
+		if task.ColumnID != nil && !columns[*task.ColumnID] {
+			return fmt.Errorf("tasks[%d].column_id %d does not exist", task.ID, *task.ColumnID)
+		}
+		for _, id := range task.LabelIDs {
+			if !labels[id] {
+				return fmt.Errorf("tasks[%d].label_ids references unknown label %d", task.ID, id)
+			}
+		}
+	}
+
+	for _, comment := range doc.Comments {
+		if !tasks[comment.TaskID] {
+			return fmt.Errorf("comment references unknown task %d", comment.TaskID)
+		}
+	}
+	return nil
+}
+
+// Import creates a new project from doc inside a single transaction. Users
+// are matched by email; members, assignees and comment authors without an
+// account are listed in the report and dropped. The importing user, if any,
+// becomes a member of the new project. Relative due dates that were not
+// anchored are counted from today.
+func Import(db *gorm.DB, doc *Document, importerID uint) (*Report, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return importDocument(tx, doc, importerID, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// errDryRun rolls back the transaction used by DryRun.
+var errDryRun = errors.New("dry run")
+
+// DryRun performs an import and rolls it back, returning the report the
+// import would produce. The report's ProjectID is always zero.
+func DryRun(db *gorm.DB, doc *Document, importerID uint) (*Report, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := importDocument(tx, doc, importerID, report); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	report.ProjectID = 0
+	return report, nil
+}
+
+func importDocument(tx *gorm.DB, doc *Document, importerID uint, report *Report) error {
+	doc.Anchor(time.Now())
+
+	users, unmapped, err := usersByEmail(tx, doc)
+	if err != nil {
+		return err
+	}
+	report.UnmappedMembers = unmapped
+
+	status := doc.Project.Status
+	if !contains(models.ProjectStatuses, status) {
+		status = models.ProjectStatusActive
+	}
+	project := models.Project{
+		Title:       doc.Project.Title,
+		Description: doc.Project.Description,
+		Status:      status,
+	}
+	if err := tx.Create(&project).Error; err != nil {
+		return err
+	}
+	report.ProjectID = project.ID
+
+	var members []models.User
+	seen := make(map[uint]bool)
+	for _, member := range doc.Members {
+		if user, ok := users[member.Email]; ok && !seen[user.ID] {
+			members = append(members, user)
+			seen[user.ID] = true
+		}
+	}
+	if importerID != 0 && !seen[importerID] {
+		var importer models.User
+		if err := tx.First(&importer, importerID).Error; err != nil {
+			return err
+		}
+		members = append(members, importer)
+	}
+	if len(members) > 0 {
+		if err := tx.Model(&project).Association("Users").Append(members); err != nil {
+			return err
+		}
+	}
+
+	columnIDs := make(map[uint]uint, len(doc.Columns))
+	for _, c := range doc.Columns {
+		column := models.Column{ProjectID: project.ID, Name: c.Name, Position: c.Position}
+		if err := tx.Create(&column).Error; err != nil {
+			return err
+		}
+		columnIDs[c.ID] = column.ID
+	}
+
+	labels := make(map[uint]models.Label, len(doc.Labels))
+	for _, l := range doc.Labels {
+		label := models.Label{ProjectID: project.ID, Name: l.Name, Color: l.Color}
+		if err := tx.Where(models.Label{ProjectID: project.ID, Name: l.Name}).
+			Attrs(models.Label{Color: l.Color}).FirstOrCreate(&label).Error; err != nil {
+			return err
+		}
+		labels[l.ID] = label
+	}
+
+	taskIDs := make(map[uint]uint, len(doc.Tasks))
+	for _, t := range doc.Tasks {
+		task := models.Task{
+			Title:       t.Title,
+			Description: t.Description,
+			Status:      t.Status,
+			Priority:    t.Priority,
+			ProjectID:   project.ID,
+			Archived:    t.Archived,
+		}
+		if task.Status == "" {
+			task.Status = models.TaskStatusTodo
+		}
+		if task.Priority == "" {
+			task.Priority = models.TaskPriorityMedium
+		}
+		if t.DueDate != nil {
+			task.DueDate = *t.DueDate
+		}
+		if t.ColumnID != nil {
+			id := columnIDs[*t.ColumnID]
+			task.ColumnID = &id
+		}
+		if t.AssigneeEmail != "" {
+			task.AssignedTo = users[t.AssigneeEmail].ID
+		}
+		for _, id := range t.LabelIDs {
+			task.Labels = append(task.Labels, labels[id])
+		}
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		taskIDs[t.ID] = task.ID
+	}
+	report.Tasks = len(doc.Tasks)
+
+	for _, c := range doc.Comments {
+		author, ok := users[c.AuthorEmail]
+		if !ok {
+			report.SkippedComments++
+			continue
+		}
+		comment := models.Comment{
+			TaskID:    taskIDs[c.TaskID],
+			UserID:    author.ID,
+			Body:      c.Body,
+			CreatedAt: c.CreatedAt,
+		}
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		report.Comments++
+	}
+	return nil
+}
+
+// usersByEmail looks up every email referenced by the document and returns
+// the ones without an account.
+func usersByEmail(db *gorm.DB, doc *Document) (map[string]models.User, []string, error) {
+	referenced := make(map[string]bool)
+	for _, member := range doc.Members {
+		referenced[member.Email] = true
+	}
+	for _, task := range doc.Tasks {
+		if task.AssigneeEmail != "" {
+			referenced[task.AssigneeEmail] = true
+		}
+	}
+	for _, comment := range doc.Comments {
+		referenced[comment.AuthorEmail] = true
+	}
+
+	emails := make([]string, 0, len(referenced))
+	for email := range referenced {
+		if email != "" {
+			emails = append(emails, email)
+		}
+	}
+
+	users := make(map[string]models.User, len(emails))
+	if len(emails) > 0 {
+		var found []models.User
+		if err := db.Where("email IN ?", emails).Find(&found).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, user := range found {
+			users[user.Email] = user
+		}
+	}
+
+	var unmapped []string
+	for _, email := range emails {
+		if _, ok := users[email]; !ok {
+			unmapped = append(unmapped, email)
+		}
+	}
+	sort.Strings(unmapped)
+	return users, unmapped, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDocumentValidate(t *testing.T) {
+	columnID := uint(10)
+	missingColumn := uint(11)
+
+	valid := func() *Document {
+		return &Document{
+			SchemaVersion: SchemaVersion,
+			Project:       Project{Title: "Board"},
+			Columns:       []Column{{ID: columnID, Name: "Doing"}},
+			Labels:        []Label{{ID: 20, Name: "bug"}},
+			Tasks: []Task{
+				{ID: 1, Title: "First", Status: "todo", ColumnID: &columnID, LabelIDs: []uint{20}},
+				{ID: 2, Title: "Second", Priority: "high"},
+			},
+			Comments: []Comment{{TaskID: 1, AuthorEmail: "a@example.com", Body: "hi"}},
+		}
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("valid document rejected: %v", err)
+	}
+
+	tests := map[string]func(doc *Document){
+		"schema version":   func(doc *Document) { doc.SchemaVersion = SchemaVersion + 1 },
+		"missing title":    func(doc *Document) { doc.Project.Title = "" },
+		"duplicate task":   func(doc *Document) { doc.Tasks[1].ID = 1 },
+		"duplicate column": func(doc *Document) { doc.Columns = append(doc.Columns, Column{ID: columnID, Name: "Done"}) },
+		"duplicate label":  func(doc *Document) { doc.Labels = append(doc.Labels, Label{ID: 20, Name: "feature"}) },
+		"invalid status":   func(doc *Document) { doc.Tasks[0].Status = "blocked" },
+		"invalid priority": func(doc *Document) { doc.Tasks[1].Priority = "urgent" },
+		"unknown column":   func(doc *Document) { doc.Tasks[0].ColumnID = &missingColumn },
+		"unknown label":    func(doc *Document) { doc.Tasks[0].LabelIDs = []uint{21} },
+		"orphan comment":   func(doc *Document) { doc.Comments[0].TaskID = 3 },
+	}
+	for name, mutate := range tests {
+		doc := valid()
+		mutate(doc)
+		if err := doc.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestRelativizeAndAnchor(t *testing.T) {
+	created := time.Date(2025, 1, 10, 15, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 1, 17, 9, 0, 0, 0, time.UTC)
+	doc := &Document{Tasks: []Task{{ID: 1, Title: "Kickoff", DueDate: &due}, {ID: 2, Title: "Someday"}}}
+
+	doc.Relativize(created)
+	if doc.Tasks[0].DueDate != nil || doc.Tasks[0].DueInDays == nil || *doc.Tasks[0].DueInDays != 7 {
+		t.Fatalf("relativized task = %+v", doc.Tasks[0])
+	}
+	if doc.Tasks[1].DueInDays != nil {
+		t.Errorf("task without due date got offset %d", *doc.Tasks[1].DueInDays)
+	}
+
+	doc.Anchor(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+	got := doc.Tasks[0].DueDate
+	if got == nil || got.Format("2006-01-02") != "2025-03-08" {
+		t.Errorf("anchored due date = %v, want 2025-03-08", got)
+	}
+}
+
+func TestStrip(t *testing.T) {
+	columnID := uint(1)
+	doc := &Document{
+		Members:  []Member{{Email: "a@example.com"}},
+		Columns:  []Column{{ID: 1, Name: "Doing"}},
+		Labels:   []Label{{ID: 2, Name: "bug"}},
+		Tasks:    []Task{{ID: 3, Title: "Task", ColumnID: &columnID, LabelIDs: []uint{2}, AssigneeEmail: "a@example.com"}},
+		Comments: []Comment{{TaskID: 3, Body: "hi"}},
+	}
+
+	doc.Strip(CopyOptions{Tasks: true})
+	if len(doc.Members) != 0 || len(doc.Columns) != 0 || len(doc.Labels) != 0 || len(doc.Comments) != 0 {
+		t.Errorf("unselected parts were kept: %+v", doc)
+	}
+	task := doc.Tasks[0]
+	if task.ColumnID != nil || task.LabelIDs != nil || task.AssigneeEmail != "" {
+		t.Errorf("task still references stripped data: %+v", task)
+	}
+}
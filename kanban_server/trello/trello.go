@@ -0,0 +1,343 @@
+// Package trello converts Trello board JSON exports into transfer documents
+// that can be imported as kanban projects.
+package trello
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/transfer"
+)
+
+// Board is the subset of a Trello board export that the importer reads.
+// Everything else in the file is reported as unmapped.
+type Board struct {
+	Name         string            `json:"name"`
+	Desc         string            `json:"desc"`
+	Closed       bool              `json:"closed"`
+	Lists        []List            `json:"lists"`
+	Cards        []Card            `json:"cards"`
+	Labels       []Label           `json:"labels"`
+	Checklists   []Checklist       `json:"checklists"`
+	Members      []Member          `json:"members"`
+	Actions      []Action          `json:"actions"`
+	CustomFields []json.RawMessage `json:"customFields"`
+	PluginData   []json.RawMessage `json:"pluginData"`
+}
+
+type List struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type Card struct {
+	ID                string            `json:"id"`
+	Name              string            `json:"name"`
+	Desc              string            `json:"desc"`
+	Closed            bool              `json:"closed"`
+	IDList            string            `json:"idList"`
+	IDLabels          []string          `json:"idLabels"`
+	IDMembers         []string          `json:"idMembers"`
+	IDChecklists      []string          `json:"idChecklists"`
+	Due               *time.Time        `json:"due"`
+	DueComplete       bool              `json:"dueComplete"`
+	Pos               float64           `json:"pos"`
+	DateLastActivity  time.Time         `json:"dateLastActivity"`
+	Attachments       []json.RawMessage `json:"attachments"`
+	CustomFieldItems  []json.RawMessage `json:"customFieldItems"`
+	IDMembersVoted    []string          `json:"idMembersVoted"`
+	Start             *time.Time        `json:"start"`
+	CoverAttachmentID string            `json:"idAttachmentCover"`
+}
+
+type Label struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type Checklist struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	IDCard     string      `json:"idCard"`
+	Pos        float64     `json:"pos"`
+	CheckItems []CheckItem `json:"checkItems"`
+}
+
+type CheckItem struct {
+	Name  string  `json:"name"`
+	State string  `json:"state"`
+	Pos   float64 `json:"pos"`
+}
+
+type Member struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"fullName"`
+}
+
+type Action struct {
+	ID              string    `json:"id"`
+	Type            string    `json:"type"`
+	Date            time.Time `json:"date"`
+	IDMemberCreator string    `json:"idMemberCreator"`
+	Data            struct {
+		Text string `json:"text"`
+		Card struct {
+			ID string `json:"id"`
+		} `json:"card"`
+	} `json:"data"`
+}
+
+// Report summarises what a conversion maps and, more importantly, what it
+// cannot map so that it can be reviewed before committing an import.
+type Report struct {
+	Board           string           `json:"board"`
+	Columns         int              `json:"columns"`
+	Tasks           int              `json:"tasks"`
+	Labels          int              `json:"labels"`
+	Checklists      int              `json:"checklists"`
+	Comments        int              `json:"comments"`
+	UnmappedMembers []Member         `json:"unmapped_members,omitempty"`
+	UnmappedFields  map[string]int   `json:"unmapped_fields,omitempty"`
+	Warnings        []string         `json:"warnings,omitempty"`
+	Import          *transfer.Report `json:"import,omitempty"`
+}
+
+// Parse decodes a Trello board export.
+func Parse(r io.Reader) (*Board, error) {
+	var board Board
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("invalid Trello export: %v", err)
+	}
+	if board.Name == "" && len(board.Lists) == 0 && len(board.Cards) == 0 {
+		return nil, fmt.Errorf("invalid Trello export: no board found")
+	}
+	return &board, nil
+}
+
+// Convert maps a Trello board to a transfer document. Lists become columns,
+// cards become tasks and checklists are appended to the task description.
+// Trello exports carry no emails, so members are mapped through emails,
+// keyed by Trello username or member ID; members without an entry are
+// reported and their assignments and comments dropped.
+func Convert(board *Board, emails map[string]string) (*transfer.Document, *Report) {
+	report := &Report{Board: board.Name, UnmappedFields: map[string]int{}}
+	doc := &transfer.Document{
+		SchemaVersion: transfer.SchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		Project: transfer.Project{
+			Title:       board.Name,
+			Description: board.Desc,
+			Status:      models.ProjectStatusActive,
+		},
+		Members:  []transfer.Member{},
+		Columns:  []transfer.Column{},
+		Labels:   []transfer.Label{},
+		Tasks:    []transfer.Task{},
+		Comments: []transfer.Comment{},
+	}
+	if doc.Project.Title == "" {
+		doc.Project.Title = "Imported Trello board"
+	}
+	if board.Closed {
+		doc.Project.Status = models.ProjectStatusArchived
+	}
+
+	memberEmails := make(map[string]string, len(board.Members))
+	for _, member := range board.Members {
+		email := emails[member.Username]
+		if email == "" {
+			email = emails[member.ID]
+		}
+		if email == "" {
+			report.UnmappedMembers = append(report.UnmappedMembers, member)
+			continue
+		}
+		memberEmails[member.ID] = email
+		doc.Members = append(doc.Members, transfer.Member{Email: email, Name: member.FullName})
+	}
+
+	lists := append([]List(nil), board.Lists...)
+	sort.SliceStable(lists, func(i, j int) bool { return lists[i].Pos < lists[j].Pos })
+	columnIDs := make(map[string]uint, len(lists))
+	closedLists := make(map[string]bool)
+	listStatus := make(map[string]string, len(lists))
+	for i, list := range lists {
+		id := uint(i + 1)
+		columnIDs[list.ID] = id
+		closedLists[list.ID] = list.Closed
+		listStatus[list.ID] = statusForList(list.Name)
+		name := list.Name
+		if name == "" {
+			name = "Untitled list"
+		}
+		doc.Columns = append(doc.Columns, transfer.Column{ID: id, Name: name, Position: i})
+	}
+	report.Columns = len(doc.Columns)
+
+	labelIDs := make(map[string]uint, len(board.Labels))
+	labelsByName := make(map[string]uint)
+	for _, label := range board.Labels {
+		name := label.Name
+		if name == "" {
+			name = label.Color
+		}
+		if name == "" {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("label %s has no name or color and was skipped", label.ID))
+			continue
+		}
+		// Trello allows several labels with the same name, projects do not.
+		if id, ok := labelsByName[name]; ok {
+			labelIDs[label.ID] = id
+			continue
+		}
+		id := uint(len(doc.Labels) + 1)
+		labelIDs[label.ID] = id
+		labelsByName[name] = id
+		doc.Labels = append(doc.Labels, transfer.Label{ID: id, Name: name, Color: label.Color})
+	}
+	report.Labels = len(doc.Labels)
+
+	checklists := make(map[string][]Checklist)
+	for _, checklist := range board.Checklists {
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], checklist)
+	}
+
+	cards := append([]Card(nil), board.Cards...)
+	sort.SliceStable(cards, func(i, j int) bool { return cards[i].Pos < cards[j].Pos })
+	taskIDs := make(map[string]uint, len(cards))
+	for i, card := range cards {
+		id := uint(i + 1)
+		taskIDs[card.ID] = id
+
+		task := transfer.Task{
+			ID:          id,
+			Title:       card.Name,
+			Description: card.Desc,
+			Status:      listStatus[card.IDList],
+			Priority:    models.TaskPriorityMedium,
+			DueDate:     card.Due,
+			Archived:    card.Closed || closedLists[card.IDList],
+			CreatedAt:   card.DateLastActivity,
+		}
+		if task.Title == "" {
+			task.Title = "Untitled card"
+		}
+		if task.Status == "" {
+			task.Status = models.TaskStatusTodo
+		}
+		if card.DueComplete {
+			task.Status = models.TaskStatusDone
+		}
+		if columnID, ok := columnIDs[card.IDList]; ok {
+			task.ColumnID = &columnID
+		}
+		for _, labelID := range card.IDLabels {
+			if id, ok := labelIDs[labelID]; ok {
+				task.LabelIDs = append(task.LabelIDs, id)
+			}
+		}
+		for _, memberID := range card.IDMembers {
+			email, ok := memberEmails[memberID]
+			if !ok {
+				continue
+			}
+			if task.AssigneeEmail == "" {
+				task.AssigneeEmail = email
+			} else {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("card %q has several members, only the first is assigned", card.Name))
+				break
+			}
+		}
+		if cardChecklists := checklists[card.ID]; len(cardChecklists) > 0 {
+			task.Description = appendChecklists(task.Description, cardChecklists)
+			report.Checklists += len(cardChecklists)
+		}
+
+		countUnmapped(report, "attachments", len(card.Attachments))
+		countUnmapped(report, "custom field values", len(card.CustomFieldItems))
+		countUnmapped(report, "votes", len(card.IDMembersVoted))
+		if card.Start != nil {
+			countUnmapped(report, "start dates", 1)
+		}
+		if card.CoverAttachmentID != "" {
+			countUnmapped(report, "card covers", 1)
+		}
+
+		doc.Tasks = append(doc.Tasks, task)
+	}
+	report.Tasks = len(doc.Tasks)
+
+	for _, action := range board.Actions {
+		if action.Type != "commentCard" {
+			continue
+		}
+		taskID, ok := taskIDs[action.Data.Card.ID]
+		if !ok {
+			continue
+		}
+		doc.Comments = append(doc.Comments, transfer.Comment{
+			TaskID:      taskID,
+			AuthorEmail: memberEmails[action.IDMemberCreator],
+			Body:        action.Data.Text,
+			CreatedAt:   action.Date,
+		})
+	}
+	report.Comments = len(doc.Comments)
+
+	countUnmapped(report, "custom fields", len(board.CustomFields))
+	countUnmapped(report, "power-up data", len(board.PluginData))
+
+	return doc, report
+}
+
+// statusForList guesses a task status from the name of its Trello list.
+func statusForList(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "done"), strings.Contains(name, "complete"), strings.Contains(name, "finished"):
+		return models.TaskStatusDone
+	case strings.Contains(name, "doing"), strings.Contains(name, "progress"), strings.Contains(name, "review"):
+		return models.TaskStatusInProgress
+	default:
+		return models.TaskStatusTodo
+	}
+}
+
+func appendChecklists(description string, checklists []Checklist) string {
+	sort.SliceStable(checklists, func(i, j int) bool { return checklists[i].Pos < checklists[j].Pos })
+
+	var b strings.Builder
+	b.WriteString(description)
+	for _, checklist := range checklists {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "### %s\n", checklist.Name)
+
+		items := append([]CheckItem(nil), checklist.CheckItems...)
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+		for _, item := range items {
+			mark := " "
+			if item.State == "complete" {
+				mark = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] %s\n", mark, item.Name)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func countUnmapped(report *Report, field string, n int) {
+	if n > 0 {
+		report.UnmappedFields[field] += n
+	}
+}
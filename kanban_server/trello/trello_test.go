@@ -0,0 +1,76 @@
+package trello
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	f, err := os.Open("testdata/board.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	board, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, report := Convert(board, map[string]string{"alice": "alice@example.com"})
+	if err := doc.Validate(); err != nil {
+		t.Fatalf("converted document is invalid: %v", err)
+	}
+
+	if doc.Project.Title != "Website Relaunch" {
+		t.Errorf("project title = %q", doc.Project.Title)
+	}
+
+	var columns []string
+	for _, column := range doc.Columns {
+		columns = append(columns, column.Name)
+	}
+	if got := strings.Join(columns, ","); got != "Backlog,Doing,Done,Old ideas" {
+		t.Errorf("columns = %s, want lists ordered by position", got)
+	}
+
+	if len(doc.Labels) != 2 {
+		t.Errorf("expected duplicate label names to be merged, got %d labels", len(doc.Labels))
+	}
+
+	if len(doc.Tasks) != 3 {
+		t.Fatalf("expected 3 tasks, got %d", len(doc.Tasks))
+	}
+	header, launch, blog := doc.Tasks[0], doc.Tasks[1], doc.Tasks[2]
+
+	if header.Status != "in_progress" || header.AssigneeEmail != "alice@example.com" {
+		t.Errorf("header task = %+v", header)
+	}
+	if !strings.Contains(header.Description, "- [x] Export SVG") || !strings.Contains(header.Description, "- [ ] Replace asset") {
+		t.Errorf("checklist not appended to description: %q", header.Description)
+	}
+	if header.DueDate == nil || header.DueDate.Day() != 1 {
+		t.Errorf("due date not mapped: %v", header.DueDate)
+	}
+	if launch.Status != "done" {
+		t.Errorf("completed due date should mark task done, got %q", launch.Status)
+	}
+	if !blog.Archived {
+		t.Error("cards in closed lists should be archived")
+	}
+
+	if len(doc.Comments) != 1 || doc.Comments[0].AuthorEmail != "alice@example.com" {
+		t.Errorf("comments = %+v", doc.Comments)
+	}
+
+	if len(report.UnmappedMembers) != 1 || report.UnmappedMembers[0].Username != "bob" {
+		t.Errorf("unmapped members = %+v", report.UnmappedMembers)
+	}
+	if report.UnmappedFields["attachments"] != 1 || report.UnmappedFields["custom fields"] != 1 {
+		t.Errorf("unmapped fields = %v", report.UnmappedFields)
+	}
+	if report.Checklists != 1 {
+		t.Errorf("checklists = %d", report.Checklists)
+	}
+}
@@ -0,0 +1,155 @@
+// Package validation checks request payloads against the rules declared in
+// their `validate` struct tags and reports every violation in one problem.
+//
+// Besides the go-playground/validator tags, payloads can use:
+//
+//	notblank      the string contains more than whitespace
+//	taskstatus    one of models.TaskStatuses
+//	taskpriority  one of models.TaskPriorities
+//	projectstatus one of models.ProjectStatuses
+//	sanedate      a time between MinYear and MaxYear
+//
+// Fields are named after their json tags, so the reported paths match the
+// request body ("title", "add_labels.2").
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"kanban_server/models"
+	"kanban_server/problem"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Dates outside [MinYear, MaxYear] are taken for typos, such as a two-digit
+// year, rather than plans.
+const (
+	MinYear = 1970
+	MaxYear = 2199
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	v.RegisterValidation("taskstatus", oneOf(models.TaskStatuses))
+	v.RegisterValidation("taskpriority", oneOf(models.TaskPriorities))
+	v.RegisterValidation("projectstatus", oneOf(models.ProjectStatuses))
+	v.RegisterValidation("sanedate", func(fl validator.FieldLevel) bool {
+		t, ok := fl.Field().Interface().(time.Time)
+		return ok && t.Year() >= MinYear && t.Year() <= MaxYear
+	})
+	return v
+}
+
+func oneOf(allowed []string) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		value := fl.Field().String()
+		for _, a := range allowed {
+			if value == a {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// enums lists the values accepted by the enum tags, for messages.
+var enums = map[string][]string{
+	"taskstatus":    models.TaskStatuses,
+	"taskpriority":  models.TaskPriorities,
+	"projectstatus": models.ProjectStatuses,
+}
+
+// Struct checks v, a struct or pointer to one. It returns nil or a 422
+// *problem.Problem listing every violation.
+func Struct(v interface{}) error {
+	errs, err := check(v)
+	if err != nil || len(errs) == 0 {
+		return err
+	}
+	return problem.Invalid("Request validation failed", errs...)
+}
+
+// FieldErrors returns the violations of v, for callers that report some of
+// them along with problems of their own.
+func FieldErrors(v interface{}) []problem.FieldError {
+	errs, _ := check(v)
+	return errs
+}
+
+func check(v interface{}) ([]problem.FieldError, error) {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil, nil
+	}
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return nil, err
+	}
+	errs := make([]problem.FieldError, len(fieldErrs))
+	for i, e := range fieldErrs {
+		errs[i] = problem.FieldError{Field: path(e), In: "body", Message: message(e)}
+	}
+	return errs, nil
+}
+
+// path turns "Request.add_labels[2]" into "add_labels.2".
+func path(e validator.FieldError) string {
+	namespace := e.Namespace()
+	if _, rest, ok := strings.Cut(namespace, "."); ok {
+		namespace = rest
+	}
+	namespace = strings.ReplaceAll(namespace, "[", ".")
+	return strings.ReplaceAll(namespace, "]", "")
+}
+
+func message(e validator.FieldError) string {
+	unit := "characters"
+	switch e.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		unit = ""
+	}
+
+	switch e.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "email":
+		return "must be a valid email address"
+	case "min", "gte":
+		return strings.TrimSpace(fmt.Sprintf("must be at least %s %s", e.Param(), unit))
+	case "max", "lte":
+		return strings.TrimSpace(fmt.Sprintf("must be at most %s %s", e.Param(), unit))
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(e.Param()), ", ")
+	case "taskstatus", "taskpriority", "projectstatus":
+		return "must be one of " + strings.Join(enums[e.Tag()], ", ")
+	case "sanedate":
+		return fmt.Sprintf("must be a date between %d and %d", MinYear, MaxYear)
+	}
+	return fmt.Sprintf("fails the %s rule", e.Tag())
+}
@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"kanban_server/problem"
+)
+
+type item struct {
+	Name string `json:"name" validate:"required,notblank,max=5"`
+}
+
+type payload struct {
+	Email    string     `json:"email" validate:"required,email"`
+	Password string     `json:"password" validate:"min=8"`
+	Status   string     `json:"status" validate:"omitempty,taskstatus"`
+	Priority string     `json:"priority" validate:"omitempty,taskpriority"`
+	Due      *time.Time `json:"due_date" validate:"omitempty,sanedate"`
+	Labels   []string   `json:"labels" validate:"max=2,dive,notblank"`
+	Items    []item     `json:"items" validate:"dive"`
+	Internal string     `json:"-" validate:"max=1"`
+}
+
+func TestStruct(t *testing.T) {
+	valid := payload{
+		Email:    "ada@example.com",
+		Password: "long enough",
+		Status:   "in_progress",
+		Priority: "high",
+		Due:      ptr(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)),
+		Labels:   []string{"bug"},
+		Items:    []item{{Name: "a"}},
+	}
+	if err := Struct(&valid); err != nil {
+		t.Fatalf("valid payload rejected: %v", err)
+	}
+
+	invalid := payload{
+		Email:    "ada",
+		Password: "short",
+		Status:   "blocked",
+		Priority: "urgent",
+		Due:      ptr(time.Date(25, 3, 1, 0, 0, 0, 0, time.UTC)),
+		Labels:   []string{"bug", " "},
+		Items:    []item{{Name: "fine"}, {Name: "too long"}},
+	}
+	err := Struct(&invalid)
+	p, ok := err.(*problem.Problem)
+	if !ok {
+		t.Fatalf("got %v, want a problem", err)
+	}
+	if p.Status != http.StatusUnprocessableEntity || p.Code != problem.CodeValidation {
+		t.Errorf("got status %d and code %s, want 422 and %s", p.Status, p.Code, problem.CodeValidation)
+	}
+	want := []problem.FieldError{
+		{Field: "email", In: "body", Message: "must be a valid email address"},
+		{Field: "password", In: "body", Message: "must be at least 8 characters"},
+		{Field: "status", In: "body", Message: "must be one of todo, in_progress, done"},
+		{Field: "priority", In: "body", Message: "must be one of low, medium, high"},
+		{Field: "due_date", In: "body", Message: "must be a date between 1970 and 2199"},
+		{Field: "labels.1", In: "body", Message: "must not be blank"},
+		{Field: "items.1.name", In: "body", Message: "must be at most 5 characters"},
+	}
+	if !reflect.DeepEqual(p.Errors, want) {
+		t.Errorf("got errors\n%+v\nwant\n%+v", p.Errors, want)
+	}
+}
+
+func TestRequired(t *testing.T) {
+	got := FieldErrors(&payload{Password: "long enough", Labels: []string{"a", "b", "c"}})
+	want := []problem.FieldError{
+		{Field: "email", In: "body", Message: "is required"},
+		{Field: "labels", In: "body", Message: "must be at most 2 items"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
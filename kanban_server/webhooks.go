@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"kanban_server/events"
+	"kanban_server/models"
+	"kanban_server/problem"
+	"kanban_server/webhooks"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const maxDeliveryLogEntries = 100
+
+type WebhookRequest struct {
+	URL    string   `json:"url" validate:"required,notblank,max=2048"`
+	Secret string   `json:"secret" validate:"max=255"`
+	Events []string `json:"events" validate:"max=50,dive,notblank"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookResponse includes the signing secret, which is only returned when it
+// is set.
+type WebhookResponse struct {
+	models.WebhookSubscription
+	Secret string `json:"secret,omitempty"`
+}
+
+// findProjectWebhook loads the webhook named by the webhookId route variable
+// in the project named by id, reporting an error to the client if there is
+// none or the current user is not a member of the project.
+func (s *Server) findProjectWebhook(w http.ResponseWriter, r *http.Request) (models.WebhookSubscription, bool) {
+	var sub models.WebhookSubscription
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return sub, false
+	}
+	if err := s.db(r).Where("project_id = ?", project.ID).First(&sub, mux.Vars(r)["webhookId"]).Error; err != nil {
+		writeLookupError(w, r, err, "Webhook not found")
+		return sub, false
+	}
+	return sub, true
+}
+
+// apply validates req and copies it onto sub. An empty secret keeps the
+// current one, or generates one for new subscriptions.
+func (req WebhookRequest) apply(sub *models.WebhookSubscription) error {
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return problem.Field("url", "url must be an absolute http or https URL")
+	}
+	// The deliverer checks every address it connects to; literal addresses
+	// and localhost are refused here already.
+	if addr, err := netip.ParseAddr(u.Hostname()); (err == nil && !webhooks.PublicAddr(addr)) || strings.EqualFold(u.Hostname(), "localhost") {
+		return problem.Field("url", "url must not point to a local or private address")
+	}
+
+	eventTypes := req.Events
+	if len(eventTypes) == 0 {
+		eventTypes = []string{webhooks.AllEvents}
+	}
+	for _, t := range eventTypes {
+		if t != webhooks.AllEvents && !contains(events.Types, t) {
+			return problem.Field("events", fmt.Sprintf("events must be %q or among %s", webhooks.AllEvents, strings.Join(events.Types, ", ")))
+		}
+	}
+
+	sub.URL = u.String()
+	sub.Events = strings.Join(eventTypes, ",")
+	if req.Secret != "" {
+		sub.Secret = req.Secret
+	} else if sub.Secret == "" {
+		sub.Secret = webhooks.NewSecret()
+	}
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+	return nil
+}
+
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req WebhookRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	sub := models.WebhookSubscription{ProjectID: project.ID, Active: true, CreatedBy: currentUserID(r)}
+	if err := req.apply(&sub); err != nil {
+		writeError(w, r, err, "Invalid webhook")
+		return
+	}
+	if err := s.db(r).Create(&sub).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to create webhook"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Webhook created successfully",
+		Data:    WebhookResponse{WebhookSubscription: sub, Secret: sub.Secret},
+	})
+}
+
+func (s *Server) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	project, ok := s.findProject(w, r)
+	if !ok {
+		return
+	}
+
+	var subs []models.WebhookSubscription
+	if err := s.db(r).Where("project_id = ?", project.ID).Order("id").Find(&subs).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch webhooks"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: subs,
+	})
+}
+
+func (s *Server) getWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sub, ok := s.findProjectWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: sub,
+	})
+}
+
+func (s *Server) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req WebhookRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	sub, ok := s.findProjectWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err := req.apply(&sub); err != nil {
+		writeError(w, r, err, "Invalid webhook")
+		return
+	}
+	if err := s.db(r).Save(&sub).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to update webhook"))
+		return
+	}
+
+	resp := WebhookResponse{WebhookSubscription: sub}
+	if req.Secret != "" {
+		resp.Secret = sub.Secret
+	}
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Webhook updated successfully",
+		Data:    resp,
+	})
+}
+
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sub, ok := s.findProjectWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.db(r).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", sub.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&sub).Error
+	})
+	if err != nil {
+		problem.Write(w, r, problem.Internal("Failed to delete webhook"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// getWebhookDeliveries returns the delivery log, newest first. ?status=
+// filters by delivery state and ?before=<id> pages back.
+func (s *Server) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	sub, ok := s.findProjectWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	db := s.db(r).Where("subscription_id = ?", sub.ID)
+	if status := query.Get("status"); status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if v := query.Get("before"); v != "" {
+		before, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			problem.Write(w, r, problem.Param("query", "before", "before must be a delivery ID"))
+			return
+		}
+		db = db.Where("id < ?", before)
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := db.Order("id DESC").Limit(maxDeliveryLogEntries).Find(&deliveries).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to fetch deliveries"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: deliveries,
+	})
+}
+
+func (s *Server) getWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	delivery, ok := s.findWebhookDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Data: map[string]interface{}{
+			"delivery": delivery,
+			"payload":  json.RawMessage(delivery.Payload),
+		},
+	})
+}
+
+// redeliverWebhook queues a new delivery of the same payload. The original
+// entry stays in the log unchanged.
+func (s *Server) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	delivery, ok := s.findWebhookDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	redelivery := models.WebhookDelivery{
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         models.DeliveryPending,
+		NextAttemptAt:  time.Now(),
+		RedeliveryOf:   &delivery.ID,
+	}
+	if err := s.db(r).Create(&redelivery).Error; err != nil {
+		problem.Write(w, r, problem.Internal("Failed to queue redelivery"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(RouteResponse{
+		Message: "Redelivery queued",
+		Data:    redelivery,
+	})
+}
+
+// findWebhookDelivery loads the delivery named by the deliveryId route
+// variable, reporting an error to the client as findProjectWebhook does.
+func (s *Server) findWebhookDelivery(w http.ResponseWriter, r *http.Request) (models.WebhookDelivery, bool) {
+	var delivery models.WebhookDelivery
+	sub, ok := s.findProjectWebhook(w, r)
+	if !ok {
+		return delivery, false
+	}
+	if err := s.db(r).Where("subscription_id = ?", sub.ID).First(&delivery, mux.Vars(r)["deliveryId"]).Error; err != nil {
+		writeLookupError(w, r, err, "Delivery not found")
+		return delivery, false
+	}
+	return delivery, true
+}
@@ -0,0 +1,301 @@
+// Package webhooks delivers project events to subscribed URLs. Events are
+// queued as models.WebhookDelivery rows and sent by a Deliverer, which retries
+// failures with exponential backoff.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"kanban_server/events"
+	"kanban_server/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before it is marked failed.
+	MaxAttempts = 8
+	// BaseBackoff is the wait after the first failed attempt; it doubles with
+	// every further attempt up to MaxBackoff.
+	BaseBackoff = 30 * time.Second
+	MaxBackoff  = 6 * time.Hour
+
+	batchSize = 50
+)
+
+// AllEvents subscribes to every event type.
+const AllEvents = "*"
+
+// Headers set on every delivery besides SignatureHeader.
+const (
+	EventHeader    = "X-Kanban-Event"
+	DeliveryHeader = "X-Kanban-Delivery"
+)
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Subscribed reports whether a subscription's comma separated event list
+// includes eventType.
+func Subscribed(list, eventType string) bool {
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == AllEvents || item == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Enqueue queues e for every active subscription of its project that wants
+// it.
+func Enqueue(db *gorm.DB, e events.Event) error {
+	var subs []models.WebhookSubscription
+	if err := db.Where("project_id = ? AND active = ?", e.ProjectID, true).Find(&subs).Error; err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, sub := range subs {
+		if !Subscribed(sub.Events, e.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        string(payload),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  e.OccurredAt,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return db.Create(&deliveries).Error
+}
+
+// ErrForbiddenAddress is returned when a subscription URL resolves to an
+// address that is not public.
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// PublicAddr reports whether webhooks may be sent to addr. Loopback,
+// link-local, private and unspecified addresses are refused, so that
+// subscriptions cannot reach services on the server's own network.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast() &&
+		!addr.IsPrivate() && !addr.IsUnspecified()
+}
+
+// NewClient returns an HTTP client for deliveries that refuses to connect to
+// addresses PublicAddr rejects. The check runs on every connection, so it
+// also covers DNS names resolving to such addresses and redirects. Proxies
+// are not used, as the check would only see the proxy's address.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: checkPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func checkPublic(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// Backoff returns the wait before the attempt following the given number of
+// failed attempts.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	d := BaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return d
+}
+
+// Result is the outcome of one delivery attempt. The response body is not
+// kept, as it would hand whatever the URL serves to API callers.
+type Result struct {
+	StatusCode int
+	Err        error
+}
+
+// OK reports whether the receiver accepted the delivery with a 2xx status.
+func (r Result) OK() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Send posts a delivery's payload to the subscription URL once.
+func Send(ctx context.Context, client *http.Client, sub models.WebhookSubscription, d models.WebhookDelivery, now time.Time) Result {
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return Result{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kanban-server-webhooks")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, fmt.Sprint(d.ID))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, now, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+	result := Result{StatusCode: resp.StatusCode}
+	if !result.OK() {
+		result.Err = fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return result
+}
+
+// Deliverer sends due deliveries from the queue. Several deliverers may share
+// a database: each attempt is claimed by moving next_attempt_at forward with
+// a compare-and-set before the request is made.
+type Deliverer struct {
+	db       *gorm.DB
+	client   *http.Client
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewDeliverer(db *gorm.DB, client *http.Client, interval time.Duration) *Deliverer {
+	return &Deliverer{db: db, client: client, interval: interval, now: time.Now}
+}
+
+// Run sends due deliveries immediately and then every interval until ctx is
+// cancelled.
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.RunOnce(ctx); err != nil {
+			log.Println("Webhook deliverer:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce attempts every delivery that is due and returns the number of
+// attempts made.
+func (d *Deliverer) RunOnce(ctx context.Context) (int, error) {
+	db := d.db.WithContext(ctx)
+	attempts := 0
+	for {
+		var due []models.WebhookDelivery
+		err := db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, d.now()).
+			Order("next_attempt_at, id").Limit(batchSize).Find(&due).Error
+		if err != nil {
+			return attempts, err
+		}
+		if len(due) == 0 {
+			return attempts, nil
+		}
+
+		for _, delivery := range due {
+			if ctx.Err() != nil {
+				return attempts, ctx.Err()
+			}
+			if d.claim(db, &delivery) {
+				d.attempt(ctx, db, delivery)
+				attempts++
+			}
+		}
+		if len(due) < batchSize {
+			return attempts, nil
+		}
+	}
+}
+
+// claim reserves a delivery for one attempt. If this process dies during the
+// attempt, the delivery becomes due again once the lease expires.
+func (d *Deliverer) claim(db *gorm.DB, delivery *models.WebhookDelivery) bool {
+	lease := d.now().Add(d.client.Timeout + time.Minute)
+	result := db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, models.DeliveryPending, delivery.NextAttemptAt).
+		Update("next_attempt_at", lease)
+	if result.Error != nil {
+		log.Printf("Webhook delivery %d: %v\n", delivery.ID, result.Error)
+		return false
+	}
+	return result.RowsAffected > 0
+}
+
+func (d *Deliverer) attempt(ctx context.Context, db *gorm.DB, delivery models.WebhookDelivery) {
+	now := d.now()
+	updates := map[string]interface{}{
+		"attempts":        delivery.Attempts + 1,
+		"last_attempt_at": now,
+	}
+
+	var sub models.WebhookSubscription
+	if err := db.Where("active = ?", true).First(&sub, delivery.SubscriptionID).Error; err != nil {
+		updates["status"] = models.DeliveryFailed
+		updates["error"] = "subscription is inactive or was deleted"
+	} else {
+		result := Send(ctx, d.client, sub, delivery, now)
+		updates["response_status"] = result.StatusCode
+		updates["error"] = ""
+		switch {
+		case result.OK():
+			updates["status"] = models.DeliverySucceeded
+		case delivery.Attempts+1 >= MaxAttempts:
+			updates["status"] = models.DeliveryFailed
+			updates["error"] = result.Err.Error()
+		default:
+			updates["error"] = result.Err.Error()
+			updates["next_attempt_at"] = now.Add(Backoff(delivery.Attempts + 1))
+		}
+	}
+
+	if err := db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+		log.Printf("Webhook delivery %d: %v\n", delivery.ID, err)
+	}
+}
@@ -0,0 +1,165 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"kanban_server/events"
+	"kanban_server/models"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"task.created"}`)
+	header := Sign("secret", now, body)
+
+	if err := Verify("secret", header, body, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Verify valid signature: %v", err)
+	}
+	if err := Verify("other", header, body, 0, now); err != ErrSignatureMismatch {
+		t.Errorf("Verify with wrong secret = %v, want %v", err, ErrSignatureMismatch)
+	}
+	if err := Verify("secret", header, []byte(`{}`), 0, now); err != ErrSignatureMismatch {
+		t.Errorf("Verify with tampered body = %v, want %v", err, ErrSignatureMismatch)
+	}
+	if err := Verify("secret", header, body, 5*time.Minute, now.Add(time.Hour)); err != ErrSignatureExpired {
+		t.Errorf("Verify replayed signature = %v, want %v", err, ErrSignatureExpired)
+	}
+	if err := Verify("secret", "v1=abc", body, 0, now); err != ErrMalformedSignature {
+		t.Errorf("Verify malformed header = %v, want %v", err, ErrMalformedSignature)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, MaxBackoff},
+		{100, MaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	if !Subscribed("task.created, task.updated", events.TaskUpdated) {
+		t.Error("explicit event type not matched")
+	}
+	if Subscribed("task.created", events.TaskDeleted) {
+		t.Error("unsubscribed event type matched")
+	}
+	if !Subscribed(AllEvents, events.MemberAdded) {
+		t.Error("wildcard did not match")
+	}
+}
+
+func TestSend(t *testing.T) {
+	now := time.Now()
+	event := events.New(events.TaskCreated, 7, 3, map[string]string{"title": "Write docs"})
+	payload, _ := json.Marshal(event)
+	sub := models.WebhookSubscription{ID: 1, Secret: "s3cret"}
+	delivery := models.WebhookDelivery{ID: 42, EventType: event.Type, Payload: string(payload)}
+
+	var received *http.Request
+	var receivedBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		if err := Verify("s3cret", r.Header.Get(SignatureHeader), receivedBody, time.Minute, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+	sub.URL = receiver.URL
+
+	result := Send(context.Background(), receiver.Client(), sub, delivery, now)
+	if !result.OK() {
+		t.Fatalf("Send = %+v, want success", result)
+	}
+	if got := received.Header.Get(EventHeader); got != events.TaskCreated {
+		t.Errorf("%s = %q, want %q", EventHeader, got, events.TaskCreated)
+	}
+	if got := received.Header.Get(DeliveryHeader); got != "42" {
+		t.Errorf("%s = %q, want %q", DeliveryHeader, got, "42")
+	}
+	var got events.Event
+	if err := json.Unmarshal(receivedBody, &got); err != nil || got.ID != event.ID {
+		t.Errorf("receiver got event %+v (%v), want ID %s", got, err, event.ID)
+	}
+
+	sub.Secret = "rotated"
+	result = Send(context.Background(), receiver.Client(), sub, delivery, now)
+	if result.OK() || result.StatusCode != http.StatusUnauthorized || result.Err == nil {
+		t.Errorf("Send with wrong secret = %+v, want 401 failure", result)
+	}
+}
+
+func TestSendUnreachable(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	url := receiver.URL
+	receiver.Close()
+
+	sub := models.WebhookSubscription{URL: url, Secret: "s"}
+	result := Send(context.Background(), http.DefaultClient, sub, models.WebhookDelivery{Payload: "{}"}, time.Now())
+	if result.OK() || result.Err == nil || result.StatusCode != 0 {
+		t.Errorf("Send to closed receiver = %+v, want connection error", result)
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":    true,
+		"2606:2800:220::1": true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"fd00::1":          false,
+		"0.0.0.0":          false,
+		"::":               false,
+		"::ffff:127.0.0.1": false,
+	}
+	for addr, want := range tests {
+		if got := PublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("PublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestClientRefusesLocalAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback receiver")
+	}))
+	defer receiver.Close()
+
+	// Names are resolved first, so localhost is refused like 127.0.0.1.
+	for _, url := range []string{receiver.URL, strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)} {
+		sub := models.WebhookSubscription{URL: url, Secret: "s"}
+		result := Send(context.Background(), NewClient(time.Second), sub, models.WebhookDelivery{Payload: "{}"}, time.Now())
+		if !errors.Is(result.Err, ErrForbiddenAddress) {
+			t.Errorf("Send to %s = %+v, want ErrForbiddenAddress", url, result)
+		}
+	}
+}